@@ -68,3 +68,31 @@ func ExampleParseResourceID() {
 	// Parent: /subscriptions/00000000-0000-0000-0000-000000000000
 	// Name: 00000000-0000-0000-0000-000000000000, ResourceType: Microsoft.Resources/subscriptions, SubscriptionId: 00000000-0000-0000-0000-000000000000, ResourceGroupName:
 }
+
+func ExampleNewResourceIDBuilder() {
+	id, err := NewResourceIDBuilder(nil, nil).
+		Subscription("00000000-0000-0000-0000-000000000000").
+		ResourceGroup("myRg").
+		Resource("Microsoft.Network", "virtualNetworks", "vnet").
+		Child("subnets", "default").
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(id.String())
+
+	// extension resources can be added to any scope
+	lock, err := NewResourceIDBuilder(id.Parent, nil).
+		Extension("Microsoft.Authorization", "locks", "myLock").
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(lock.String())
+	fmt.Println(id.CommonScope(lock).String())
+
+	// Output:
+	// /subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/default
+	// /subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRg/providers/Microsoft.Network/virtualNetworks/vnet/providers/Microsoft.Authorization/locks/myLock
+	// /subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/myRg/providers/Microsoft.Network/virtualNetworks/vnet
+}
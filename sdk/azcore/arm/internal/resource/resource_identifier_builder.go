@@ -0,0 +1,252 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package resource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResourceIDBuilderOptions contains the optional values for [NewResourceIDBuilder].
+type ResourceIDBuilderOptions struct {
+	// ResourceTypes are the known resource types of their namespaces. Resources of a namespace having
+	// known types must have one of them, which checks both the type names and how deeply they're nested,
+	// e.g. "Microsoft.Network/virtualNetworks/subnets". Resources of other namespaces can have any type.
+	// The default value is nil, which accepts any resource type.
+	ResourceTypes []ResourceType
+}
+
+// ResourceIDBuilder builds a ResourceID one segment at a time, starting from a scope such as the tenant.
+// Each method returns the builder so that calls can be chained. The first invalid segment is reported by
+// [ResourceIDBuilder.Build]; subsequent calls are ignored.
+// Don't use this type directly, use [NewResourceIDBuilder] instead.
+type ResourceIDBuilder struct {
+	current *ResourceID
+	err     error
+	// knownTypes maps lower-cased namespaces to their lower-cased known resource types
+	knownTypes map[string]map[string]struct{}
+}
+
+// NewResourceIDBuilder creates a builder for resource IDs under the specified scope.
+//   - scope is the ID the builder starts from, pass nil to start from [RootResourceID]
+//   - options contains optional configuration, pass nil to accept the default values
+func NewResourceIDBuilder(scope *ResourceID, options *ResourceIDBuilderOptions) *ResourceIDBuilder {
+	if scope == nil {
+		scope = RootResourceID
+	}
+	if options == nil {
+		options = &ResourceIDBuilderOptions{}
+	}
+	knownTypes := map[string]map[string]struct{}{}
+	for _, rt := range options.ResourceTypes {
+		ns := strings.ToLower(rt.Namespace)
+		if knownTypes[ns] == nil {
+			knownTypes[ns] = map[string]struct{}{}
+		}
+		knownTypes[ns][strings.ToLower(rt.Type)] = struct{}{}
+	}
+	return &ResourceIDBuilder{current: scope, knownTypes: knownTypes}
+}
+
+// Subscription appends a subscription. The scope must be the tenant.
+func (b *ResourceIDBuilder) Subscription(subscriptionID string) *ResourceIDBuilder {
+	return b.append(func(parent *ResourceID) (*ResourceID, error) {
+		if !isType(parent, TenantResourceType) {
+			return nil, fmt.Errorf("a subscription can't be a child of %s", describe(parent))
+		}
+		if err := validateName(subscriptionID); err != nil {
+			return nil, err
+		}
+		return newResourceIDWithResourceType(parent, SubscriptionResourceType, subscriptionID), nil
+	})
+}
+
+// ResourceGroup appends a resource group. The scope must be a subscription.
+func (b *ResourceIDBuilder) ResourceGroup(name string) *ResourceIDBuilder {
+	return b.append(func(parent *ResourceID) (*ResourceID, error) {
+		if !isType(parent, SubscriptionResourceType) {
+			return nil, fmt.Errorf("a resource group can't be a child of %s", describe(parent))
+		}
+		if err := validateName(name); err != nil {
+			return nil, err
+		}
+		return newResourceIDWithResourceType(parent, ResourceGroupResourceType, name), nil
+	})
+}
+
+// Resource appends a top-level resource of a resource provider, e.g. Resource("Microsoft.Network", "virtualNetworks", "vnet").
+// The scope must be the tenant, a subscription or a resource group.
+func (b *ResourceIDBuilder) Resource(namespace, resourceType, name string) *ResourceIDBuilder {
+	return b.append(func(parent *ResourceID) (*ResourceID, error) {
+		if !isType(parent, TenantResourceType) && !isType(parent, SubscriptionResourceType) && !isType(parent, ResourceGroupResourceType) {
+			return nil, fmt.Errorf("resource %s/%s can't be a child of %s, use Child or Extension instead", namespace, resourceType, describe(parent))
+		}
+		return newProviderResource(parent, namespace, resourceType, name)
+	})
+}
+
+// Child appends a child resource of the current resource, e.g. Child("subnets", "default") after a virtual network.
+// The child's resource type is the type of its parent with resourceType appended. The scope can't be the tenant or a resource group.
+func (b *ResourceIDBuilder) Child(resourceType, name string) *ResourceIDBuilder {
+	return b.append(func(parent *ResourceID) (*ResourceID, error) {
+		if isType(parent, TenantResourceType) || isType(parent, ResourceGroupResourceType) {
+			return nil, fmt.Errorf("%s can't have child resources, use Resource instead", describe(parent))
+		}
+		if isType(parent, SubscriptionResourceType) && (strings.EqualFold(resourceType, resourceGroupsLowerKey) || strings.EqualFold(resourceType, providersKey)) {
+			return nil, fmt.Errorf("child resource type %q is reserved", resourceType)
+		}
+		if err := validateSegment("resource type", resourceType); err != nil {
+			return nil, err
+		}
+		if err := validateName(name); err != nil {
+			return nil, err
+		}
+		return newResourceID(parent, resourceType, name), nil
+	})
+}
+
+// Extension appends an extension resource, which is a resource of a provider that extends another resource, e.g.
+// Extension("Microsoft.Authorization", "roleAssignments", name) for a role assignment. The scope can be any resource,
+// subscription or resource group, but not the tenant; use [ResourceIDBuilder.Resource] for tenant-level resources.
+func (b *ResourceIDBuilder) Extension(namespace, resourceType, name string) *ResourceIDBuilder {
+	return b.append(func(parent *ResourceID) (*ResourceID, error) {
+		if isType(parent, TenantResourceType) {
+			return nil, fmt.Errorf("extension resource %s/%s requires a scope other than the tenant", namespace, resourceType)
+		}
+		return newProviderResource(parent, namespace, resourceType, name)
+	})
+}
+
+// Build returns the ResourceID or the first error encountered while building it.
+func (b *ResourceIDBuilder) Build() (*ResourceID, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.current, nil
+}
+
+func (b *ResourceIDBuilder) append(next func(parent *ResourceID) (*ResourceID, error)) *ResourceIDBuilder {
+	if b.err != nil {
+		return b
+	}
+	id, err := next(b.current)
+	if err == nil {
+		err = b.checkKnownType(id.ResourceType)
+	}
+	if err != nil {
+		b.err = fmt.Errorf("invalid resource ID: %w", err)
+		return b
+	}
+	b.current = id
+	return b
+}
+
+// checkKnownType returns an error if the namespace of rt has known types and rt isn't one of them
+func (b *ResourceIDBuilder) checkKnownType(rt ResourceType) error {
+	if strings.EqualFold(rt.Namespace, builtInResourceNamespace) {
+		// subscriptions, resource groups and their children such as locations
+		return nil
+	}
+	types, ok := b.knownTypes[strings.ToLower(rt.Namespace)]
+	if !ok {
+		return nil
+	}
+	if _, ok := types[strings.ToLower(rt.Type)]; !ok {
+		return fmt.Errorf("%s isn't a known resource type of namespace %s, add it to ResourceIDBuilderOptions.ResourceTypes if it's valid", rt, rt.Namespace)
+	}
+	return nil
+}
+
+// IsDescendantOf returns true when the receiver is a descendant of ancestor, e.g. a subnet is a descendant of its
+// virtual network, resource group and subscription. An ID isn't a descendant of itself. IDs are compared case-insensitively.
+func (id *ResourceID) IsDescendantOf(ancestor *ResourceID) bool {
+	if id == nil || ancestor == nil {
+		return false
+	}
+	for parent := id.Parent; parent != nil; parent = parent.Parent {
+		if strings.EqualFold(parent.String(), ancestor.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommonScope returns the deepest ResourceID that is the receiver or one of its ancestors, and also the other ID or one of
+// its ancestors, e.g. the resource group of two resources in the same resource group. IDs are compared case-insensitively.
+// The result is [RootResourceID] when the IDs have nothing else in common.
+func (id *ResourceID) CommonScope(other *ResourceID) *ResourceID {
+	if id == nil || other == nil {
+		return RootResourceID
+	}
+	for scope := id; scope != nil; scope = scope.Parent {
+		if strings.EqualFold(scope.String(), other.String()) || other.IsDescendantOf(scope) {
+			return scope
+		}
+	}
+	return RootResourceID
+}
+
+// newProviderResource returns a resource of a resource provider after validating its segments
+func newProviderResource(parent *ResourceID, namespace, resourceType, name string) (*ResourceID, error) {
+	if err := validateNamespace(namespace); err != nil {
+		return nil, err
+	}
+	if err := validateSegment("resource type", resourceType); err != nil {
+		return nil, err
+	}
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(namespace, builtInResourceNamespace) {
+		for _, reserved := range []ResourceType{SubscriptionResourceType, ResourceGroupResourceType, TenantResourceType, ProviderResourceType} {
+			if strings.EqualFold(resourceType, reserved.Type) {
+				return nil, fmt.Errorf("resource type %s/%s is reserved", namespace, resourceType)
+			}
+		}
+	}
+	return newResourceIDWithProvider(parent, namespace, resourceType, name), nil
+}
+
+// validateNamespace returns an error if namespace isn't a resource provider namespace such as "Microsoft.Network"
+func validateNamespace(namespace string) error {
+	if err := validateSegment("namespace", namespace); err != nil {
+		return err
+	}
+	for _, part := range strings.Split(namespace, ".") {
+		if part == "" {
+			return fmt.Errorf("namespace %q must contain two or more names separated by periods", namespace)
+		}
+	}
+	if !strings.Contains(namespace, ".") {
+		return fmt.Errorf("namespace %q must contain two or more names separated by periods", namespace)
+	}
+	return nil
+}
+
+func validateName(name string) error {
+	return validateSegment("name", name)
+}
+
+// validateSegment returns an error if value can't be a single segment of a resource ID
+func validateSegment(kind, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("%s can't be empty", kind)
+	}
+	if strings.Contains(value, "/") {
+		return fmt.Errorf("%s %q can't contain '/'", kind, value)
+	}
+	return nil
+}
+
+func isType(id *ResourceID, resourceType ResourceType) bool {
+	return id.ResourceType.String() == resourceType.String()
+}
+
+// describe returns a description of id for error messages
+func describe(id *ResourceID) string {
+	if isType(id, TenantResourceType) {
+		return "the tenant"
+	}
+	return id.String()
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSubscriptionID = "17fecd63-33d8-4e43-ac6f-0aafa111b38d"
+
+func TestResourceIDBuilder(t *testing.T) {
+	for expected, b := range map[string]*ResourceIDBuilder{
+		"/subscriptions/" + testSubscriptionID: NewResourceIDBuilder(nil, nil).Subscription(testSubscriptionID),
+		"/subscriptions/" + testSubscriptionID + "/resourceGroups/myRg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/default": NewResourceIDBuilder(nil, nil).
+			Subscription(testSubscriptionID).
+			ResourceGroup("myRg").
+			Resource("Microsoft.Network", "virtualNetworks", "vnet").
+			Child("subnets", "default"),
+		"/subscriptions/" + testSubscriptionID + "/resourceGroups/myRg/providers/Microsoft.Compute/virtualMachines/vm/providers/Microsoft.Authorization/roleAssignments/ra": NewResourceIDBuilder(nil, nil).
+			Subscription(testSubscriptionID).
+			ResourceGroup("myRg").
+			Resource("Microsoft.Compute", "virtualMachines", "vm").
+			Extension("Microsoft.Authorization", "roleAssignments", "ra"),
+		"/subscriptions/" + testSubscriptionID + "/locations/westus": NewResourceIDBuilder(nil, nil).Subscription(testSubscriptionID).Child("locations", "westus"),
+		"/providers/Microsoft.Management/managementGroups/mg":        NewResourceIDBuilder(nil, nil).Resource("Microsoft.Management", "managementGroups", "mg"),
+	} {
+		id, err := b.Build()
+		require.NoError(t, err, expected)
+		require.Equal(t, expected, id.String())
+		// the built ID is the same as the parsed ID
+		parsed, err := ParseResourceID(expected)
+		require.NoError(t, err)
+		require.Equal(t, parsed, id)
+	}
+
+	// build from a parsed scope
+	scope, err := ParseResourceID("/subscriptions/" + testSubscriptionID + "/resourceGroups/myRg")
+	require.NoError(t, err)
+	id, err := NewResourceIDBuilder(scope, nil).Extension("Microsoft.Authorization", "locks", "lock").Build()
+	require.NoError(t, err)
+	require.Equal(t, scope.String()+"/providers/Microsoft.Authorization/locks/lock", id.String())
+	require.Equal(t, "myRg", id.ResourceGroupName)
+	require.Equal(t, NewResourceType("Microsoft.Authorization", "locks"), id.ResourceType)
+}
+
+func TestResourceIDBuilderErrors(t *testing.T) {
+	sub := func() *ResourceIDBuilder { return NewResourceIDBuilder(nil, nil).Subscription(testSubscriptionID) }
+	rg := func() *ResourceIDBuilder { return sub().ResourceGroup("myRg") }
+	for name, b := range map[string]*ResourceIDBuilder{
+		"empty subscription":          NewResourceIDBuilder(nil, nil).Subscription(""),
+		"nested subscription":         sub().Subscription(testSubscriptionID),
+		"resource group under tenant": NewResourceIDBuilder(nil, nil).ResourceGroup("myRg"),
+		"name with slash":             sub().ResourceGroup("my/Rg"),
+		"blank name":                  rg().Resource("Microsoft.Network", "virtualNetworks", " "),
+		"invalid namespace":           rg().Resource("Network", "virtualNetworks", "vnet"),
+		"empty namespace part":        rg().Resource("Microsoft.", "virtualNetworks", "vnet"),
+		"multi-segment type":          rg().Resource("Microsoft.Network", "virtualNetworks/subnets", "vnet"),
+		"reserved type":               sub().Resource("Microsoft.Resources", "resourceGroups", "myRg"),
+		"resource under resource":     rg().Resource("Microsoft.Network", "virtualNetworks", "vnet").Resource("Microsoft.Network", "subnets", "default"),
+		"child of tenant":             NewResourceIDBuilder(nil, nil).Child("locations", "westus"),
+		"child of resource group":     rg().Child("virtualNetworks", "vnet"),
+		"reserved child":              sub().Child("resourceGroups", "myRg"),
+		"extension of tenant":         NewResourceIDBuilder(nil, nil).Extension("Microsoft.Authorization", "roleAssignments", "ra"),
+		// subsequent segments are ignored after an error
+		"first error": rg().Child("virtualNetworks", "vnet").Child("subnets", "default"),
+	} {
+		id, err := b.Build()
+		require.Error(t, err, name)
+		require.Nil(t, id, name)
+	}
+	_, err := rg().Child("virtualNetworks", "vnet").Child("subnets", "").Build()
+	require.ErrorContains(t, err, "child resources")
+}
+
+func TestResourceIDBuilderKnownTypes(t *testing.T) {
+	rg := func(options *ResourceIDBuilderOptions) *ResourceIDBuilder {
+		return NewResourceIDBuilder(nil, options).Subscription(testSubscriptionID).ResourceGroup("myRg")
+	}
+
+	// without options, any type is accepted
+	for _, b := range []*ResourceIDBuilder{
+		rg(nil).Resource("Microsoft.Network", "networkWatchers", "nw"),
+		rg(nil).Resource("Microsoft.Storage", "storageAccounts", "sa").Child("managementPolicies", "default"),
+		rg(nil).Resource("Contoso.Widgets", "widgets", "w").Child("parts", "p").Child("bolts", "b"),
+	} {
+		_, err := b.Build()
+		require.NoError(t, err)
+	}
+
+	options := &ResourceIDBuilderOptions{ResourceTypes: []ResourceType{
+		NewResourceType("Microsoft.Network", "virtualNetworks"),
+		NewResourceType("Microsoft.Network", "virtualNetworks/subnets"),
+		NewResourceType("Microsoft.Storage", "storageAccounts"),
+		NewResourceType("Microsoft.Storage", "storageAccounts/blobServices"),
+		NewResourceType("Microsoft.Storage", "storageAccounts/blobServices/containers"),
+		NewResourceType("Microsoft.Authorization", "roleAssignments"),
+	}}
+	for name, b := range map[string]*ResourceIDBuilder{
+		"unknown type":            rg(options).Resource("Microsoft.Network", "virtualNetwork", "vnet"),
+		"unknown child":           rg(options).Resource("Microsoft.Network", "virtualNetworks", "vnet").Child("subnet", "default"),
+		"child nested too deeply": rg(options).Resource("Microsoft.Network", "virtualNetworks", "vnet").Child("subnets", "default").Child("subnets", "nested"),
+		"child type at top level": rg(options).Resource("Microsoft.Storage", "containers", "c"),
+		"unknown extension":       rg(options).Resource("Microsoft.Compute", "virtualMachines", "vm").Extension("Microsoft.Authorization", "roleAssignment", "ra"),
+	} {
+		_, err := b.Build()
+		require.ErrorContains(t, err, "isn't a known resource type", name)
+	}
+
+	// types are matched case-insensitively
+	_, err := rg(options).Resource("microsoft.storage", "STORAGEACCOUNTS", "sa").Child("blobServices", "default").Child("containers", "c").Build()
+	require.NoError(t, err)
+
+	// namespaces without known types accept any type
+	_, err = rg(options).Resource("Microsoft.Compute", "virtualMachines", "vm").Child("extensions", "e").Build()
+	require.NoError(t, err)
+}
+
+func TestResourceIDRelations(t *testing.T) {
+	parse := func(s string) *ResourceID {
+		id, err := ParseResourceID(s)
+		require.NoError(t, err)
+		return id
+	}
+	sub := parse("/subscriptions/" + testSubscriptionID)
+	rg := parse("/subscriptions/" + testSubscriptionID + "/resourceGroups/myRg")
+	vnet := parse("/subscriptions/" + testSubscriptionID + "/resourceGroups/myRg/providers/Microsoft.Network/virtualNetworks/vnet")
+	subnet := parse("/subscriptions/" + testSubscriptionID + "/resourceGroups/MYRG/providers/Microsoft.Network/virtualNetworks/vnet/subnets/default")
+	vm := parse("/subscriptions/" + testSubscriptionID + "/resourceGroups/myRg/providers/Microsoft.Compute/virtualMachines/vm")
+	otherSub := parse("/subscriptions/00000000-0000-0000-0000-000000000000")
+
+	require.True(t, subnet.IsDescendantOf(vnet))
+	require.True(t, subnet.IsDescendantOf(rg))
+	require.True(t, subnet.IsDescendantOf(sub))
+	require.True(t, subnet.IsDescendantOf(RootResourceID))
+	require.False(t, subnet.IsDescendantOf(subnet))
+	require.False(t, vnet.IsDescendantOf(subnet))
+	require.False(t, subnet.IsDescendantOf(vm))
+	require.False(t, subnet.IsDescendantOf(nil))
+
+	require.Equal(t, rg, vm.CommonScope(subnet))
+	// the result is an ancestor of the receiver
+	require.Equal(t, subnet.Parent.Parent, subnet.CommonScope(vm))
+	require.Equal(t, subnet.Parent, subnet.CommonScope(vnet))
+	require.Equal(t, vnet, vnet.CommonScope(subnet))
+	require.Equal(t, subnet, subnet.CommonScope(subnet))
+	require.Equal(t, RootResourceID, subnet.CommonScope(otherSub))
+	require.Equal(t, RootResourceID, subnet.CommonScope(nil))
+}
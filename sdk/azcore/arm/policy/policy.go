@@ -20,6 +20,10 @@ type BearerTokenOptions struct {
 	// policy's credential must support multitenant authentication.
 	AuxiliaryTenants []string
 
+	// BackgroundRefresh enables refreshing tokens for the primary tenant in a background goroutine before
+	// they expire. See [policy.BearerTokenOptions] for more information.
+	BackgroundRefresh bool
+
 	// InsecureAllowCredentialWithHTTP enables authenticated requests over HTTP.
 	// By default, authenticated requests to an HTTP endpoint are rejected by the client.
 	// WARNING: setting this to true will allow sending the authentication key in clear text. Use with caution.
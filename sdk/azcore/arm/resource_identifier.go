@@ -22,3 +22,20 @@ type ResourceID = resource.ResourceID
 func ParseResourceID(id string) (*ResourceID, error) {
 	return resource.ParseResourceID(id)
 }
+
+// ResourceIDBuilder builds a ResourceID one segment at a time, starting from a scope such as the tenant.
+// Each method returns the builder so that calls can be chained. The first invalid segment is reported by
+// Build; subsequent calls are ignored.
+// Don't use this type directly, use [NewResourceIDBuilder] instead.
+type ResourceIDBuilder = resource.ResourceIDBuilder
+
+// ResourceIDBuilderOptions contains the optional values for [NewResourceIDBuilder].
+type ResourceIDBuilderOptions = resource.ResourceIDBuilderOptions
+
+// NewResourceIDBuilder creates a builder for resource IDs under the specified scope.
+// Resources of namespaces having types in options.ResourceTypes must have one of those types.
+//   - scope is the ID the builder starts from, pass nil to start from [RootResourceID]
+//   - options contains optional configuration, pass nil to accept the default values
+func NewResourceIDBuilder(scope *ResourceID, options *ResourceIDBuilderOptions) *ResourceIDBuilder {
+	return resource.NewResourceIDBuilder(scope, options)
+}
@@ -0,0 +1,193 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/errorinfo"
+)
+
+// ErrorClass is a category of errors that callers handle the same way regardless of the service that returned them.
+type ErrorClass string
+
+const (
+	// ErrorClassNotFound indicates the resource doesn't exist. The default for status code 404.
+	ErrorClassNotFound ErrorClass = "NotFound"
+
+	// ErrorClassConflict indicates the request conflicts with the state of the resource, e.g. it already exists.
+	// The default for status code 409.
+	ErrorClassConflict ErrorClass = "Conflict"
+
+	// ErrorClassThrottled indicates the caller sent too many requests. The default for status code 429.
+	// Throttled errors are also transient.
+	ErrorClassThrottled ErrorClass = "Throttled"
+
+	// ErrorClassTransient indicates the request might succeed if sent again later. The default for
+	// status codes 408, 500, 502, 503 and 504, and for network errors such as timeouts and connection
+	// resets, i.e. the errors the retry policy retries.
+	ErrorClassTransient ErrorClass = "Transient"
+
+	// ErrorClassAuthFailure indicates the caller isn't authenticated or isn't authorized to perform the
+	// operation. The default for status codes 401 and 403.
+	ErrorClassAuthFailure ErrorClass = "AuthFailure"
+
+	// ErrorClassPreconditionFailed indicates a condition of the request, such as If-Match, wasn't met.
+	// The default for status code 412.
+	ErrorClassPreconditionFailed ErrorClass = "PreconditionFailed"
+)
+
+// ErrorClassifier returns the class of an error returned by a service, or false if it doesn't recognize the error.
+// It's called with the error as returned to the caller, so it can inspect any error in the chain, such as a
+// [*ResponseError] or a service-specific error type. Classifiers are shared by all modules in the process, so
+// a classifier must recognize only its service's errors, e.g. by the host of the request or by error type.
+type ErrorClassifier func(err error) (ErrorClass, bool)
+
+var (
+	classifiersMu sync.RWMutex
+	classifiers   []ErrorClassifier
+)
+
+// RegisterErrorClassifier adds a classifier used by [Classify] and the IsXxx functions, such as [IsNotFound].
+// Service modules register classifiers for their error codes, typically in an init function, so that errors
+// from all services can be handled uniformly. Use [ErrorCodeClassifier] to map error codes, which scopes
+// them to the service's hosts. Registered classifiers are called in the order they were registered and take
+// precedence over the classification of HTTP status codes.
+// It's safe to call RegisterErrorClassifier concurrently with classification.
+func RegisterErrorClassifier(classifier ErrorClassifier) {
+	if classifier == nil {
+		return
+	}
+	classifiersMu.Lock()
+	defer classifiersMu.Unlock()
+	classifiers = append(classifiers, classifier)
+}
+
+// ErrorCodeClassifier returns an [ErrorClassifier] that classifies a [*ResponseError] in the error chain by
+// its ErrorCode when its request was sent to a host ending with one of hostSuffixes, e.g.
+//
+//	ErrorCodeClassifier([]string{".blob.core.windows.net"}, map[string]ErrorClass{"ContainerNotFound": ErrorClassNotFound})
+//
+// Scoping the codes by host keeps services that return the same error code with different meanings from
+// colliding. Host suffixes are matched case-insensitively and error codes are matched exactly. With no host
+// suffixes, the classifier doesn't recognize any error. The arguments must not be modified after they're
+// passed to this function.
+func ErrorCodeClassifier(hostSuffixes []string, codes map[string]ErrorClass) ErrorClassifier {
+	return func(err error) (ErrorClass, bool) {
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) || respErr.ErrorCode == "" || !hasHostSuffix(respErr, hostSuffixes) {
+			return "", false
+		}
+		class, ok := codes[respErr.ErrorCode]
+		return class, ok
+	}
+}
+
+// hasHostSuffix returns true if the request of respErr was sent to a host ending with one of suffixes
+func hasHostSuffix(respErr *ResponseError, suffixes []string) bool {
+	if respErr.RawResponse == nil || respErr.RawResponse.Request == nil || respErr.RawResponse.Request.URL == nil {
+		return false
+	}
+	host := strings.ToLower(respErr.RawResponse.Request.URL.Hostname())
+	for _, suffix := range suffixes {
+		if suffix != "" && strings.HasSuffix(host, strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify returns the class of err and true, or false if err isn't in any class.
+// Registered classifiers are consulted first. Otherwise, a [*ResponseError] in the error chain is
+// classified by its status code, and network errors are transient unless the request was cancelled,
+// its context's deadline was exceeded or the error is non-retriable, consistent with the retry policy.
+func Classify(err error) (ErrorClass, bool) {
+	if err == nil {
+		return "", false
+	}
+	classifiersMu.RLock()
+	registered := classifiers
+	classifiersMu.RUnlock()
+	for _, classifier := range registered {
+		if class, ok := classifier(err); ok {
+			return class, true
+		}
+	}
+
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.StatusCode {
+		case http.StatusNotFound:
+			return ErrorClassNotFound, true
+		case http.StatusConflict:
+			return ErrorClassConflict, true
+		case http.StatusTooManyRequests:
+			return ErrorClassThrottled, true
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrorClassAuthFailure, true
+		case http.StatusPreconditionFailed:
+			return ErrorClassPreconditionFailed, true
+		case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return ErrorClassTransient, true
+		}
+		return "", false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "", false
+	}
+	var nre errorinfo.NonRetriable
+	if errors.As(err, &nre) {
+		return "", false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorClassTransient, true
+	}
+	return "", false
+}
+
+// IsNotFound returns true if err is in class [ErrorClassNotFound].
+func IsNotFound(err error) bool {
+	return isClass(err, ErrorClassNotFound)
+}
+
+// IsConflict returns true if err is in class [ErrorClassConflict].
+func IsConflict(err error) bool {
+	return isClass(err, ErrorClassConflict)
+}
+
+// IsThrottled returns true if err is in class [ErrorClassThrottled].
+func IsThrottled(err error) bool {
+	return isClass(err, ErrorClassThrottled)
+}
+
+// IsTransient returns true if err is in class [ErrorClassTransient] or [ErrorClassThrottled].
+func IsTransient(err error) bool {
+	class, ok := Classify(err)
+	return ok && (class == ErrorClassTransient || class == ErrorClassThrottled)
+}
+
+// IsAuthFailure returns true if err is in class [ErrorClassAuthFailure].
+func IsAuthFailure(err error) bool {
+	return isClass(err, ErrorClassAuthFailure)
+}
+
+// IsPreconditionFailed returns true if err is in class [ErrorClassPreconditionFailed].
+func IsPreconditionFailed(err error) bool {
+	return isClass(err, ErrorClassPreconditionFailed)
+}
+
+func isClass(err error, class ErrorClass) bool {
+	c, ok := Classify(err)
+	return ok && c == class
+}
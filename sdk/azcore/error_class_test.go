@@ -0,0 +1,178 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// withClassifiers replaces the registered classifiers for the duration of a test
+func withClassifiers(t *testing.T) {
+	classifiersMu.Lock()
+	saved := classifiers
+	classifiers = nil
+	classifiersMu.Unlock()
+	t.Cleanup(func() {
+		classifiersMu.Lock()
+		classifiers = saved
+		classifiersMu.Unlock()
+	})
+}
+
+func newTestResponseError(statusCode int, errorCode string, header http.Header) error {
+	return newTestResponseErrorFromHost("contoso.blob.core.windows.net", statusCode, errorCode, header)
+}
+
+func newTestResponseErrorFromHost(host string, statusCode int, errorCode string, header http.Header) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &ResponseError{
+		StatusCode: statusCode,
+		ErrorCode:  errorCode,
+		RawResponse: &http.Response{
+			StatusCode: statusCode,
+			Header:     header,
+			Request:    &http.Request{URL: &url.URL{Scheme: "https", Host: host}},
+		},
+	}
+}
+
+func TestClassifyStatusCodes(t *testing.T) {
+	withClassifiers(t)
+	for statusCode, is := range map[int]func(error) bool{
+		http.StatusNotFound:            IsNotFound,
+		http.StatusConflict:            IsConflict,
+		http.StatusTooManyRequests:     IsThrottled,
+		http.StatusUnauthorized:        IsAuthFailure,
+		http.StatusForbidden:           IsAuthFailure,
+		http.StatusPreconditionFailed:  IsPreconditionFailed,
+		http.StatusRequestTimeout:      IsTransient,
+		http.StatusInternalServerError: IsTransient,
+		http.StatusBadGateway:          IsTransient,
+		http.StatusServiceUnavailable:  IsTransient,
+		http.StatusGatewayTimeout:      IsTransient,
+	} {
+		err := fmt.Errorf("wrapped: %w", newTestResponseError(statusCode, "", nil))
+		require.True(t, is(err), statusCode)
+	}
+	// throttling is transient
+	require.True(t, IsTransient(newTestResponseError(http.StatusTooManyRequests, "", nil)))
+	require.False(t, IsThrottled(newTestResponseError(http.StatusServiceUnavailable, "", nil)))
+
+	for _, err := range []error{
+		nil,
+		errors.New("unclassified"),
+		context.Canceled,
+		newTestResponseError(http.StatusBadRequest, "InvalidInput", nil),
+	} {
+		_, ok := Classify(err)
+		require.False(t, ok, err)
+		require.False(t, IsNotFound(err))
+		require.False(t, IsTransient(err))
+	}
+
+	// network errors are transient, like the retry policy retries them
+	for _, err := range []error{
+		fmt.Errorf("read failed: %w", os.ErrDeadlineExceeded),
+		&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		&url.Error{Op: "Get", URL: "https://contoso.com", Err: &net.DNSError{Err: "no such host", Name: "contoso.com"}},
+		fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF),
+	} {
+		require.True(t, IsTransient(err), err)
+	}
+	// except when the caller gave up or the error is non-retriable
+	for _, err := range []error{
+		&url.Error{Op: "Get", URL: "https://contoso.com", Err: context.Canceled},
+		&url.Error{Op: "Get", URL: "https://contoso.com", Err: context.DeadlineExceeded},
+		&url.Error{Op: "Get", URL: "https://contoso.com", Err: &nonRetriableError{}},
+	} {
+		require.False(t, IsTransient(err), err)
+	}
+}
+
+type nonRetriableError struct{}
+
+func (*nonRetriableError) Error() string {
+	return "non-retriable"
+}
+
+func (*nonRetriableError) NonRetriable() {}
+
+type testAMQPError struct {
+	condition string
+}
+
+func (e *testAMQPError) Error() string {
+	return e.condition
+}
+
+func TestRegisterErrorClassifier(t *testing.T) {
+	withClassifiers(t)
+	RegisterErrorClassifier(nil)
+	// a service that reports missing resources with a different status code
+	RegisterErrorClassifier(ErrorCodeClassifier([]string{".blob.core.windows.net"}, map[string]ErrorClass{
+		"ContainerNotFound": ErrorClassNotFound,
+		"ServerBusy":        ErrorClassThrottled,
+		"ConditionNotMet":   ErrorClassPreconditionFailed,
+	}))
+	// a service with substatus codes
+	RegisterErrorClassifier(func(err error) (ErrorClass, bool) {
+		var respErr *ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden && respErr.RawResponse.Header.Get("x-ms-substatus") == "3" {
+			return ErrorClassTransient, true
+		}
+		return "", false
+	})
+	// a service with its own error type
+	RegisterErrorClassifier(func(err error) (ErrorClass, bool) {
+		var amqpErr *testAMQPError
+		if errors.As(err, &amqpErr) && amqpErr.condition == "amqp:not-found" {
+			return ErrorClassNotFound, true
+		}
+		return "", false
+	})
+
+	require.True(t, IsNotFound(newTestResponseError(http.StatusBadRequest, "ContainerNotFound", nil)))
+	require.True(t, IsThrottled(newTestResponseError(http.StatusServiceUnavailable, "ServerBusy", nil)))
+	require.True(t, IsTransient(newTestResponseError(http.StatusServiceUnavailable, "ServerBusy", nil)))
+	require.True(t, IsPreconditionFailed(newTestResponseError(http.StatusPreconditionFailed, "ConditionNotMet", nil)))
+	require.True(t, IsTransient(newTestResponseError(http.StatusForbidden, "", http.Header{"X-Ms-Substatus": []string{"3"}})))
+	require.True(t, IsAuthFailure(newTestResponseError(http.StatusForbidden, "", nil)))
+	require.True(t, IsNotFound(fmt.Errorf("receive failed: %w", &testAMQPError{condition: "amqp:not-found"})))
+	require.False(t, IsNotFound(&testAMQPError{condition: "amqp:internal-error"}))
+
+	// unmatched codes fall back to the status code
+	class, ok := Classify(newTestResponseError(http.StatusConflict, "BlobAlreadyExists", nil))
+	require.True(t, ok)
+	require.Equal(t, ErrorClassConflict, class)
+}
+
+func TestErrorCodeClassifierHostScope(t *testing.T) {
+	withClassifiers(t)
+	RegisterErrorClassifier(ErrorCodeClassifier([]string{".blob.core.windows.net"}, map[string]ErrorClass{"ServerBusy": ErrorClassThrottled}))
+	RegisterErrorClassifier(ErrorCodeClassifier([]string{".vault.azure.net"}, map[string]ErrorClass{"ServerBusy": ErrorClassConflict}))
+
+	require.True(t, IsThrottled(newTestResponseErrorFromHost("contoso.BLOB.core.windows.net:443", http.StatusBadRequest, "ServerBusy", nil)))
+	require.True(t, IsConflict(newTestResponseErrorFromHost("contoso.vault.azure.net", http.StatusBadRequest, "ServerBusy", nil)))
+	_, ok := Classify(newTestResponseErrorFromHost("contoso.queue.core.windows.net", http.StatusBadRequest, "ServerBusy", nil))
+	require.False(t, ok)
+	_, ok = Classify(&ResponseError{StatusCode: http.StatusBadRequest, ErrorCode: "ServerBusy"})
+	require.False(t, ok)
+
+	// no host suffixes matches nothing
+	_, ok = ErrorCodeClassifier(nil, map[string]ErrorClass{"ServerBusy": ErrorClassThrottled})(newTestResponseError(http.StatusBadRequest, "ServerBusy", nil))
+	require.False(t, ok)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+)
+
+// ReadModifyWriteOptions contains the optional values for [ReadModifyWrite].
+type ReadModifyWriteOptions struct {
+	// MaxAttempts is the maximum number of read-modify-write cycles.
+	// The default value is 5.
+	MaxAttempts int
+
+	// RetryDelay is the delay before reading the resource again after a write failed its precondition.
+	// The default value is zero, reading the resource again immediately.
+	RetryDelay time.Duration
+}
+
+// ReadModifyWrite updates a resource using optimistic concurrency. It reads the resource and its ETag,
+// applies modify to it, and writes it back on the condition that its ETag hasn't changed. If the write
+// fails with status code 412 (Precondition Failed), the cycle starts over with a fresh read, up to
+// MaxAttempts times. The result of the successful write is returned.
+//
+// When read returns an empty ETag, the resource is considered to not exist and write is called with
+// IfNoneMatch set to [ETagAny], so it fails if the resource was created concurrently. As some services
+// report this with status code 409 (Conflict), it's also retried in this case. Otherwise, write is
+// called with IfMatch set to the ETag. Errors from read, modify, and write are returned as-is, except
+// for a precondition failure on the final attempt which is wrapped.
+//   - ctx controls the lifetime of the operation
+//   - read retrieves the current state of the resource and its ETag
+//   - modify returns the updated resource; it can be called more than once
+//   - write stores the updated resource; it must send the specified match conditions
+//   - options contains optional configuration, pass nil to accept the default values
+func ReadModifyWrite[T, R any](ctx context.Context,
+	read func(context.Context) (T, ETag, error),
+	modify func(T) (T, error),
+	write func(context.Context, T, MatchConditions) (R, error),
+	options *ReadModifyWriteOptions) (R, error) {
+	if options == nil {
+		options = &ReadModifyWriteOptions{}
+	}
+	maxAttempts := options.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+
+	var zero R
+	for attempt := 1; ; attempt++ {
+		current, etag, err := read(ctx)
+		if err != nil {
+			return zero, err
+		}
+		updated, err := modify(current)
+		if err != nil {
+			return zero, err
+		}
+		conditions := MatchConditions{}
+		if etag == "" {
+			conditions.IfNoneMatch = to.Ptr(ETagAny)
+		} else {
+			conditions.IfMatch = to.Ptr(etag)
+		}
+		result, err := write(ctx, updated, conditions)
+		if err == nil {
+			return result, nil
+		}
+		var respErr *exported.ResponseError
+		if !errors.As(err, &respErr) || (respErr.StatusCode != http.StatusPreconditionFailed && (etag != "" || respErr.StatusCode != http.StatusConflict)) {
+			return zero, err
+		}
+		if attempt == maxAttempts {
+			return zero, fmt.Errorf("resource was modified concurrently, giving up after %d attempts: %w", attempt, err)
+		}
+		if options.RetryDelay > 0 {
+			if err := shared.Delay(ctx, options.RetryDelay); err != nil {
+				return zero, err
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/stretchr/testify/require"
+)
+
+// etagStore is a resource store that supports conditional writes
+type etagStore struct {
+	mu      sync.Mutex
+	value   int
+	etag    ETag
+	version int
+	// beforeWrite is called before evaluating the conditions of a write
+	beforeWrite func()
+}
+
+func newPreconditionError(statusCode int) error {
+	req, _ := http.NewRequest(http.MethodPut, "https://contoso.com/resource", nil)
+	return exported.NewResponseError(&http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	})
+}
+
+func (s *etagStore) read(ctx context.Context) (int, ETag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value, s.etag, nil
+}
+
+func (s *etagStore) write(ctx context.Context, value int, conditions MatchConditions) (ETag, error) {
+	if s.beforeWrite != nil {
+		s.beforeWrite()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conditions.IfMatch != nil && *conditions.IfMatch != s.etag {
+		return "", newPreconditionError(http.StatusPreconditionFailed)
+	}
+	if conditions.IfNoneMatch != nil && *conditions.IfNoneMatch == ETagAny && s.etag != "" {
+		return "", newPreconditionError(http.StatusConflict)
+	}
+	s.version++
+	s.value = value
+	s.etag = ETag(fmt.Sprintf(`"%d"`, s.version))
+	return s.etag, nil
+}
+
+func (s *etagStore) set(value int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	s.value = value
+	s.etag = ETag(fmt.Sprintf(`"%d"`, s.version))
+}
+
+func increment(v int) (int, error) {
+	return v + 1, nil
+}
+
+func TestReadModifyWrite(t *testing.T) {
+	store := &etagStore{}
+	store.set(1)
+	etag, err := ReadModifyWrite(context.Background(), store.read, increment, store.write, nil)
+	require.NoError(t, err)
+	require.Equal(t, ETag(`"2"`), etag)
+	require.Equal(t, 2, store.value)
+}
+
+func TestReadModifyWriteConcurrent(t *testing.T) {
+	store := &etagStore{}
+	store.set(0)
+	const workers = 10
+	wg := sync.WaitGroup{}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ReadModifyWrite(context.Background(), store.read, increment, store.write, &ReadModifyWriteOptions{MaxAttempts: workers + 1})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, workers, store.value)
+}
+
+func TestReadModifyWriteConflict(t *testing.T) {
+	store := &etagStore{}
+	store.set(0)
+	// another writer always modifies the resource between the read and the write
+	store.beforeWrite = func() { store.set(100) }
+	reads := 0
+	read := func(ctx context.Context) (int, ETag, error) {
+		reads++
+		return store.read(ctx)
+	}
+	_, err := ReadModifyWrite(context.Background(), read, increment, store.write, &ReadModifyWriteOptions{MaxAttempts: 3})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "giving up after 3 attempts")
+	var respErr *ResponseError
+	require.ErrorAs(t, err, &respErr)
+	require.Equal(t, http.StatusPreconditionFailed, respErr.StatusCode)
+	require.Equal(t, 3, reads)
+}
+
+func TestReadModifyWriteCreate(t *testing.T) {
+	store := &etagStore{}
+	// the resource is created between the first read and write
+	store.beforeWrite = func() {
+		store.beforeWrite = nil
+		store.set(10)
+	}
+	var conditions []MatchConditions
+	write := func(ctx context.Context, value int, mc MatchConditions) (ETag, error) {
+		conditions = append(conditions, mc)
+		return store.write(ctx, value, mc)
+	}
+	_, err := ReadModifyWrite(context.Background(), store.read, increment, write, nil)
+	require.NoError(t, err)
+	require.Equal(t, 11, store.value)
+	require.Len(t, conditions, 2)
+	require.Nil(t, conditions[0].IfMatch)
+	require.Equal(t, ETagAny, *conditions[0].IfNoneMatch)
+	require.Equal(t, ETag(`"1"`), *conditions[1].IfMatch)
+	require.Nil(t, conditions[1].IfNoneMatch)
+}
+
+func TestReadModifyWriteErrors(t *testing.T) {
+	store := &etagStore{}
+	store.set(0)
+	readErr := errors.New("read failed")
+	_, err := ReadModifyWrite(context.Background(), func(context.Context) (int, ETag, error) {
+		return 0, "", readErr
+	}, increment, store.write, nil)
+	require.ErrorIs(t, err, readErr)
+
+	modifyErr := errors.New("modify failed")
+	_, err = ReadModifyWrite(context.Background(), store.read, func(int) (int, error) {
+		return 0, modifyErr
+	}, store.write, nil)
+	require.ErrorIs(t, err, modifyErr)
+
+	// a conflict isn't retried when updating an existing resource
+	writes := 0
+	_, err = ReadModifyWrite(context.Background(), store.read, increment, func(context.Context, int, MatchConditions) (ETag, error) {
+		writes++
+		return "", newPreconditionError(http.StatusConflict)
+	}, nil)
+	var respErr *ResponseError
+	require.ErrorAs(t, err, &respErr)
+	require.Equal(t, http.StatusConflict, respErr.StatusCode)
+	require.Equal(t, 1, writes)
+	require.False(t, strings.Contains(err.Error(), "giving up"))
+}
+
+func TestReadModifyWriteCancelled(t *testing.T) {
+	store := &etagStore{}
+	store.set(0)
+	store.beforeWrite = func() { store.set(100) }
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ReadModifyWrite(ctx, store.read, increment, store.write, &ReadModifyWriteOptions{RetryDelay: time.Hour})
+	require.ErrorIs(t, err, context.Canceled)
+}
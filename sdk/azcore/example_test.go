@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
@@ -86,3 +87,86 @@ func ExampleResponseError() {
 	// Do something with response
 	fmt.Println(io.ReadAll(resp.Body))
 }
+
+// amqpError stands in for the error type of an AMQP-based module such as azservicebus or azeventhubs
+type amqpError struct {
+	Condition string
+}
+
+func (e *amqpError) Error() string {
+	return e.Condition
+}
+
+func ExampleRegisterErrorClassifier() {
+	// a Storage module maps its error codes for the hosts of its service
+	azcore.RegisterErrorClassifier(azcore.ErrorCodeClassifier(
+		[]string{".blob.core.windows.net", ".blob.core.chinacloudapi.cn", ".blob.core.usgovcloudapi.net"},
+		map[string]azcore.ErrorClass{
+			"BlobNotFound":                     azcore.ErrorClassNotFound,
+			"ContainerNotFound":                azcore.ErrorClassNotFound,
+			"BlobAlreadyExists":                azcore.ErrorClassConflict,
+			"ContainerAlreadyExists":           azcore.ErrorClassConflict,
+			"LeaseIdMismatchWithBlobOperation": azcore.ErrorClassPreconditionFailed,
+			"ConditionNotMet":                  azcore.ErrorClassPreconditionFailed,
+			"ServerBusy":                       azcore.ErrorClassThrottled,
+			"OperationTimedOut":                azcore.ErrorClassTransient,
+			"AuthorizationFailure":             azcore.ErrorClassAuthFailure,
+		},
+	))
+
+	// Key Vault reports some missing resources with status code 400
+	azcore.RegisterErrorClassifier(azcore.ErrorCodeClassifier(
+		[]string{".vault.azure.net", ".managedhsm.azure.net"},
+		map[string]azcore.ErrorClass{
+			"SecretNotFound":      azcore.ErrorClassNotFound,
+			"KeyNotFound":         azcore.ErrorClassNotFound,
+			"CertificateNotFound": azcore.ErrorClassNotFound,
+			"Forbidden":           azcore.ErrorClassAuthFailure,
+			"Throttled":           azcore.ErrorClassThrottled,
+		},
+	))
+
+	// Cosmos DB qualifies status codes with the x-ms-substatus header
+	azcore.RegisterErrorClassifier(func(err error) (azcore.ErrorClass, bool) {
+		var respErr *azcore.ResponseError
+		if !errors.As(err, &respErr) || respErr.RawResponse == nil || respErr.RawResponse.Request == nil ||
+			!strings.HasSuffix(respErr.RawResponse.Request.URL.Hostname(), ".documents.azure.com") {
+			return "", false
+		}
+		switch substatus := respErr.RawResponse.Header.Get("x-ms-substatus"); {
+		case respErr.StatusCode == http.StatusForbidden && substatus == "3":
+			// write forbidden, the account is failing over
+			return azcore.ErrorClassTransient, true
+		case respErr.StatusCode == http.StatusNotFound && substatus == "1002":
+			// read session not available, the replica hasn't caught up
+			return azcore.ErrorClassTransient, true
+		}
+		return "", false
+	})
+
+	// AMQP-based modules such as azservicebus and azeventhubs classify their own error type
+	azcore.RegisterErrorClassifier(func(err error) (azcore.ErrorClass, bool) {
+		var amqpErr *amqpError
+		if !errors.As(err, &amqpErr) {
+			return "", false
+		}
+		switch amqpErr.Condition {
+		case "amqp:not-found", "com.microsoft:entity-disabled":
+			return azcore.ErrorClassNotFound, true
+		case "amqp:unauthorized-access":
+			return azcore.ErrorClassAuthFailure, true
+		case "com.microsoft:server-busy":
+			return azcore.ErrorClassThrottled, true
+		case "com.microsoft:timeout", "amqp:connection:forced":
+			return azcore.ErrorClassTransient, true
+		}
+		return "", false
+	})
+
+	// callers then handle errors from all of these services the same way
+	var err error = &amqpError{Condition: "com.microsoft:server-busy"}
+	if azcore.IsTransient(err) {
+		fmt.Println("try again later")
+	}
+	// Output: try again later
+}
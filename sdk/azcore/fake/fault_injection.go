@@ -0,0 +1,245 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// Fault injects a failure into an HTTP request.
+// A Fault can send the request with next, modify the response, or return its own response or error.
+type Fault func(req *http.Request, next policy.Transporter) (*http.Response, error)
+
+// LatencyFault returns a [Fault] that delays the request by the specified duration before sending it.
+// The delay ends early if the request's context is done.
+func LatencyFault(delay time.Duration) Fault {
+	return func(req *http.Request, next policy.Transporter) (*http.Response, error) {
+		if err := shared.Delay(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		return next.Do(req)
+	}
+}
+
+// ConnectionResetFault returns a [Fault] that fails the request with a connection reset
+// error without sending it. The error is a *net.OpError wrapping syscall.ECONNRESET.
+func ConnectionResetFault() Fault {
+	return func(req *http.Request, next policy.Transporter) (*http.Response, error) {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	}
+}
+
+// TruncatedBodyFault returns a [Fault] that sends the request and truncates the response body.
+// Reading the body returns at most n bytes followed by [io.ErrUnexpectedEOF], as happens when a
+// connection is closed while a response is being read. When the body is n bytes or shorter, all of it
+// is returned before the error. A negative n truncates the body by half.
+func TruncatedBodyFault(n int) Fault {
+	return func(req *http.Request, next policy.Transporter) (*http.Response, error) {
+		resp, err := next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := readBody(&resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		size := n
+		if size < 0 {
+			size = len(body) / 2
+		} else if size > len(body) {
+			size = len(body)
+		}
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body[:size]), errorReader{err: io.ErrUnexpectedEOF}))
+		return resp, nil
+	}
+}
+
+// ThrottlingFault returns a [Fault] that responds with the specified status code and a Retry-After
+// header without sending the request. Use it with http.StatusTooManyRequests or http.StatusServiceUnavailable.
+// The Retry-After header contains the delay in whole seconds, rounded up. When the delay isn't a whole
+// number of seconds, a Retry-After-Ms header with the delay in milliseconds is also included.
+func ThrottlingFault(statusCode int, retryAfter time.Duration) Fault {
+	return func(req *http.Request, next policy.Transporter) (*http.Response, error) {
+		header := http.Header{}
+		seconds := (retryAfter + time.Second - 1) / time.Second
+		header.Set(shared.HeaderRetryAfter, strconv.FormatInt(int64(seconds), 10))
+		if retryAfter%time.Second != 0 {
+			header.Set(shared.HeaderRetryAfterMS, strconv.FormatInt(retryAfter.Milliseconds(), 10))
+		}
+		return newFaultResponse(req, statusCode, header, nil), nil
+	}
+}
+
+// MalformedJSONFault returns a [Fault] that sends the request and replaces the response
+// body with a document that isn't valid JSON. The status code and headers are unchanged.
+func MalformedJSONFault() Fault {
+	return func(req *http.Request, next policy.Transporter) (*http.Response, error) {
+		resp, err := next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = readBody(&resp.Body); err != nil {
+			return nil, err
+		}
+		body := []byte(`{"malformed": [1, 2,`)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		if resp.Header == nil {
+			resp.Header = http.Header{}
+		}
+		resp.Header.Set(shared.HeaderContentLength, strconv.Itoa(len(body)))
+		return resp, nil
+	}
+}
+
+func newFaultResponse(req *http.Request, statusCode int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// errorReader returns err from every call to Read
+type errorReader struct {
+	err error
+}
+
+func (e errorReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// FaultRule describes which requests a [Fault] is injected into.
+type FaultRule struct {
+	// Fault is the fault to inject. This field is required.
+	Fault Fault
+
+	// Method is the HTTP method of the requests the rule applies to, compared case-insensitively.
+	// The default value matches all methods.
+	Method string
+
+	// URLPattern matches the URLs of the requests the rule applies to.
+	// The default value matches all URLs.
+	URLPattern *regexp.Regexp
+
+	// Probability is the share of matching requests the fault is injected into, between 0 and 1.
+	// The default value is 1, injecting the fault into all matching requests. Because 0 selects
+	// the default, set Disabled instead to stop injecting a rule's fault.
+	Probability float64
+
+	// Disabled turns off the rule so that its fault isn't injected into any request.
+	Disabled bool
+}
+
+// FaultInjectorOptions contains the optional values for [NewFaultInjector].
+type FaultInjectorOptions struct {
+	// Seed seeds the random number generator that decides if a fault is injected
+	// into a request. Use a nonzero value for reproducible results.
+	// The default value seeds the generator randomly.
+	Seed int64
+}
+
+// FaultInjector is a [policy.Transporter] that injects faults into some of the requests
+// it sends with another Transporter. Rules are evaluated in order and at most one fault
+// is injected per request. Requests that don't receive a fault are sent unmodified.
+// Don't use this type directly, use [NewFaultInjector] instead.
+type FaultInjector struct {
+	transport policy.Transporter
+	rules     []FaultRule
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	injected int
+}
+
+// NewFaultInjector creates a new instance of [FaultInjector].
+//   - transport sends the requests; it can be a real or a fake transport
+//   - rules describe which faults are injected into which requests
+//   - options contains optional configuration, pass nil to accept the default values
+func NewFaultInjector(transport policy.Transporter, rules []FaultRule, options *FaultInjectorOptions) (*FaultInjector, error) {
+	if options == nil {
+		options = &FaultInjectorOptions{}
+	}
+	if transport == nil {
+		return nil, errors.New("transport can't be nil")
+	}
+	for i, rule := range rules {
+		if rule.Fault == nil {
+			return nil, fmt.Errorf("rule %d has no fault", i)
+		}
+		if rule.Probability < 0 || rule.Probability > 1 {
+			return nil, fmt.Errorf("rule %d has invalid probability %v", i, rule.Probability)
+		}
+	}
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultInjector{
+		transport: transport,
+		rules:     append([]FaultRule{}, rules...),
+		rand:      rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Do implements the [policy.Transporter] interface for [FaultInjector].
+func (f *FaultInjector) Do(req *http.Request) (*http.Response, error) {
+	if fault := f.selectFault(req); fault != nil {
+		return fault(req, f.transport)
+	}
+	return f.transport.Do(req)
+}
+
+// FaultsInjected returns the number of requests that a fault has been injected into.
+func (f *FaultInjector) FaultsInjected() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.injected
+}
+
+// selectFault returns the fault to inject into the request or nil
+func (f *FaultInjector) selectFault(req *http.Request) Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rule := range f.rules {
+		if rule.Disabled {
+			continue
+		}
+		if rule.Method != "" && !strings.EqualFold(rule.Method, req.Method) {
+			continue
+		}
+		if rule.URLPattern != nil && !rule.URLPattern.MatchString(req.URL.String()) {
+			continue
+		}
+		if rule.Probability > 0 && f.rand.Float64() >= rule.Probability {
+			continue
+		}
+		f.injected++
+		return rule.Fault
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newFaultInjectionClient(t *testing.T, transport policy.Transporter, maxRetries int32) *azcore.Client {
+	client, err := azcore.NewClient("module", "v1.0.0", runtime.PipelineOptions{}, &azcore.ClientOptions{
+		Retry: policy.RetryOptions{
+			MaxRetries:    maxRetries,
+			RetryDelay:    time.Millisecond,
+			MaxRetryDelay: 10 * time.Millisecond,
+		},
+		Transport: transport,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestFaultInjectorRetries(t *testing.T) {
+	for name, fault := range map[string]fake.Fault{
+		"connectionReset": fake.ConnectionResetFault(),
+		"throttled":       fake.ThrottlingFault(http.StatusTooManyRequests, time.Millisecond),
+		"unavailable":     fake.ThrottlingFault(http.StatusServiceUnavailable, time.Millisecond),
+		"truncatedBody":   fake.TruncatedBodyFault(-1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv, close := mock.NewServer()
+			defer close()
+			srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"name": "widget"}`)))
+
+			// inject the fault into the first request only
+			first := true
+			fi, err := fake.NewFaultInjector(srv, []fake.FaultRule{{
+				Fault: func(req *http.Request, next policy.Transporter) (*http.Response, error) {
+					if first {
+						first = false
+						return fault(req, next)
+					}
+					return next.Do(req)
+				},
+			}}, nil)
+			require.NoError(t, err)
+
+			client := newFaultInjectionClient(t, fi, 3)
+			req, err := runtime.NewRequest(context.Background(), http.MethodGet, srv.URL())
+			require.NoError(t, err)
+			resp, err := client.Pipeline().Do(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			body, err := runtime.Payload(resp)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"name": "widget"}`, string(body))
+			require.Equal(t, 2, fi.FaultsInjected())
+		})
+	}
+}
+
+func TestFaultInjectorRules(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"name": "widget"}`)))
+
+	fi, err := fake.NewFaultInjector(srv, []fake.FaultRule{
+		{Method: http.MethodPut, Fault: fake.ConnectionResetFault()},
+		{URLPattern: regexp.MustCompile(`/malformed$`), Fault: fake.MalformedJSONFault()},
+	}, nil)
+	require.NoError(t, err)
+	client := newFaultInjectionClient(t, fi, -1)
+
+	req, err := runtime.NewRequest(context.Background(), http.MethodPut, srv.URL()+"/malformed")
+	require.NoError(t, err)
+	_, err = client.Pipeline().Do(req)
+	require.ErrorIs(t, err, syscall.ECONNRESET)
+
+	req, err = runtime.NewRequest(context.Background(), http.MethodGet, srv.URL()+"/malformed")
+	require.NoError(t, err)
+	resp, err := client.Pipeline().Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var v map[string]any
+	require.Error(t, runtime.UnmarshalAsJSON(resp, &v))
+
+	req, err = runtime.NewRequest(context.Background(), http.MethodGet, srv.URL()+"/ok")
+	require.NoError(t, err)
+	resp, err = client.Pipeline().Do(req)
+	require.NoError(t, err)
+	require.NoError(t, runtime.UnmarshalAsJSON(resp, &v))
+	require.Equal(t, 2, fi.FaultsInjected())
+	require.EqualValues(t, 2, srv.Requests())
+}
+
+func TestFaultInjectorProbability(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+
+	fi, err := fake.NewFaultInjector(srv, []fake.FaultRule{
+		{Fault: fake.ThrottlingFault(http.StatusTooManyRequests, 1500*time.Millisecond), Probability: 0.25},
+	}, &fake.FaultInjectorOptions{Seed: 42})
+	require.NoError(t, err)
+
+	throttled := 0
+	for range 400 {
+		req, err := http.NewRequest(http.MethodGet, srv.URL(), nil)
+		require.NoError(t, err)
+		resp, err := fi.Do(req)
+		require.NoError(t, err)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			throttled++
+			require.Equal(t, "2", resp.Header.Get("Retry-After"))
+			require.Equal(t, "1500", resp.Header.Get("Retry-After-Ms"))
+		}
+	}
+	require.Equal(t, throttled, fi.FaultsInjected())
+	require.InDelta(t, 100, throttled, 40)
+	require.EqualValues(t, 400-throttled, srv.Requests())
+}
+
+func TestFaultInjectorLatency(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+
+	fi, err := fake.NewFaultInjector(srv, []fake.FaultRule{{Fault: fake.LatencyFault(time.Hour)}}, nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL(), nil)
+	require.NoError(t, err)
+	_, err = fi.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, srv.Requests())
+}
+
+func TestFaultInjectorTruncatedBody(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte("0123456789")))
+
+	fi, err := fake.NewFaultInjector(srv, []fake.FaultRule{{Fault: fake.TruncatedBodyFault(3)}}, nil)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, srv.URL(), nil)
+	require.NoError(t, err)
+	resp, err := fi.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Equal(t, "012", string(body))
+
+	// a body no longer than n is returned whole before the error
+	fi, err = fake.NewFaultInjector(srv, []fake.FaultRule{{Fault: fake.TruncatedBodyFault(20)}}, nil)
+	require.NoError(t, err)
+	resp, err = fi.Do(req)
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Equal(t, "0123456789", string(body))
+}
+
+func TestFaultInjectorDisabledRule(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+
+	fi, err := fake.NewFaultInjector(srv, []fake.FaultRule{
+		{Fault: fake.ConnectionResetFault(), Disabled: true},
+	}, nil)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, srv.URL(), nil)
+	require.NoError(t, err)
+	resp, err := fi.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Zero(t, fi.FaultsInjected())
+}
+
+func TestMalformedJSONFaultNilHeader(t *testing.T) {
+	transport := shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	fi, err := fake.NewFaultInjector(transport, []fake.FaultRule{{Fault: fake.MalformedJSONFault()}}, nil)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, "https://contoso.com", nil)
+	require.NoError(t, err)
+	resp, err := fi.Do(req)
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Header.Get("Content-Length"))
+}
+
+func TestNewFaultInjectorErrors(t *testing.T) {
+	_, err := fake.NewFaultInjector(nil, nil, nil)
+	require.Error(t, err)
+	_, err = fake.NewFaultInjector(http.DefaultClient, []fake.FaultRule{{}}, nil)
+	require.Error(t, err)
+	_, err = fake.NewFaultInjector(http.DefaultClient, []fake.FaultRule{{Fault: fake.ConnectionResetFault(), Probability: 2}}, nil)
+	require.Error(t, err)
+}
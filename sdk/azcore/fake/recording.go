@@ -0,0 +1,472 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/errorinfo"
+)
+
+// Cassette contains the HTTP interactions recorded by a [Recorder].
+// It's serialized as JSON and replayed by a [Replayer].
+type Cassette struct {
+	// Interactions contains the recorded interactions in the order in which they were sent.
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette from the JSON file at the specified path.
+func LoadCassette(path string) (*Cassette, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(content, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as JSON to the file at the specified path, replacing any existing file.
+func (c *Cassette) Save(path string) error {
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// Interaction is a recorded HTTP request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is an HTTP request within an [Interaction].
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// RecordedResponse is an HTTP response within an [Interaction].
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body is a recorded HTTP body. It's serialized as a JSON string when the body
+// is valid UTF-8, else as an object containing the base64 encoded bytes.
+type Body []byte
+
+// MarshalJSON implements the json.Marshaller interface for Body.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(struct {
+		Base64 string `json:"base64"`
+	}{Base64: base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements the json.Unmarshaller interface for Body.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// redactedValue replaces sanitized values
+const redactedValue = "REDACTED"
+
+// Sanitizer removes secrets from an [Interaction] before it's recorded.
+// A [Replayer] applies the same sanitizers to the requests it receives before matching them.
+type Sanitizer func(*Interaction)
+
+// DefaultSanitizers returns the sanitizers used when none are specified. They redact
+// credential headers and the signature of shared access signatures.
+func DefaultSanitizers() []Sanitizer {
+	return []Sanitizer{
+		SanitizeHeaders("Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "x-ms-authorization-auxiliary", "api-key", "Ocp-Apim-Subscription-Key"),
+		SanitizeQueryParams("sig"),
+	}
+}
+
+// SanitizeHeaders returns a [Sanitizer] that redacts the values of the specified request and response headers.
+func SanitizeHeaders(names ...string) Sanitizer {
+	return func(i *Interaction) {
+		for _, name := range names {
+			for _, header := range []http.Header{i.Request.Header, i.Response.Header} {
+				if header.Get(name) != "" {
+					header.Set(name, redactedValue)
+				}
+			}
+		}
+	}
+}
+
+// urlResponseHeaders are response headers containing a URL that can include query parameters to sanitize
+var urlResponseHeaders = []string{"Location", "Azure-AsyncOperation", "Operation-Location"}
+
+// SanitizeQueryParams returns a [Sanitizer] that redacts the values of the specified query parameters
+// in the request URL and in the Location, Azure-AsyncOperation and Operation-Location response headers.
+// Parameter names are case-insensitive.
+func SanitizeQueryParams(names ...string) Sanitizer {
+	sanitize := func(rawURL string) string {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.RawQuery == "" {
+			return rawURL
+		}
+		qp := u.Query()
+		changed := false
+		for k := range qp {
+			for _, name := range names {
+				if strings.EqualFold(k, name) {
+					qp.Set(k, redactedValue)
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			return rawURL
+		}
+		u.RawQuery = qp.Encode()
+		return u.String()
+	}
+	return func(i *Interaction) {
+		i.Request.URL = sanitize(i.Request.URL)
+		for _, name := range urlResponseHeaders {
+			if v := i.Response.Header.Get(name); v != "" {
+				i.Response.Header.Set(name, sanitize(v))
+			}
+		}
+	}
+}
+
+// SanitizeBodies returns a [Sanitizer] that replaces all matches of the regular expression in the
+// request and response bodies with the replacement text. See [regexp.Regexp.ReplaceAll] for the
+// syntax of the replacement.
+func SanitizeBodies(re *regexp.Regexp, replacement string) Sanitizer {
+	return func(i *Interaction) {
+		if len(i.Request.Body) > 0 {
+			i.Request.Body = re.ReplaceAll(i.Request.Body, []byte(replacement))
+		}
+		if len(i.Response.Body) > 0 {
+			i.Response.Body = re.ReplaceAll(i.Response.Body, []byte(replacement))
+		}
+	}
+}
+
+func sanitize(i *Interaction, sanitizers []Sanitizer) {
+	for _, s := range sanitizers {
+		s(i)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// RecorderOptions contains the optional values for [NewRecorder].
+type RecorderOptions struct {
+	// Sanitizers are applied to each interaction before it's recorded.
+	// The default value is the result of [DefaultSanitizers].
+	Sanitizers []Sanitizer
+}
+
+// Recorder is a [policy.Transporter] that sends requests with another
+// Transporter and records the interactions in a [Cassette].
+// Don't use this type directly, use [NewRecorder] instead.
+type Recorder struct {
+	transport  policy.Transporter
+	sanitizers []Sanitizer
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder creates a new instance of [Recorder].
+//   - transport sends the requests; pass nil to use [http.DefaultClient]
+//   - options contains optional configuration, pass nil to accept the default values
+func NewRecorder(transport policy.Transporter, options *RecorderOptions) *Recorder {
+	if options == nil {
+		options = &RecorderOptions{}
+	}
+	if transport == nil {
+		transport = http.DefaultClient
+	}
+	sanitizers := options.Sanitizers
+	if sanitizers == nil {
+		sanitizers = DefaultSanitizers()
+	}
+	return &Recorder{
+		transport:  transport,
+		sanitizers: sanitizers,
+	}
+}
+
+// Do implements the [policy.Transporter] interface for [Recorder].
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.transport.Do(req)
+	if err != nil {
+		// failed requests aren't recorded
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	i := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   reqBody,
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       respBody,
+		},
+	}
+	sanitize(&i, r.sanitizers)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	return resp, nil
+}
+
+// Cassette returns a copy of the interactions recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction{}, r.cassette.Interactions...)}
+}
+
+// readBody reads and replaces the body so that it can be read again
+func readBody(body *io.ReadCloser) (Body, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	content, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(content))
+	return content, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// RequestMatcher returns true when a request received by a [Replayer] matches a recorded request.
+// The request has been sanitized with the Replayer's sanitizers before it's passed to the matcher.
+type RequestMatcher func(req RecordedRequest, recorded RecordedRequest) bool
+
+// RequestMatcherOptions contains the optional values for [NewRequestMatcher].
+type RequestMatcherOptions struct {
+	// IgnoredQueryParams contains the names of query parameters that aren't compared.
+	// Names are case-insensitive.
+	IgnoredQueryParams []string
+
+	// Headers contains the names of request headers whose values must match.
+	// By default, headers aren't compared.
+	Headers []string
+
+	// CompareBodies indicates the request bodies must match.
+	// The default value is false.
+	CompareBodies bool
+}
+
+// NewRequestMatcher creates a [RequestMatcher] that compares the method, the URL and the optional values.
+// Query parameters are compared regardless of their order.
+//   - options contains optional configuration, pass nil to accept the default values
+func NewRequestMatcher(options *RequestMatcherOptions) RequestMatcher {
+	if options == nil {
+		options = &RequestMatcherOptions{}
+	}
+	ignored := map[string]struct{}{}
+	for _, qp := range options.IgnoredQueryParams {
+		ignored[strings.ToLower(qp)] = struct{}{}
+	}
+	normalizeURL := func(rawURL string) (string, url.Values) {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return rawURL, nil
+		}
+		qp := url.Values{}
+		for k, v := range u.Query() {
+			if _, ok := ignored[strings.ToLower(k)]; !ok {
+				qp[k] = v
+			}
+		}
+		u.RawQuery = ""
+		u.Fragment = ""
+		return u.String(), qp
+	}
+	headers := append([]string{}, options.Headers...)
+	compareBodies := options.CompareBodies
+	return func(req RecordedRequest, recorded RecordedRequest) bool {
+		if !strings.EqualFold(req.Method, recorded.Method) {
+			return false
+		}
+		reqURL, reqQP := normalizeURL(req.URL)
+		recURL, recQP := normalizeURL(recorded.URL)
+		if !strings.EqualFold(reqURL, recURL) || reqQP.Encode() != recQP.Encode() {
+			return false
+		}
+		for _, h := range headers {
+			if req.Header.Get(h) != recorded.Header.Get(h) {
+				return false
+			}
+		}
+		return !compareBodies || bytes.Equal(req.Body, recorded.Body)
+	}
+}
+
+// ReplayerOptions contains the optional values for [NewReplayer].
+type ReplayerOptions struct {
+	// Matcher determines if a request matches a recorded request.
+	// The default value is the result of NewRequestMatcher(nil).
+	Matcher RequestMatcher
+
+	// Sanitizers are applied to each request before it's matched. They should be the
+	// same as the sanitizers used when recording so that redacted values match.
+	// The default value is the result of [DefaultSanitizers].
+	Sanitizers []Sanitizer
+}
+
+// Replayer is a [policy.Transporter] that returns the responses recorded in a [Cassette]
+// without sending any requests. Each recorded interaction is returned once. A request
+// gets the response of the first unused interaction that matches it, so repeated
+// requests (e.g. polling a long-running operation) are replayed in the recorded order.
+// Don't use this type directly, use [NewReplayer] instead.
+type Replayer struct {
+	matcher    RequestMatcher
+	sanitizers []Sanitizer
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer creates a new instance of [Replayer].
+//   - cassette contains the interactions to replay; a nil cassette has no interactions
+//   - options contains optional configuration, pass nil to accept the default values
+func NewReplayer(cassette *Cassette, options *ReplayerOptions) *Replayer {
+	if options == nil {
+		options = &ReplayerOptions{}
+	}
+	matcher := options.Matcher
+	if matcher == nil {
+		matcher = NewRequestMatcher(nil)
+	}
+	sanitizers := options.Sanitizers
+	if sanitizers == nil {
+		sanitizers = DefaultSanitizers()
+	}
+	interactions := []Interaction{}
+	if cassette != nil {
+		interactions = append(interactions, cassette.Interactions...)
+	}
+	return &Replayer{
+		matcher:      matcher,
+		sanitizers:   sanitizers,
+		interactions: interactions,
+		used:         make([]bool, len(interactions)),
+	}
+}
+
+// Do implements the [policy.Transporter] interface for [Replayer].
+// An error is returned if no unused interaction matches the request.
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	i := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   reqBody,
+		},
+		Response: RecordedResponse{Header: http.Header{}},
+	}
+	sanitize(&i, r.sanitizers)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for idx, recorded := range r.interactions {
+		if r.used[idx] || !r.matcher(i.Request, recorded.Request) {
+			continue
+		}
+		r.used[idx] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", recorded.Response.StatusCode, http.StatusText(recorded.Response.StatusCode)),
+			StatusCode:    recorded.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        recorded.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(recorded.Response.Body)),
+			ContentLength: int64(len(recorded.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, errorinfo.NonRetriableError(fmt.Errorf("no recorded interaction matches %s %s", i.Request.Method, i.Request.URL))
+}
+
+// Remaining returns the number of recorded interactions that haven't been replayed.
+// Use this to verify that all expected requests were sent.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// ensure the transports satisfy the interface
+var (
+	_ policy.Transporter = (*Recorder)(nil)
+	_ policy.Transporter = (*Replayer)(nil)
+)
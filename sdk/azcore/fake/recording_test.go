@@ -0,0 +1,149 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package fake_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/errorinfo"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func sendRequest(t *testing.T, transport policy.Transporter, method, url, body string) (*http.Response, error) {
+	client, err := azcore.NewClient("module", "v1.0.0", runtime.PipelineOptions{}, &azcore.ClientOptions{
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+		Transport: transport,
+	})
+	require.NoError(t, err)
+	req, err := runtime.NewRequest(context.Background(), method, url)
+	require.NoError(t, err)
+	req.Raw().Header.Set("Authorization", "Bearer secret")
+	if body != "" {
+		require.NoError(t, req.SetBody(streaming.NopCloser(strings.NewReader(body)), "application/json"))
+	}
+	return client.Pipeline().Do(req)
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated), mock.WithBody([]byte(`{"name": "widget", "key": "hunter2"}`)), mock.WithHeader("Location", srv.URL()+"/status?sv=2024&sig=abc123"))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusAccepted))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte{0xff, 0xfe, 0x00}))
+
+	recorder := fake.NewRecorder(srv, &fake.RecorderOptions{
+		Sanitizers: append(fake.DefaultSanitizers(), fake.SanitizeBodies(regexp.MustCompile(`"key": "[^"]*"`), `"key": "REDACTED"`)),
+	})
+	resp, err := sendRequest(t, recorder, http.MethodPut, srv.URL()+"/widgets/1?sig=secret&api-version=1", `{"name": "widget"}`)
+	require.NoError(t, err)
+	// the caller receives the unsanitized response
+	body, err := runtime.Payload(resp)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "hunter2")
+	for _, code := range []int{http.StatusAccepted, http.StatusOK} {
+		resp, err = sendRequest(t, recorder, http.MethodGet, srv.URL()+"/status", "")
+		require.NoError(t, err)
+		require.Equal(t, code, resp.StatusCode)
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	require.NoError(t, recorder.Cassette().Save(path))
+	cassette, err := fake.LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions, 3)
+
+	first := cassette.Interactions[0]
+	require.Equal(t, "REDACTED", first.Request.Header.Get("Authorization"))
+	require.Contains(t, first.Request.URL, "sig=REDACTED")
+	require.NotContains(t, first.Request.URL, "secret")
+	require.Equal(t, `{"name": "widget"}`, string(first.Request.Body))
+	require.Equal(t, `{"name": "widget", "key": "REDACTED"}`, string(first.Response.Body))
+	require.Contains(t, first.Response.Header.Get("Location"), "sig=REDACTED")
+	require.Equal(t, []byte{0xff, 0xfe, 0x00}, []byte(cassette.Interactions[2].Response.Body))
+
+	// replay without the server. query parameters can be in a different order
+	replayer := fake.NewReplayer(cassette, nil)
+	require.Equal(t, 3, replayer.Remaining())
+	resp, err = sendRequest(t, replayer, http.MethodPut, srv.URL()+"/widgets/1?api-version=1&sig=different", `{"name": "widget"}`)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	for _, code := range []int{http.StatusAccepted, http.StatusOK} {
+		resp, err = sendRequest(t, replayer, http.MethodGet, srv.URL()+"/status", "")
+		require.NoError(t, err)
+		require.Equal(t, code, resp.StatusCode)
+	}
+	require.Zero(t, replayer.Remaining())
+
+	// all interactions have been replayed
+	_, err = sendRequest(t, replayer, http.MethodGet, srv.URL()+"/status", "")
+	require.Error(t, err)
+	var nre errorinfo.NonRetriable
+	require.ErrorAs(t, err, &nre)
+	require.EqualValues(t, 3, srv.Requests())
+}
+
+func TestRequestMatcher(t *testing.T) {
+	recorded := fake.RecordedRequest{
+		Method: http.MethodPost,
+		URL:    "https://contoso.com/widgets?api-version=1&timestamp=1",
+		Header: http.Header{"X-Ms-Client-Request-Id": []string{"abc"}},
+		Body:   fake.Body(`{"a": 1}`),
+	}
+
+	matcher := fake.NewRequestMatcher(nil)
+	require.True(t, matcher(recorded, recorded))
+	req := recorded
+	req.Method = http.MethodGet
+	require.False(t, matcher(req, recorded))
+	req = recorded
+	req.URL = "https://CONTOSO.com/widgets?timestamp=1&api-version=1"
+	require.True(t, matcher(req, recorded))
+	req.URL = "https://contoso.com/widgets?api-version=1&timestamp=2"
+	require.False(t, matcher(req, recorded))
+	req.Body = fake.Body(`{"a": 2}`)
+	req.Header = http.Header{}
+
+	matcher = fake.NewRequestMatcher(&fake.RequestMatcherOptions{IgnoredQueryParams: []string{"Timestamp"}})
+	require.True(t, matcher(req, recorded))
+
+	matcher = fake.NewRequestMatcher(&fake.RequestMatcherOptions{IgnoredQueryParams: []string{"timestamp"}, CompareBodies: true})
+	require.False(t, matcher(req, recorded))
+
+	matcher = fake.NewRequestMatcher(&fake.RequestMatcherOptions{IgnoredQueryParams: []string{"timestamp"}, Headers: []string{"x-ms-client-request-id"}})
+	require.False(t, matcher(req, recorded))
+	req.Header.Set("x-ms-client-request-id", "abc")
+	require.True(t, matcher(req, recorded))
+}
+
+func TestReplayerCustomMatcher(t *testing.T) {
+	replayer := fake.NewReplayer(&fake.Cassette{
+		Interactions: []fake.Interaction{
+			{
+				Request:  fake.RecordedRequest{Method: http.MethodGet, URL: "https://contoso.com/a"},
+				Response: fake.RecordedResponse{StatusCode: http.StatusOK, Body: fake.Body("a")},
+			},
+		},
+	}, &fake.ReplayerOptions{
+		Matcher: func(req, recorded fake.RecordedRequest) bool {
+			return strings.HasSuffix(req.URL, "/b")
+		},
+	})
+	resp, err := sendRequest(t, replayer, http.MethodGet, "https://contoso.com/b", "")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "a", string(body))
+}
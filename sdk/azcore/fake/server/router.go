@@ -0,0 +1,322 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake/internal/exported"
+	fakepoller "github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/pollers/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/errorinfo"
+)
+
+// PathParams contains the values of the parameters in a path template, keyed by parameter name.
+// The values are unescaped.
+type PathParams map[string]string
+
+// Handler handles a request dispatched by a [Router].
+//   - req is the HTTP request
+//   - params contains the values of the parameters in the route's path template
+type Handler func(req *http.Request, params PathParams) (*http.Response, error)
+
+// Router is a [policy.Transporter] that dispatches requests to handlers by HTTP method and
+// URL path, so that fakes can be written for clients that don't have a generated fake server.
+// Use [HandleResponder], [HandlePager], and [HandlePoller] to register handlers that return
+// the values of [fake.Responder], [fake.PagerResponder], and [fake.PollerResponder]. Router
+// takes care of requests for the next pages of a pager and for the status of a long-running
+// operation, sending them to the handler that started the pager or operation.
+//
+// Path templates contain literal segments, which are matched case-insensitively, and parameter
+// segments such as {name}. The last segment can be a wildcard parameter such as {name...} that
+// matches the remainder of the path, including any slashes. Routes are matched in the order in
+// which they were registered; the host and query are ignored.
+//
+// A Router is safe for concurrent use once all routes have been registered.
+// Don't use this type directly, use [NewRouter] instead.
+type Router struct {
+	routes []*route
+}
+
+// NewRouter creates a new instance of [Router].
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers a handler for requests with the specified HTTP method and path template.
+// It panics if the path template is invalid.
+func (r *Router) Handle(method, pathTemplate string, handler Handler) {
+	r.handle(method, pathTemplate, routeDefault, handler)
+}
+
+func (r *Router) handle(method, pathTemplate string, kind routeKind, handler Handler) {
+	segments, err := parsePathTemplate(pathTemplate)
+	if err != nil {
+		panic(err)
+	}
+	r.routes = append(r.routes, &route{
+		method:   strings.ToUpper(method),
+		segments: segments,
+		kind:     kind,
+		handler:  handler,
+	})
+}
+
+// Do implements the [policy.Transporter] interface for [Router].
+func (r *Router) Do(req *http.Request) (*http.Response, error) {
+	path := req.URL.EscapedPath()
+	sanitized := SanitizePagerPollerPath(path)
+	// requests for subsequent pages and the status of an LRO are sent
+	// with GET, regardless of the method of the initial request
+	continuation := routeDefault
+	if exported.SanitizePagerPath(path) != path {
+		continuation = routePager
+	} else if fakepoller.SanitizePollerPath(path) != path {
+		continuation = routePoller
+	}
+
+	for _, rt := range r.routes {
+		if rt.method != req.Method && (continuation == routeDefault || rt.kind != continuation) {
+			continue
+		}
+		params, ok := rt.match(sanitized)
+		if !ok {
+			continue
+		}
+		return dispatch(req, func() (*http.Response, error) {
+			return rt.handler(req, params)
+		})
+	}
+	return nil, errorinfo.NonRetriableError(fmt.Errorf("fake: no handler registered for %s %s", req.Method, sanitized))
+}
+
+// dispatch calls handler and returns its result, or the request's error if its context is done first
+func dispatch(req *http.Request, handler func() (*http.Response, error)) (*http.Response, error) {
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	resultChan := make(chan result, 1)
+	go func() {
+		var res result
+		res.resp, res.err = handler()
+		resultChan <- res
+	}()
+
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case res := <-resultChan:
+		return res.resp, res.err
+	}
+}
+
+// HandleResponder registers a handler for requests with the specified HTTP method and path template.
+// The handler returns a [fake.Responder], whose value is marshalled as JSON, or a [fake.ErrorResponder].
+// To respond with other formats, use [Router.Handle] and a function such as [MarshalResponseAsXML].
+// It panics if the path template is invalid.
+func HandleResponder[T any](r *Router, method, pathTemplate string, handler func(req *http.Request, params PathParams) (fake.Responder[T], fake.ErrorResponder)) {
+	r.Handle(method, pathTemplate, func(req *http.Request, params PathParams) (*http.Response, error) {
+		respr, errRespr := handler(req, params)
+		if respErr := GetError(errRespr, req); respErr != nil {
+			return nil, respErr
+		}
+		return MarshalResponseAsJSON(GetResponseContent(respr), GetResponse(respr), req)
+	})
+}
+
+// HandlePager registers a handler for paged requests with the specified HTTP method and path template.
+// The handler is called for the request of the first page and returns a [fake.PagerResponder] with the
+// pages of the sequence. The router sets the next link of each page, except the last one, with setNextLink
+// and serves the subsequent pages from the PagerResponder.
+// It panics if the path template is invalid.
+func HandlePager[T any](r *Router, method, pathTemplate string, handler func(req *http.Request, params PathParams) fake.PagerResponder[T], setNextLink func(page *T, nextLink string)) {
+	pagers := newTracker[fake.PagerResponder[T]]()
+	r.handle(method, pathTemplate, routePager, func(req *http.Request, params PathParams) (*http.Response, error) {
+		pager := pagers.get(req)
+		if pager == nil {
+			resp := handler(req, params)
+			pager = &resp
+			pagers.add(req, pager)
+			PagerResponderInjectNextLinks(pager, req, func(page *T, createLink func() string) {
+				setNextLink(page, createLink())
+			})
+		}
+		resp, err := PagerResponderNext(pager, req)
+		if err != nil || !PagerResponderMore(pager) {
+			pagers.remove(req)
+		}
+		return resp, err
+	})
+}
+
+// HandlePoller registers a handler for requests that start a long-running operation with the specified
+// HTTP method and path template. The handler returns a [fake.PollerResponder] with the responses of the
+// operation, or a [fake.ErrorResponder] when the operation fails to start. The router serves the status
+// of the operation from the PollerResponder.
+// It panics if the path template is invalid.
+func HandlePoller[T any](r *Router, method, pathTemplate string, handler func(req *http.Request, params PathParams) (fake.PollerResponder[T], fake.ErrorResponder)) {
+	apiName := strings.ToUpper(method) + " " + pathTemplate
+	pollers := newTracker[fake.PollerResponder[T]]()
+	r.handle(method, pathTemplate, routePoller, func(req *http.Request, params PathParams) (*http.Response, error) {
+		if req.Context().Value(shared.CtxAPINameKey{}) == nil {
+			// the fake poller requires an API name, which hand-written clients might not set
+			req = req.WithContext(context.WithValue(req.Context(), shared.CtxAPINameKey{}, apiName))
+		}
+		poller := pollers.get(req)
+		if poller == nil {
+			respr, errRespr := handler(req, params)
+			if respErr := GetError(errRespr, req); respErr != nil {
+				return nil, respErr
+			}
+			poller = &respr
+			pollers.add(req, poller)
+		}
+		resp, err := PollerResponderNext(poller, req)
+		if err != nil || !PollerResponderMore(poller) {
+			pollers.remove(req)
+		}
+		return resp, err
+	})
+}
+
+// routeKind identifies the handlers that serve requests for subsequent pages and LRO status
+type routeKind int
+
+const (
+	routeDefault routeKind = iota
+	routePager
+	routePoller
+)
+
+// route is a handler registered with a Router
+type route struct {
+	method   string
+	segments []pathSegment
+	kind     routeKind
+	handler  Handler
+}
+
+// pathSegment is a segment of a path template
+type pathSegment struct {
+	// literal is the segment's value when it's not a parameter
+	literal string
+	// param is the name of the parameter
+	param string
+	// wildcard is true when the parameter matches the remainder of the path
+	wildcard bool
+}
+
+func parsePathTemplate(pathTemplate string) ([]pathSegment, error) {
+	if !strings.HasPrefix(pathTemplate, "/") {
+		return nil, fmt.Errorf("fake: path template %q must start with a slash", pathTemplate)
+	}
+	parts := splitPath(pathTemplate)
+	segments := make([]pathSegment, 0, len(parts))
+	names := map[string]bool{}
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, fmt.Errorf("fake: invalid segment %q in path template %q", part, pathTemplate)
+			}
+			segments = append(segments, pathSegment{literal: part})
+			continue
+		}
+		name, wildcard := strings.CutSuffix(part[1:len(part)-1], "...")
+		if name == "" || strings.ContainsAny(name, "{}") {
+			return nil, fmt.Errorf("fake: invalid parameter %q in path template %q", part, pathTemplate)
+		}
+		if wildcard && i != len(parts)-1 {
+			return nil, fmt.Errorf("fake: wildcard parameter %q must be the last segment in path template %q", part, pathTemplate)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("fake: duplicate parameter %q in path template %q", name, pathTemplate)
+		}
+		names[name] = true
+		segments = append(segments, pathSegment{param: name, wildcard: wildcard})
+	}
+	return segments, nil
+}
+
+// match returns the path parameters if the escaped path matches the route's template
+func (rt *route) match(path string) (PathParams, bool) {
+	parts := splitPath(path)
+	params := PathParams{}
+	for i, segment := range rt.segments {
+		if segment.wildcard {
+			value, err := url.PathUnescape(strings.Join(parts[i:], "/"))
+			if err != nil {
+				return nil, false
+			}
+			params[segment.param] = value
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		if segment.param == "" {
+			if !strings.EqualFold(segment.literal, parts[i]) {
+				return nil, false
+			}
+			continue
+		}
+		if parts[i] == "" {
+			return nil, false
+		}
+		value, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return nil, false
+		}
+		params[segment.param] = value
+	}
+	if len(parts) != len(rt.segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// splitPath returns the segments of a path, ignoring the leading and trailing slashes
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// tracker contains the pagers or pollers in progress, keyed by the request's sanitized path
+type tracker[T any] struct {
+	mu    sync.Mutex
+	items map[string]*T
+}
+
+func newTracker[T any]() *tracker[T] {
+	return &tracker[T]{
+		items: map[string]*T{},
+	}
+}
+
+func (p *tracker[T]) get(req *http.Request) *T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.items[SanitizePagerPollerPath(req.URL.Path)]
+}
+
+func (p *tracker[T]) add(req *http.Request, item *T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.items[SanitizePagerPollerPath(req.URL.Path)] = item
+}
+
+func (p *tracker[T]) remove(req *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.items, SanitizePagerPollerPath(req.URL.Path))
+}
@@ -0,0 +1,213 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/stretchr/testify/require"
+)
+
+type widgetPage struct {
+	Widgets  []widget
+	NextLink *string
+}
+
+func newRouterClient(t *testing.T, router *Router) *azcore.Client {
+	client, err := azcore.NewClient("module", "v1.0.0", runtime.PipelineOptions{}, &azcore.ClientOptions{
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+		Transport: router,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestRouterResponder(t *testing.T) {
+	router := NewRouter()
+	HandleResponder(router, http.MethodGet, "/containers/{container}/widgets/{name...}", func(req *http.Request, params PathParams) (fake.Responder[widget], fake.ErrorResponder) {
+		resp := fake.Responder[widget]{}
+		errResp := fake.ErrorResponder{}
+		if params["name"] == "missing" {
+			errResp.SetResponseError(http.StatusNotFound, "WidgetNotFound")
+		} else {
+			resp.SetResponse(http.StatusOK, widget{Name: params["container"] + ":" + params["name"]}, &fake.SetResponseOptions{
+				Header: http.Header{"X-Widget": []string{"yes"}},
+			})
+		}
+		return resp, errResp
+	})
+	router.Handle(http.MethodDelete, "/containers/{container}", func(req *http.Request, params PathParams) (*http.Response, error) {
+		return NewResponse(ResponseContent{HTTPStatus: http.StatusAccepted}, req, nil)
+	})
+	client := newRouterClient(t, router)
+
+	req, err := runtime.NewRequest(context.Background(), http.MethodGet, "https://contoso.com/Containers/c%201/widgets/a/b%2Fc?api-version=1")
+	require.NoError(t, err)
+	resp, err := client.Pipeline().Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "yes", resp.Header.Get("X-Widget"))
+	var w widget
+	require.NoError(t, runtime.UnmarshalAsJSON(resp, &w))
+	require.Equal(t, "c 1:a/b/c", w.Name)
+
+	req, err = runtime.NewRequest(context.Background(), http.MethodGet, "https://contoso.com/containers/c/widgets/missing")
+	require.NoError(t, err)
+	_, err = client.Pipeline().Do(req)
+	var respErr *azcore.ResponseError
+	require.ErrorAs(t, err, &respErr)
+	require.Equal(t, "WidgetNotFound", respErr.ErrorCode)
+
+	req, err = runtime.NewRequest(context.Background(), http.MethodDelete, "https://contoso.com/containers/c")
+	require.NoError(t, err)
+	resp, err = client.Pipeline().Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	// no route for the method or path
+	for _, url := range []string{"https://contoso.com/containers/c/widgets", "https://contoso.com/other"} {
+		req, err = runtime.NewRequest(context.Background(), http.MethodPut, url)
+		require.NoError(t, err)
+		_, err = client.Pipeline().Do(req)
+		require.ErrorContains(t, err, "no handler registered")
+	}
+}
+
+func TestRouterPager(t *testing.T) {
+	router := NewRouter()
+	HandlePager(router, http.MethodPost, "/widgets/list", func(req *http.Request, params PathParams) fake.PagerResponder[widgetPage] {
+		resp := fake.PagerResponder[widgetPage]{}
+		resp.AddPage(http.StatusOK, widgetPage{Widgets: []widget{{Name: "a"}, {Name: "b"}}}, nil)
+		resp.AddPage(http.StatusOK, widgetPage{Widgets: []widget{{Name: "c"}}}, nil)
+		return resp
+	}, func(page *widgetPage, nextLink string) {
+		page.NextLink = to.Ptr(nextLink)
+	})
+	client := newRouterClient(t, router)
+
+	// the pager can be iterated more than once
+	for range 2 {
+		pager := runtime.NewPager(runtime.PagingHandler[widgetPage]{
+			More: func(page widgetPage) bool {
+				return page.NextLink != nil
+			},
+			Fetcher: func(ctx context.Context, page *widgetPage) (widgetPage, error) {
+				var req *policy.Request
+				var err error
+				if page == nil {
+					req, err = runtime.NewRequest(ctx, http.MethodPost, "https://contoso.com/widgets/list")
+				} else {
+					req, err = runtime.NewRequest(ctx, http.MethodGet, *page.NextLink)
+				}
+				require.NoError(t, err)
+				resp, err := client.Pipeline().Do(req)
+				if err != nil {
+					return widgetPage{}, err
+				}
+				var result widgetPage
+				err = runtime.UnmarshalAsJSON(resp, &result)
+				return result, err
+			},
+		})
+		var names []string
+		for pager.More() {
+			page, err := pager.NextPage(context.Background())
+			require.NoError(t, err)
+			for _, w := range page.Widgets {
+				names = append(names, w.Name)
+			}
+		}
+		require.Equal(t, []string{"a", "b", "c"}, names)
+	}
+}
+
+func TestRouterPoller(t *testing.T) {
+	router := NewRouter()
+	HandlePoller(router, http.MethodPut, "/widgets/{name}", func(req *http.Request, params PathParams) (fake.PollerResponder[widget], fake.ErrorResponder) {
+		resp := fake.PollerResponder[widget]{}
+		errResp := fake.ErrorResponder{}
+		if params["name"] == "conflict" {
+			errResp.SetResponseError(http.StatusConflict, "WidgetExists")
+			return resp, errResp
+		}
+		resp.AddNonTerminalResponse(http.StatusCreated, nil)
+		resp.AddNonTerminalResponse(http.StatusOK, nil)
+		resp.SetTerminalResponse(http.StatusOK, widget{Name: params["name"]}, nil)
+		return resp, errResp
+	})
+	client := newRouterClient(t, router)
+
+	begin := func(name string) (*runtime.Poller[widget], error) {
+		req, err := runtime.NewRequest(context.Background(), http.MethodPut, "https://contoso.com/widgets/"+name)
+		require.NoError(t, err)
+		resp, err := client.Pipeline().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		return runtime.NewPoller[widget](resp, client.Pipeline(), nil)
+	}
+
+	poller, err := begin("sprocket")
+	require.NoError(t, err)
+	result, err := poller.PollUntilDone(context.Background(), &runtime.PollUntilDoneOptions{Frequency: time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, "sprocket", result.Name)
+
+	_, err = begin("conflict")
+	var respErr *azcore.ResponseError
+	require.ErrorAs(t, err, &respErr)
+	require.Equal(t, http.StatusConflict, respErr.StatusCode)
+}
+
+func TestRouterCancellation(t *testing.T) {
+	router := NewRouter()
+	block := make(chan struct{})
+	defer close(block)
+	router.Handle(http.MethodGet, "/slow", func(req *http.Request, params PathParams) (*http.Response, error) {
+		<-block
+		return nil, errors.New("unexpected")
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://contoso.com/slow", nil)
+	require.NoError(t, err)
+	_, err = router.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestParsePathTemplate(t *testing.T) {
+	for _, tmpl := range []string{
+		"widgets",
+		"/widgets/{}",
+		"/widgets/{name...}/more",
+		"/widgets/{name}/{name}",
+		"/widgets/a{name}",
+	} {
+		_, err := parsePathTemplate(tmpl)
+		require.Error(t, err, tmpl)
+	}
+	require.Panics(t, func() {
+		NewRouter().Handle(http.MethodGet, "widgets", nil)
+	})
+
+	segments, err := parsePathTemplate("/widgets/{name}/parts/{rest...}")
+	require.NoError(t, err)
+	rt := &route{segments: segments}
+	params, ok := rt.match("/widgets/w/parts/")
+	require.True(t, ok)
+	require.Equal(t, PathParams{"name": "w", "rest": ""}, params)
+	_, ok = rt.match("/widgets//parts/x")
+	require.False(t, ok)
+	_, ok = rt.match("/widgets/w")
+	require.False(t, ok)
+}
@@ -197,6 +197,19 @@ func (req *Request) Clone(ctx context.Context) *Request {
 	return &r2
 }
 
+// ConcurrentClone returns a deep copy of the request with its context changed to ctx.
+// Unlike Clone, the copy has its own operation values so that it can be sent through
+// the remainder of the pipeline concurrently with req.
+// Not exported but used by the hedging policy.
+func ConcurrentClone(req *Request, ctx context.Context) *Request {
+	r2 := req.Clone(ctx)
+	r2.values = make(opValues, len(req.values))
+	for k, v := range req.values {
+		r2.values[k] = v
+	}
+	return r2
+}
+
 // WithContext returns a shallow copy of the request with its context changed to ctx.
 func (req *Request) WithContext(ctx context.Context) *Request {
 	r2 := new(Request)
@@ -171,6 +171,24 @@ func TestRequestClone(t *testing.T) {
 	}
 }
 
+func TestRequestConcurrentClone(t *testing.T) {
+	req, err := NewRequest(context.Background(), http.MethodGet, testURL)
+	require.NoError(t, err)
+	type opValue struct {
+		Count int
+	}
+	req.SetOperationValue(opValue{Count: 1})
+	clone := ConcurrentClone(req, context.Background())
+	var v opValue
+	require.True(t, clone.OperationValue(&v))
+	require.Equal(t, 1, v.Count)
+
+	// changing the clone's values must not affect the source
+	clone.SetOperationValue(opValue{Count: 2})
+	require.True(t, req.OperationValue(&v))
+	require.Equal(t, 1, v.Count)
+}
+
 func TestNewRequestFail(t *testing.T) {
 	req, err := NewRequest(context.Background(), http.MethodOptions, "://test.contoso.com/")
 	if err == nil {
@@ -8,6 +8,11 @@
 package log
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/structuredlog"
 	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
 )
@@ -15,23 +20,28 @@ import (
 type Event = log.Event
 
 const (
-	EventRequest       = azlog.EventRequest
-	EventResponse      = azlog.EventResponse
-	EventResponseError = azlog.EventResponseError
-	EventRetryPolicy   = azlog.EventRetryPolicy
-	EventLRO           = azlog.EventLRO
+	EventRequest        = azlog.EventRequest
+	EventResponse       = azlog.EventResponse
+	EventResponseError  = azlog.EventResponseError
+	EventRetryPolicy    = azlog.EventRetryPolicy
+	EventLRO            = azlog.EventLRO
+	EventAuthentication = azlog.EventAuthentication
 )
 
-// Write invokes the underlying listener with the specified event and message.
-// If the event shouldn't be logged or there is no listener then Write does nothing.
+// Write invokes the underlying listener and slog logger with the specified event and message.
+// If the event shouldn't be logged or there is no listener or logger then Write does nothing.
 func Write(cls log.Event, msg string) {
 	log.Write(cls, msg)
+	structuredlog.Write(context.Background(), cls, msg)
 }
 
-// Writef invokes the underlying listener with the specified event and formatted message.
-// If the event shouldn't be logged or there is no listener then Writef does nothing.
+// Writef invokes the underlying listener and slog logger with the specified event and formatted message.
+// If the event shouldn't be logged or there is no listener or logger then Writef does nothing.
 func Writef(cls log.Event, format string, a ...any) {
-	log.Writef(cls, format, a...)
+	if !Should(cls) {
+		return
+	}
+	Write(cls, fmt.Sprintf(format, a...))
 }
 
 // SetListener will set the Logger to write to the specified listener.
@@ -42,9 +52,32 @@ func SetListener(lst func(Event, string)) {
 // Should returns true if the specified log event should be written to the log.
 // By default all log events will be logged.  Call SetEvents() to limit
 // the log events for logging.
-// If no listener has been set this will return false.
+// If no listener or slog logger has been set this will return false.
 // Calling this method is useful when the message to log is computationally expensive
 // and you want to avoid the overhead if its log event is not enabled.
 func Should(cls log.Event) bool {
+	return log.Should(cls) || structuredlog.Should(context.Background(), cls)
+}
+
+// ShouldListener returns true if the specified log event should be written to the listener.
+// Use this with WriteListener for entries that are written to the slog logger with WriteAttrs.
+func ShouldListener(cls log.Event) bool {
 	return log.Should(cls)
 }
+
+// WriteListener invokes the underlying listener with the specified event and message.
+// Unlike Write, the message isn't written to the slog logger.
+func WriteListener(cls log.Event, msg string) {
+	log.Write(cls, msg)
+}
+
+// ShouldAttrs returns true if the specified log event should be written to the slog logger.
+func ShouldAttrs(ctx context.Context, cls log.Event) bool {
+	return structuredlog.Should(ctx, cls)
+}
+
+// WriteAttrs writes an entry with the specified message and attributes to the slog logger.
+// Unlike Write, the entry isn't written to the listener.
+func WriteAttrs(ctx context.Context, cls log.Event, msg string, attrs ...slog.Attr) {
+	structuredlog.Write(ctx, cls, msg, attrs...)
+}
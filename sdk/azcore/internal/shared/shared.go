@@ -34,6 +34,9 @@ type CtxWithTracingTracer struct{}
 // CtxAPINameKey is used as a context key for adding/retrieving the API name.
 type CtxAPINameKey struct{}
 
+// CtxMaxResponseBodySizeKey is used as a context key for adding/retrieving the maximum response body size.
+type CtxMaxResponseBodySizeKey struct{}
+
 // Delay waits for the duration to elapse or the context to be cancelled.
 func Delay(ctx context.Context, delay time.Duration) error {
 	select {
@@ -141,7 +144,7 @@ type ContextWithDeniedValues struct {
 // It acts as a deny-list for certain context keys.
 func (c *ContextWithDeniedValues) Value(key any) any {
 	switch key.(type) {
-	case CtxAPINameKey, CtxWithCaptureResponse, CtxWithHTTPHeaderKey, CtxWithRetryOptionsKey, CtxWithTracingTracer:
+	case CtxAPINameKey, CtxMaxResponseBodySizeKey, CtxWithCaptureResponse, CtxWithHTTPHeaderKey, CtxWithRetryOptionsKey, CtxWithTracingTracer:
 		return nil
 	default:
 		return c.Context.Value(key)
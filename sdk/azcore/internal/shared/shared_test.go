@@ -113,6 +113,7 @@ func TestContextWithDeniedValues(t *testing.T) {
 
 	ctx := context.WithValue(context.Background(), testKey{}, value)
 	ctx = context.WithValue(ctx, CtxAPINameKey{}, value)
+	ctx = context.WithValue(ctx, CtxMaxResponseBodySizeKey{}, value)
 	ctx = context.WithValue(ctx, CtxWithCaptureResponse{}, value)
 	ctx = context.WithValue(ctx, CtxWithHTTPHeaderKey{}, value)
 	ctx = context.WithValue(ctx, CtxWithRetryOptionsKey{}, value)
@@ -120,6 +121,7 @@ func TestContextWithDeniedValues(t *testing.T) {
 	ctx = &ContextWithDeniedValues{Context: ctx}
 
 	require.Nil(t, ctx.Value(CtxAPINameKey{}))
+	require.Nil(t, ctx.Value(CtxMaxResponseBodySizeKey{}))
 	require.Nil(t, ctx.Value(CtxWithCaptureResponse{}))
 	require.Nil(t, ctx.Value(CtxWithHTTPHeaderKey{}))
 	require.Nil(t, ctx.Value(CtxWithRetryOptionsKey{}))
@@ -0,0 +1,58 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package structuredlog holds the process-wide *slog.Logger that receives structured log entries.
+// It's separate from package log to avoid an import cycle with the public log package.
+package structuredlog
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+)
+
+// EventAttrName is the name of the attribute containing the log event
+const EventAttrName = "az.event"
+
+// Config contains the configuration for writing to a *slog.Logger
+type Config struct {
+	Logger *slog.Logger
+	Events map[log.Event]struct{}
+	Level  func(log.Event) slog.Level
+}
+
+var config atomic.Pointer[Config]
+
+// Set sets the configuration. Pass nil to stop writing structured log entries.
+func Set(c *Config) {
+	config.Store(c)
+}
+
+// Should returns true if the specified log event should be written to the *slog.Logger.
+func Should(ctx context.Context, cls log.Event) bool {
+	return config.Load().should(ctx, cls)
+}
+
+func (c *Config) should(ctx context.Context, cls log.Event) bool {
+	if c == nil {
+		return false
+	}
+	if len(c.Events) > 0 {
+		if _, ok := c.Events[cls]; !ok {
+			return false
+		}
+	}
+	return c.Logger.Enabled(ctx, c.Level(cls))
+}
+
+// Write writes an entry with the specified message and attributes to the *slog.Logger.
+// If the event shouldn't be logged or there is no logger then Write does nothing.
+func Write(ctx context.Context, cls log.Event, msg string, attrs ...slog.Attr) {
+	c := config.Load()
+	if !c.should(ctx, cls) {
+		return
+	}
+	c.Logger.LogAttrs(ctx, c.Level(cls), msg, append(attrs, slog.String(EventAttrName, string(cls)))...)
+}
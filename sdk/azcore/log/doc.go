@@ -7,4 +7,5 @@
 
 // Package log contains functionality for configuring logging behavior.
 // Default logging to stderr can be enabled by setting environment variable AZURE_SDK_GO_LOGGING to "all".
+// Call SetSlogLogger to write entries with structured attributes to a *slog.Logger.
 package log
@@ -34,6 +34,10 @@ const (
 	// EventLRO entries contain information specific to long-running operations.
 	// This includes information like polling location, operation state, and sleep intervals.
 	EventLRO Event = "LongRunningOperation"
+
+	// EventAuthentication entries contain information about authentication.
+	// This includes information like failures to refresh access tokens in the background.
+	EventAuthentication Event = "Authentication"
 )
 
 // SetEvents is used to control which events are written to
@@ -0,0 +1,63 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package log
+
+import (
+	"log/slog"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/structuredlog"
+)
+
+// SlogOptions contains the optional values for SetSlogLogger.
+type SlogOptions struct {
+	// Events limits the events written to the logger.
+	// By default all events are written. SetEvents doesn't apply to the logger.
+	Events []Event
+
+	// Level returns the level of the entries for the specified event.
+	// By default, EventResponseError entries are written at slog.LevelWarn
+	// and all other entries at slog.LevelDebug.
+	Level func(Event) slog.Level
+}
+
+// SetSlogLogger sets a *slog.Logger to receive azcore's log entries with structured attributes.
+// The request/response logging and retry policies write attributes such as the HTTP method,
+// URL, status code, try count, duration and request IDs. Other azcore entries, such as those
+// from pollers, only contain the message. All entries include the az.event attribute with the Event.
+// Entries are written to the logger in addition to any listener set with SetListener.
+// Pass a nil logger to stop writing to the logger.
+//
+// Only entries written by azcore reach the logger. Other modules, for example azidentity, write
+// their entries, including EventAuthentication entries, only to the listener set with SetListener.
+// To receive those, also set a listener; note that it receives azcore's entries as well.
+func SetSlogLogger(logger *slog.Logger, options *SlogOptions) {
+	if logger == nil {
+		structuredlog.Set(nil)
+		return
+	}
+	if options == nil {
+		options = &SlogOptions{}
+	}
+	c := &structuredlog.Config{
+		Logger: logger,
+		Level:  options.Level,
+	}
+	if c.Level == nil {
+		c.Level = defaultSlogLevel
+	}
+	if len(options.Events) > 0 {
+		c.Events = map[Event]struct{}{}
+		for _, e := range options.Events {
+			c.Events[e] = struct{}{}
+		}
+	}
+	structuredlog.Set(c)
+}
+
+func defaultSlogLevel(e Event) slog.Level {
+	if e == EventResponseError {
+		return slog.LevelWarn
+	}
+	return slog.LevelDebug
+}
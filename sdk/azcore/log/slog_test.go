@@ -0,0 +1,79 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/structuredlog"
+	"github.com/stretchr/testify/require"
+)
+
+func readEntries(t *testing.T, b *bytes.Buffer) []map[string]any {
+	entries := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	b.Reset()
+	return entries
+}
+
+func TestSetSlogLogger(t *testing.T) {
+	b := &bytes.Buffer{}
+	SetSlogLogger(slog.New(slog.NewJSONHandler(b, &slog.HandlerOptions{Level: slog.LevelDebug})), nil)
+	defer SetSlogLogger(nil, nil)
+
+	structuredlog.Write(context.Background(), EventRequest, "request", slog.String("key", "value"))
+	structuredlog.Write(context.Background(), EventResponseError, "response error")
+	entries := readEntries(t, b)
+	require.Len(t, entries, 2)
+	require.Equal(t, "request", entries[0]["msg"])
+	require.Equal(t, "DEBUG", entries[0]["level"])
+	require.Equal(t, "value", entries[0]["key"])
+	require.Equal(t, string(EventRequest), entries[0]["az.event"])
+	require.Equal(t, "WARN", entries[1]["level"])
+
+	SetSlogLogger(nil, nil)
+	require.False(t, structuredlog.Should(context.Background(), EventRequest))
+	structuredlog.Write(context.Background(), EventRequest, "request")
+	require.Zero(t, b.Len())
+}
+
+func TestSetSlogLoggerOptions(t *testing.T) {
+	b := &bytes.Buffer{}
+	SetSlogLogger(slog.New(slog.NewJSONHandler(b, nil)), &SlogOptions{
+		Events: []Event{EventRetryPolicy, EventLRO},
+		Level: func(e Event) slog.Level {
+			if e == EventLRO {
+				return slog.LevelDebug
+			}
+			return slog.LevelInfo
+		},
+	})
+	defer SetSlogLogger(nil, nil)
+
+	// EventRequest isn't in the list of events
+	require.False(t, structuredlog.Should(context.Background(), EventRequest))
+	// EventLRO entries are below the handler's level
+	require.False(t, structuredlog.Should(context.Background(), EventLRO))
+	require.True(t, structuredlog.Should(context.Background(), EventRetryPolicy))
+
+	structuredlog.Write(context.Background(), EventRequest, "request")
+	structuredlog.Write(context.Background(), EventLRO, "lro")
+	structuredlog.Write(context.Background(), EventRetryPolicy, "retry")
+	entries := readEntries(t, b)
+	require.Len(t, entries, 1)
+	require.Equal(t, "retry", entries[0]["msg"])
+	require.Equal(t, "INFO", entries[0]["level"])
+}
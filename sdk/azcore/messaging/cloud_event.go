@@ -202,23 +202,7 @@ func (ce *CloudEvent) UnmarshalJSON(data []byte) error {
 		}
 	}
 
-	if ce.ID == "" {
-		return errors.New("required field 'id' was not present, or was empty")
-	}
-
-	if ce.Source == "" {
-		return errors.New("required field 'source' was not present, or was empty")
-	}
-
-	if ce.SpecVersion == "" {
-		return errors.New("required field 'specversion' was not present, or was empty")
-	}
-
-	if ce.Type == "" {
-		return errors.New("required field 'type' was not present, or was empty")
-	}
-
-	return nil
+	return checkRequired(ce)
 }
 
 func updateFieldFromValue(ce *CloudEvent, k string, raw json.RawMessage) error {
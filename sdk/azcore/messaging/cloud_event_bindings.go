@@ -0,0 +1,448 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package messaging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentTypeCloudEventJSON is the content type of an event in structured mode.
+	ContentTypeCloudEventJSON = "application/cloudevents+json"
+
+	// ContentTypeCloudEventBatchJSON is the content type of a batch of events.
+	ContentTypeCloudEventBatchJSON = "application/cloudevents-batch+json"
+
+	// httpHeaderPrefix is the prefix of the HTTP headers containing attributes in binary mode
+	httpHeaderPrefix = "ce-"
+
+	// amqpPropertyPrefix is the prefix of the AMQP application properties containing attributes in binary mode
+	amqpPropertyPrefix = "cloudEvents:"
+
+	// amqpPropertyPrefixJMS is the alternative prefix for AMQP application properties, compatible with JMS
+	amqpPropertyPrefixJMS = "cloudEvents_"
+
+	contentTypeJSON = "application/json"
+)
+
+// MarshalHTTPBinary encodes the event in the CloudEvents HTTP binary content mode.
+// The attributes are returned as ce-* headers and the data as the body. DataContentType
+// is returned as the Content-Type header. Data that isn't a []byte is encoded as JSON,
+// with a Content-Type of application/json unless DataContentType is set.
+// Extension values must be strings, booleans, integers, []byte, time.Time or *url.URL.
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md
+func MarshalHTTPBinary(ce CloudEvent) (http.Header, []byte, error) {
+	attrs, err := binaryAttributes(ce)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, contentType, err := binaryData(ce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	for name, value := range attrs {
+		s, err := attributeString(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to serialize %q: %w", name, err)
+		}
+		header.Set(httpHeaderPrefix+name, percentEncode(s))
+	}
+	if contentType != nil {
+		header.Set("Content-Type", *contentType)
+	}
+	return header, data, nil
+}
+
+// UnmarshalHTTPBinary decodes an event sent in the CloudEvents HTTP binary content mode.
+// Extensions are decoded as strings, as the HTTP binding doesn't retain their types.
+// The body is returned as the event's Data, or nil when it's empty.
+func UnmarshalHTTPBinary(header http.Header, body []byte) (CloudEvent, error) {
+	ce := CloudEvent{}
+	for key, values := range header {
+		name, ok := cutPrefixFold(key, httpHeaderPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		value, err := url.PathUnescape(values[0])
+		if err != nil {
+			return CloudEvent{}, fmt.Errorf("failed to deserialize %q: %w", name, err)
+		}
+		if err := setAttribute(&ce, strings.ToLower(name), value); err != nil {
+			return CloudEvent{}, fmt.Errorf("failed to deserialize %q: %w", name, err)
+		}
+	}
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		ce.DataContentType = &contentType
+	}
+	if len(body) > 0 {
+		ce.Data = body
+	}
+	if err := checkRequired(&ce); err != nil {
+		return CloudEvent{}, err
+	}
+	return ce, nil
+}
+
+// UnmarshalHTTP decodes the events in an HTTP request or response. The content mode is determined by the
+// Content-Type header: structured mode for application/cloudevents+json, batch mode for
+// application/cloudevents-batch+json, and binary mode otherwise.
+func UnmarshalHTTP(header http.Header, body []byte) ([]CloudEvent, error) {
+	switch mediaType(header.Get("Content-Type")) {
+	case ContentTypeCloudEventJSON:
+		var ce CloudEvent
+		if err := json.Unmarshal(body, &ce); err != nil {
+			return nil, err
+		}
+		return []CloudEvent{ce}, nil
+	case ContentTypeCloudEventBatchJSON:
+		return UnmarshalBatch(body)
+	default:
+		ce, err := UnmarshalHTTPBinary(header, body)
+		if err != nil {
+			return nil, err
+		}
+		return []CloudEvent{ce}, nil
+	}
+}
+
+// MarshalBatch encodes the events in the CloudEvents JSON batch format, whose content type is
+// [ContentTypeCloudEventBatchJSON]. An empty batch is encoded as an empty JSON array.
+func MarshalBatch(events []CloudEvent) ([]byte, error) {
+	if events == nil {
+		events = []CloudEvent{}
+	}
+	return json.Marshal(events)
+}
+
+// UnmarshalBatch decodes events in the CloudEvents JSON batch format.
+func UnmarshalBatch(data []byte) ([]CloudEvent, error) {
+	var events []CloudEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, err
+	}
+	if events == nil {
+		return nil, errors.New("batch must be a JSON array")
+	}
+	return events, nil
+}
+
+// AMQPMessage contains the parts of an AMQP message used by the CloudEvents AMQP binding.
+// Messaging packages, such as those for Event Hubs and Service Bus, convert it to and from their message types.
+type AMQPMessage struct {
+	// ApplicationProperties are the message's application properties.
+	ApplicationProperties map[string]any
+
+	// ContentType is the content-type property of the message.
+	ContentType *string
+
+	// Data is the body of the message, in a single data section.
+	Data []byte
+}
+
+// MarshalAMQP encodes the event in the CloudEvents AMQP binary content mode.
+// The attributes are returned as application properties prefixed with "cloudEvents:",
+// and the data as the message body. The time is an AMQP timestamp and extensions retain
+// their types. Data that isn't a []byte is encoded as JSON, as for [MarshalHTTPBinary].
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/amqp-protocol-binding.md
+func MarshalAMQP(ce CloudEvent) (AMQPMessage, error) {
+	attrs, err := binaryAttributes(ce)
+	if err != nil {
+		return AMQPMessage{}, err
+	}
+	data, contentType, err := binaryData(ce)
+	if err != nil {
+		return AMQPMessage{}, err
+	}
+
+	props := make(map[string]any, len(attrs))
+	for name, value := range attrs {
+		v, err := amqpValue(value)
+		if err != nil {
+			return AMQPMessage{}, fmt.Errorf("failed to serialize %q: %w", name, err)
+		}
+		props[amqpPropertyPrefix+name] = v
+	}
+	return AMQPMessage{
+		ApplicationProperties: props,
+		ContentType:           contentType,
+		Data:                  data,
+	}, nil
+}
+
+// UnmarshalAMQP decodes an event sent with the CloudEvents AMQP binding. A message with content
+// type application/cloudevents+json is decoded in structured mode; otherwise the attributes are
+// read from application properties prefixed with "cloudEvents:" or "cloudEvents_".
+func UnmarshalAMQP(msg AMQPMessage) (CloudEvent, error) {
+	if msg.ContentType != nil && mediaType(*msg.ContentType) == ContentTypeCloudEventJSON {
+		var ce CloudEvent
+		if err := json.Unmarshal(msg.Data, &ce); err != nil {
+			return CloudEvent{}, err
+		}
+		return ce, nil
+	}
+
+	ce := CloudEvent{}
+	for key, value := range msg.ApplicationProperties {
+		name, ok := cutPrefixFold(key, amqpPropertyPrefix)
+		if !ok {
+			if name, ok = cutPrefixFold(key, amqpPropertyPrefixJMS); !ok {
+				continue
+			}
+		}
+		if err := setAttribute(&ce, strings.ToLower(name), value); err != nil {
+			return CloudEvent{}, fmt.Errorf("failed to deserialize %q: %w", name, err)
+		}
+	}
+	ce.DataContentType = msg.ContentType
+	if len(msg.Data) > 0 {
+		ce.Data = msg.Data
+	}
+	if err := checkRequired(&ce); err != nil {
+		return CloudEvent{}, err
+	}
+	return ce, nil
+}
+
+// binaryAttributes returns the event's attributes, except datacontenttype, keyed by name
+func binaryAttributes(ce CloudEvent) (map[string]any, error) {
+	attrs := map[string]any{
+		"id":          ce.ID,
+		"source":      ce.Source,
+		"specversion": ce.SpecVersion,
+		"type":        ce.Type,
+	}
+	if ce.DataSchema != nil {
+		attrs["dataschema"] = *ce.DataSchema
+	}
+	if ce.Subject != nil {
+		attrs["subject"] = *ce.Subject
+	}
+	if ce.Time != nil {
+		attrs["time"] = *ce.Time
+	}
+	for name, value := range ce.Extensions {
+		if !isValidAttributeName(name) {
+			return nil, fmt.Errorf("extension name %q must contain only lower-case letters and digits", name)
+		}
+		if _, ok := attrs[name]; ok || name == "data" || name == "datacontenttype" {
+			return nil, fmt.Errorf("extension name %q is reserved", name)
+		}
+		attrs[name] = value
+	}
+	return attrs, nil
+}
+
+// binaryData returns the event's data and content type in binary mode
+func binaryData(ce CloudEvent) ([]byte, *string, error) {
+	contentType := ce.DataContentType
+	switch data := ce.Data.(type) {
+	case nil:
+		return nil, contentType, nil
+	case []byte:
+		return data, contentType, nil
+	default:
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if contentType == nil {
+			ct := contentTypeJSON
+			contentType = &ct
+		}
+		return b, contentType, nil
+	}
+}
+
+// setAttribute sets the named attribute of ce to value, which is a string or an AMQP value
+func setAttribute(ce *CloudEvent, name string, value any) error {
+	switch name {
+	case "id":
+		return getValue(name, value, &ce.ID)
+	case "source":
+		return getValue(name, value, &ce.Source)
+	case "specversion":
+		return getValue(name, value, &ce.SpecVersion)
+	case "type":
+		return getValue(name, value, &ce.Type)
+	case "dataschema":
+		var s string
+		if err := getValue(name, value, &s); err != nil {
+			return err
+		}
+		ce.DataSchema = &s
+	case "subject":
+		var s string
+		if err := getValue(name, value, &s); err != nil {
+			return err
+		}
+		ce.Subject = &s
+	case "time":
+		switch v := value.(type) {
+		case time.Time:
+			ce.Time = &v
+		case string:
+			tm, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return err
+			}
+			ce.Time = &tm
+		default:
+			return fmt.Errorf("field %q is a %T, but should be a timestamp", name, value)
+		}
+	case "datacontenttype", "data":
+		// the content type and data aren't sent as attributes in binary mode
+		return fmt.Errorf("%q isn't allowed in binary mode", name)
+	default:
+		if ce.Extensions == nil {
+			ce.Extensions = map[string]any{}
+		}
+		ce.Extensions[name] = value
+	}
+	return nil
+}
+
+// checkRequired returns an error if a required attribute of ce is empty
+func checkRequired(ce *CloudEvent) error {
+	if ce.ID == "" {
+		return errors.New("required field 'id' was not present, or was empty")
+	}
+
+	if ce.Source == "" {
+		return errors.New("required field 'source' was not present, or was empty")
+	}
+
+	if ce.SpecVersion == "" {
+		return errors.New("required field 'specversion' was not present, or was empty")
+	}
+
+	if ce.Type == "" {
+		return errors.New("required field 'type' was not present, or was empty")
+	}
+
+	return nil
+}
+
+// attributeString returns the canonical string encoding of an attribute value
+func attributeString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case *url.URL:
+		return v.String(), nil
+	}
+	i, err := attributeInt(value)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(int64(i), 10), nil
+}
+
+// amqpValue returns the AMQP value of an attribute. Integers are AMQP ints and URIs are strings.
+func amqpValue(value any) (any, error) {
+	switch v := value.(type) {
+	case string, bool, []byte, time.Time:
+		return v, nil
+	case *url.URL:
+		return v.String(), nil
+	}
+	return attributeInt(value)
+}
+
+// attributeInt returns value as a CloudEvents integer, which is a signed 32-bit integer.
+// Whole float64 values are accepted as that's how extensions unmarshalled from JSON are represented.
+func attributeInt(value any) (int32, error) {
+	var i int64
+	switch v := value.(type) {
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint8:
+		i = int64(v)
+	case uint16:
+		i = int64(v)
+	case uint32:
+		i = int64(v)
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt32 || v > math.MaxInt32 {
+			return 0, fmt.Errorf("%v isn't an integer", v)
+		}
+		i = int64(v)
+	default:
+		return 0, fmt.Errorf("type %T isn't supported", value)
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return 0, fmt.Errorf("%d is out of range for an integer", i)
+	}
+	return int32(i), nil
+}
+
+// isValidAttributeName returns true if name contains only lower-case ASCII letters and digits
+func isValidAttributeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// percentEncode encodes a header value as required by the HTTP binding: space, double quote,
+// percent, and characters outside of printable ASCII are percent-encoded as UTF-8 bytes.
+func percentEncode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c == '"' || c == '%' || c >= 0x7f {
+			fmt.Fprintf(&sb, "%%%02X", c)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// cutPrefixFold returns s without the case-insensitive prefix and true, or s and false if it doesn't have the prefix
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// mediaType returns the lower-case media type of a content type, without parameters
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mt
+}
@@ -0,0 +1,222 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package messaging
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBindingEvent(t *testing.T, data any) CloudEvent {
+	tm, err := time.Parse(time.RFC3339Nano, "2023-06-16T02:54:01.470515Z")
+	require.NoError(t, err)
+	ce, err := NewCloudEvent("/contoso/widgets", "Contoso.Widget.Created", data, &CloudEventOptions{
+		DataSchema: to.Ptr("https://contoso.com/schema"),
+		Extensions: map[string]any{
+			"tenant":   "a b\"%ü",
+			"priority": 2,
+			"urgent":   true,
+		},
+		Subject: to.Ptr("widgets/1"),
+		Time:    &tm,
+	})
+	require.NoError(t, err)
+	return ce
+}
+
+func TestCloudEventHTTPBinary(t *testing.T) {
+	ce := newTestBindingEvent(t, map[string]int{"size": 42})
+	header, body, err := MarshalHTTPBinary(ce)
+	require.NoError(t, err)
+	require.Equal(t, `{"size":42}`, string(body))
+	require.Equal(t, "application/json", header.Get("Content-Type"))
+	require.Equal(t, ce.ID, header.Get("ce-id"))
+	require.Equal(t, "1.0", header.Get("ce-specversion"))
+	require.Equal(t, "/contoso/widgets", header.Get("ce-source"))
+	require.Equal(t, "2023-06-16T02:54:01.470515Z", header.Get("ce-time"))
+	require.Equal(t, "a%20b%22%25%C3%BC", header.Get("ce-tenant"))
+	require.Equal(t, "2", header.Get("ce-priority"))
+	require.Equal(t, "true", header.Get("ce-urgent"))
+	require.Empty(t, header.Get("ce-datacontenttype"))
+
+	actual, err := UnmarshalHTTPBinary(header, body)
+	require.NoError(t, err)
+	expected := ce
+	expected.Data = []byte(`{"size":42}`)
+	expected.DataContentType = to.Ptr("application/json")
+	// the HTTP binding doesn't retain the types of extensions
+	expected.Extensions = map[string]any{"tenant": "a b\"%ü", "priority": "2", "urgent": "true"}
+	require.Equal(t, expected, actual)
+
+	// binary data and no data
+	ce = newTestBindingEvent(t, []byte{1, 2, 3})
+	ce.DataContentType = to.Ptr("application/octet-stream")
+	header, body, err = MarshalHTTPBinary(ce)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, body)
+	require.Equal(t, "application/octet-stream", header.Get("Content-Type"))
+
+	ce = newTestBindingEvent(t, nil)
+	header, body, err = MarshalHTTPBinary(ce)
+	require.NoError(t, err)
+	require.Nil(t, body)
+	require.Empty(t, header.Get("Content-Type"))
+	actual, err = UnmarshalHTTPBinary(header, body)
+	require.NoError(t, err)
+	require.Nil(t, actual.Data)
+	require.Nil(t, actual.DataContentType)
+}
+
+func TestCloudEventHTTPBinaryErrors(t *testing.T) {
+	for name, extensions := range map[string]map[string]any{
+		"invalid name":   {"Tenant": "a"},
+		"reserved name":  {"data": "a"},
+		"invalid value":  {"tenant": struct{}{}},
+		"out of range":   {"big": int64(1) << 40},
+		"not an integer": {"ratio": 0.5},
+	} {
+		ce := newTestBindingEvent(t, nil)
+		ce.Extensions = extensions
+		_, _, err := MarshalHTTPBinary(ce)
+		require.Error(t, err, name)
+	}
+
+	valid := http.Header{}
+	valid.Set("ce-id", "1")
+	valid.Set("ce-source", "source")
+	valid.Set("ce-specversion", "1.0")
+	valid.Set("ce-type", "type")
+	for name, header := range map[string][2]string{
+		"missing type":   {"ce-type", ""},
+		"invalid time":   {"ce-time", "yesterday"},
+		"invalid escape": {"ce-subject", "%zz"},
+		"data attribute": {"ce-datacontenttype", "text/plain"},
+	} {
+		h := valid.Clone()
+		if header[1] == "" {
+			h.Del(header[0])
+		} else {
+			h.Set(header[0], header[1])
+		}
+		_, err := UnmarshalHTTPBinary(h, nil)
+		require.Error(t, err, name)
+	}
+}
+
+func TestCloudEventBatch(t *testing.T) {
+	events := []CloudEvent{newTestBindingEvent(t, []byte("one")), newTestBindingEvent(t, "two")}
+	data, err := MarshalBatch(events)
+	require.NoError(t, err)
+
+	actual, err := UnmarshalBatch(data)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	require.Equal(t, []byte("one"), actual[0].Data)
+	require.Equal(t, []byte(`"two"`), actual[1].Data)
+	require.Equal(t, events[1].ID, actual[1].ID)
+
+	data, err = MarshalBatch(nil)
+	require.NoError(t, err)
+	require.Equal(t, "[]", string(data))
+	actual, err = UnmarshalBatch(data)
+	require.NoError(t, err)
+	require.Empty(t, actual)
+
+	for _, invalid := range []string{"null", "{}", `[{"id": "1"}]`} {
+		_, err = UnmarshalBatch([]byte(invalid))
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestUnmarshalHTTP(t *testing.T) {
+	ce := newTestBindingEvent(t, []byte("data"))
+	structured, err := ce.MarshalJSON()
+	require.NoError(t, err)
+	batch, err := MarshalBatch([]CloudEvent{ce, ce})
+	require.NoError(t, err)
+	binaryHeader, binaryBody, err := MarshalHTTPBinary(ce)
+	require.NoError(t, err)
+
+	for name, test := range map[string]struct {
+		header http.Header
+		body   []byte
+		count  int
+	}{
+		"structured": {http.Header{"Content-Type": []string{"application/cloudevents+json; charset=utf-8"}}, structured, 1},
+		"batch":      {http.Header{"Content-Type": []string{ContentTypeCloudEventBatchJSON}}, batch, 2},
+		"binary":     {binaryHeader, binaryBody, 1},
+	} {
+		events, err := UnmarshalHTTP(test.header, test.body)
+		require.NoError(t, err, name)
+		require.Len(t, events, test.count, name)
+		for _, e := range events {
+			require.Equal(t, ce.ID, e.ID, name)
+			require.Equal(t, []byte("data"), e.Data, name)
+		}
+	}
+
+	_, err = UnmarshalHTTP(http.Header{"Content-Type": []string{ContentTypeCloudEventJSON}}, []byte("{}"))
+	require.Error(t, err)
+}
+
+func TestCloudEventAMQP(t *testing.T) {
+	ce := newTestBindingEvent(t, []byte("data"))
+	ce.Extensions["blob"] = []byte{1}
+	ce.Extensions["link"], _ = url.Parse("https://contoso.com/widgets/1")
+	msg, err := MarshalAMQP(ce)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), msg.Data)
+	require.Nil(t, msg.ContentType)
+	require.Equal(t, map[string]any{
+		"cloudEvents:id":          ce.ID,
+		"cloudEvents:source":      "/contoso/widgets",
+		"cloudEvents:specversion": "1.0",
+		"cloudEvents:type":        "Contoso.Widget.Created",
+		"cloudEvents:dataschema":  "https://contoso.com/schema",
+		"cloudEvents:subject":     "widgets/1",
+		"cloudEvents:time":        *ce.Time,
+		"cloudEvents:tenant":      "a b\"%ü",
+		"cloudEvents:priority":    int32(2),
+		"cloudEvents:urgent":      true,
+		"cloudEvents:blob":        []byte{1},
+		"cloudEvents:link":        "https://contoso.com/widgets/1",
+	}, msg.ApplicationProperties)
+
+	// other application properties are ignored and the JMS prefix is accepted
+	msg.ApplicationProperties["other"] = "value"
+	msg.ApplicationProperties["cloudEvents_priority"] = msg.ApplicationProperties["cloudEvents:priority"]
+	delete(msg.ApplicationProperties, "cloudEvents:priority")
+	actual, err := UnmarshalAMQP(msg)
+	require.NoError(t, err)
+	expected := ce
+	expected.Extensions = map[string]any{
+		"tenant":   "a b\"%ü",
+		"priority": int32(2),
+		"urgent":   true,
+		"blob":     []byte{1},
+		"link":     "https://contoso.com/widgets/1",
+	}
+	require.Equal(t, expected, actual)
+
+	// structured mode
+	structured, err := ce.MarshalJSON()
+	require.NoError(t, err)
+	actual, err = UnmarshalAMQP(AMQPMessage{ContentType: to.Ptr(ContentTypeCloudEventJSON), Data: structured})
+	require.NoError(t, err)
+	require.Equal(t, ce.ID, actual.ID)
+	require.Equal(t, []byte("data"), actual.Data)
+
+	// invalid messages
+	_, err = UnmarshalAMQP(AMQPMessage{ApplicationProperties: map[string]any{"cloudEvents:id": 1}})
+	require.Error(t, err)
+	_, err = UnmarshalAMQP(AMQPMessage{ApplicationProperties: map[string]any{"cloudEvents:time": 1}})
+	require.Error(t, err)
+	_, err = UnmarshalAMQP(AMQPMessage{})
+	require.Error(t, err)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+// Package metrics contains the definitions needed to support recording metrics.
+// It parallels the tracing package; adapters for libraries such as OpenTelemetry provide implementations.
+package metrics
+
+import (
+	"context"
+)
+
+// ProviderOptions contains the optional values when creating a Provider.
+type ProviderOptions struct {
+	// for future expansion
+}
+
+// NewProvider creates a new Provider with the specified values.
+//   - newMeterFn is the underlying implementation for creating Meter instances
+//   - options contains optional values; pass nil to accept the default value
+func NewProvider(newMeterFn func(name, version string) Meter, options *ProviderOptions) Provider {
+	return Provider{
+		newMeterFn: newMeterFn,
+	}
+}
+
+// Provider is the factory that creates Meter instances.
+// It defaults to a no-op provider.
+type Provider struct {
+	newMeterFn func(name, version string) Meter
+}
+
+// NewMeter creates a new Meter for the specified module name and version.
+//   - module - the fully qualified name of the module
+//   - version - the version of the module
+func (p Provider) NewMeter(module, version string) (meter Meter) {
+	if p.newMeterFn != nil {
+		meter = p.newMeterFn(module, version)
+	}
+	return
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// MeterImpl abstracts the underlying implementation for Meter,
+// allowing it to work with various metrics implementations.
+// Any zero-values will have their default, no-op behavior.
+type MeterImpl struct {
+	// NewHistogram contains the implementation for the Meter.NewHistogram method.
+	NewHistogram func(name string, options *InstrumentOptions) Histogram
+
+	// NewCounter contains the implementation for the Meter.NewCounter method.
+	NewCounter func(name string, options *InstrumentOptions) Counter
+}
+
+// NewMeter creates a Meter with the specified implementation.
+func NewMeter(impl MeterImpl) Meter {
+	return Meter{
+		impl: impl,
+	}
+}
+
+// Meter is the factory that creates instruments.
+// A zero-value Meter creates no-op instruments.
+type Meter struct {
+	impl MeterImpl
+}
+
+// Enabled returns true if this Meter is capable of creating instruments.
+func (m Meter) Enabled() bool {
+	return m.impl.NewHistogram != nil || m.impl.NewCounter != nil
+}
+
+// NewHistogram creates a Histogram that records a distribution of values.
+//   - name is the name of the instrument, e.g. http.client.request.duration
+//   - options contains optional values for the instrument, pass nil to accept any defaults
+func (m Meter) NewHistogram(name string, options *InstrumentOptions) Histogram {
+	if m.impl.NewHistogram != nil {
+		return m.impl.NewHistogram(name, options)
+	}
+	return Histogram{}
+}
+
+// NewCounter creates a Counter that records monotonically increasing values.
+//   - name is the name of the instrument, e.g. az.client.retry.count
+//   - options contains optional values for the instrument, pass nil to accept any defaults
+func (m Meter) NewCounter(name string, options *InstrumentOptions) Counter {
+	if m.impl.NewCounter != nil {
+		return m.impl.NewCounter(name, options)
+	}
+	return Counter{}
+}
+
+// InstrumentOptions contains optional settings for creating an instrument.
+type InstrumentOptions struct {
+	// Description describes the instrument in human-readable terms.
+	Description string
+
+	// Unit is the unit of the recorded values, following the UCUM conventions, e.g. "s" or "By".
+	Unit string
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// HistogramImpl abstracts the underlying implementation for Histogram.
+// Any zero-values will have their default, no-op behavior.
+type HistogramImpl struct {
+	// Record contains the implementation for the Histogram.Record method.
+	Record func(context.Context, float64, ...Attribute)
+}
+
+// NewHistogram creates a Histogram with the specified implementation.
+func NewHistogram(impl HistogramImpl) Histogram {
+	return Histogram{
+		impl: impl,
+	}
+}
+
+// Histogram records a distribution of values, e.g. request durations.
+// A zero-value Histogram provides a no-op implementation.
+type Histogram struct {
+	impl HistogramImpl
+}
+
+// Record records value along with the specified attributes.
+func (h Histogram) Record(ctx context.Context, value float64, attrs ...Attribute) {
+	if h.impl.Record != nil {
+		h.impl.Record(ctx, value, attrs...)
+	}
+}
+
+// CounterImpl abstracts the underlying implementation for Counter.
+// Any zero-values will have their default, no-op behavior.
+type CounterImpl struct {
+	// Add contains the implementation for the Counter.Add method.
+	Add func(context.Context, int64, ...Attribute)
+}
+
+// NewCounter creates a Counter with the specified implementation.
+func NewCounter(impl CounterImpl) Counter {
+	return Counter{
+		impl: impl,
+	}
+}
+
+// Counter records monotonically increasing values, e.g. the number of retries.
+// A zero-value Counter provides a no-op implementation.
+type Counter struct {
+	impl CounterImpl
+}
+
+// Add increments the counter by incr along with the specified attributes.
+// incr must not be negative.
+func (c Counter) Add(ctx context.Context, incr int64, attrs ...Attribute) {
+	if c.impl.Add != nil {
+		c.impl.Add(ctx, incr, attrs...)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Attribute is a key-value pair.
+type Attribute struct {
+	// Key is the name of the attribute.
+	Key string
+
+	// Value is the attribute's value.
+	// Types that are natively supported include int64, float64, int, bool, string.
+	// Any other type will be formatted per rules of fmt.Sprintf("%v").
+	Value any
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderZeroValues(t *testing.T) {
+	pr := Provider{}
+	m := pr.NewMeter("name", "version")
+	require.Zero(t, m)
+	require.False(t, m.Enabled())
+	h := m.NewHistogram("histogram", nil)
+	require.Zero(t, h)
+	h.Record(context.Background(), 1.5, Attribute{})
+	c := m.NewCounter("counter", nil)
+	require.Zero(t, c)
+	c.Add(context.Background(), 1, Attribute{})
+}
+
+func TestProvider(t *testing.T) {
+	var recorded float64
+	var added int64
+	var histogramOpts, counterOpts *InstrumentOptions
+	var attrs []Attribute
+
+	pr := NewProvider(func(name, version string) Meter {
+		require.Equal(t, "name", name)
+		require.Equal(t, "version", version)
+		return NewMeter(MeterImpl{
+			NewHistogram: func(name string, options *InstrumentOptions) Histogram {
+				histogramOpts = options
+				return NewHistogram(HistogramImpl{
+					Record: func(_ context.Context, v float64, a ...Attribute) {
+						recorded = v
+						attrs = a
+					},
+				})
+			},
+			NewCounter: func(name string, options *InstrumentOptions) Counter {
+				counterOpts = options
+				return NewCounter(CounterImpl{
+					Add: func(_ context.Context, v int64, a ...Attribute) {
+						added += v
+						attrs = a
+					},
+				})
+			},
+		})
+	}, nil)
+	m := pr.NewMeter("name", "version")
+	require.True(t, m.Enabled())
+
+	h := m.NewHistogram("histogram", &InstrumentOptions{Unit: "s"})
+	require.Equal(t, "s", histogramOpts.Unit)
+	h.Record(context.Background(), 1.5, Attribute{Key: "some", Value: "attribute"})
+	require.EqualValues(t, 1.5, recorded)
+	require.Equal(t, []Attribute{{Key: "some", Value: "attribute"}}, attrs)
+
+	c := m.NewCounter("counter", &InstrumentOptions{Description: "counts things"})
+	require.Equal(t, "counts things", counterOpts.Description)
+	c.Add(context.Background(), 2)
+	c.Add(context.Background(), 3)
+	require.EqualValues(t, 5, added)
+	require.Empty(t, attrs)
+}
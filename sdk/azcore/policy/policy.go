@@ -14,6 +14,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/metrics"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/tracing"
 )
 
@@ -36,6 +37,9 @@ type ClientOptions struct {
 	// Set with caution as this package version has not been tested with arbitrary service versions.
 	APIVersion string
 
+	// Audit configures the audit hook, which is called with a record of each request the client sends.
+	Audit AuditOptions
+
 	// Cloud specifies a cloud for the client. The default is Azure Public Cloud.
 	Cloud cloud.Configuration
 
@@ -47,6 +51,17 @@ type ClientOptions struct {
 	// Logging configures the built-in logging policy.
 	Logging LogOptions
 
+	// MaxResponseBodySize is the maximum size, in bytes, of a response body read by runtime.Payload
+	// and the functions that use it, such as runtime.UnmarshalAsJSON. When a response body is decoded
+	// by runtime.CompressionPolicy, the limit also applies to the decoded body. Reading a larger body
+	// fails with a *runtime.ResponseBodyTooLargeError.
+	// The default value is zero, which doesn't limit the size of response bodies.
+	MaxResponseBodySize int64
+
+	// MeterProvider configures the metrics provider used to record HTTP request metrics.
+	// It defaults to a no-op provider.
+	MeterProvider metrics.Provider
+
 	// Retry configures the built-in retry policy.
 	Retry RetryOptions
 
@@ -86,6 +101,70 @@ type LogOptions struct {
 	AllowedQueryParams []string
 }
 
+// AuditOptions configures the audit hook.
+type AuditOptions struct {
+	// Hook is called with a record of each request sent by the client after its final response is
+	// received or it fails, including the history of all its tries. It's called synchronously, before
+	// the client method returns, and from concurrent goroutines when the client is used concurrently.
+	// Header values and query parameters are redacted as configured by [LogOptions].
+	// The default value is nil, which disables auditing.
+	Hook func(ctx context.Context, record AuditRecord)
+}
+
+// AuditRecord is a redacted snapshot of a request and its final response.
+// It doesn't share any state with the request, so the hook can retain it.
+type AuditRecord struct {
+	// Method is the request's HTTP method.
+	Method string
+
+	// URL is the request's URL. Values of query parameters not in LogOptions.AllowedQueryParams are REDACTED.
+	URL string
+
+	// RequestHeader contains the headers of the final try of the request.
+	// Values of headers not in LogOptions.AllowedHeaders are REDACTED.
+	RequestHeader http.Header
+
+	// StatusCode is the status code of the final response. It's zero when no response was received.
+	StatusCode int
+
+	// ResponseHeader contains the headers of the final response. It's nil when no response was received.
+	// Values of headers not in LogOptions.AllowedHeaders are REDACTED.
+	ResponseHeader http.Header
+
+	// Err is the error returned for the request, if any.
+	// When it contains a *url.Error, query parameters not in LogOptions.AllowedQueryParams
+	// are REDACTED from its URL and message, and errors.As returns the redacted *url.Error.
+	Err error
+
+	// Start is when the request was sent.
+	Start time.Time
+
+	// Duration is the time taken by the request, including all tries and the delays between them.
+	Duration time.Duration
+
+	// Tries contains the history of each try of the request, in the order they were sent.
+	Tries []AuditTry
+}
+
+// AuditTry is a record of a single try of a request.
+type AuditTry struct {
+	// Start is when the try was sent.
+	Start time.Time
+
+	// Duration is the time taken by the try.
+	Duration time.Duration
+
+	// Delay is the time between the end of the previous try and the start of this one.
+	// It's zero for the first try.
+	Delay time.Duration
+
+	// StatusCode is the status code of the try's response. It's zero when no response was received.
+	StatusCode int
+
+	// Err is the error returned by the try, if any. It's redacted like AuditRecord.Err.
+	Err error
+}
+
 // RetryOptions configures the retry policy's behavior.
 // Zero-value fields will have their specified default values applied during use.
 // This allows for modification of a subset of fields.
@@ -131,6 +210,52 @@ type RetryOptions struct {
 	// if one is nil, the other is not nil.
 	// A return value of true means the retry policy should retry.
 	ShouldRetry func(*http.Response, error) bool
+
+	// Backoff selects the strategy used to calculate the delay between retries
+	// when the HTTP response does not contain a Retry-After header.
+	// The default value is RetryBackoffExponential. Requests fail without being sent when
+	// it's set to a value that isn't one of the RetryBackoff constants.
+	Backoff RetryBackoff
+
+	// Budget caps the number of retries sent by a client at a share of the requests it sends,
+	// so that retries don't multiply the load on a service that's failing.
+	// Each client has its own budget, which is shared by all its requests.
+	// The default value is nil, which disables the budget.
+	// NOTE: this field is ignored when the options are specified with [WithRetryOptions]. Requests
+	// using such options still count against the client's budget, and their retries are limited by it.
+	Budget *RetryBudgetOptions
+}
+
+// RetryBackoff is the strategy used to calculate the delay between retries.
+type RetryBackoff string
+
+const (
+	// RetryBackoffExponential increases the delay exponentially with each retry, ((2 ^ try) - 1) * RetryDelay,
+	// multiplied by a random factor between 0.8 and 1.3. This is the default strategy.
+	RetryBackoffExponential RetryBackoff = "exponential"
+
+	// RetryBackoffFullJitter picks a random delay between zero and ((2 ^ try) - 1) * RetryDelay.
+	// It spreads the retries of many clients that failed at the same time.
+	RetryBackoffFullJitter RetryBackoff = "fullJitter"
+
+	// RetryBackoffDecorrelatedJitter picks a random delay between RetryDelay and three times the previous delay.
+	// The delay grows with each retry without synchronizing the retries of many clients.
+	RetryBackoffDecorrelatedJitter RetryBackoff = "decorrelatedJitter"
+)
+
+// RetryBudgetOptions configures a client's retry budget.
+// The budget allows a number of retries per second, plus a share of the requests
+// the client sent in the last ten seconds. Requests aren't retried once it's exhausted.
+// Zero-value fields will have their specified default values applied during use.
+type RetryBudgetOptions struct {
+	// Ratio is the maximum number of retries as a share of requests, e.g. 0.1 allows one retry for every ten requests.
+	// The default value is 0.1. A value less than zero allows no retries beyond MinRetriesPerSecond.
+	Ratio float64
+
+	// MinRetriesPerSecond is the number of retries allowed per second regardless of Ratio,
+	// so that clients sending few requests can still retry.
+	// The default value is 10. A value less than zero means no minimum.
+	MinRetriesPerSecond int
 }
 
 // TelemetryOptions configures the telemetry policy's behavior.
@@ -153,6 +278,15 @@ type BearerTokenOptions struct {
 	// its given credential.
 	AuthorizationHandler AuthorizationHandler
 
+	// BackgroundRefresh enables refreshing tokens in a background goroutine before they expire, at the time suggested
+	// by the token's RefreshOn field or 5 minutes before it expires when RefreshOn isn't set. The policy authorizes
+	// requests with the current token until the refreshed token arrives, so requests don't wait for token requests
+	// unless the current token expires. The policy stops refreshing in the background while it isn't sending requests
+	// and resumes when it sends another. Repeated refresh failures are logged as
+	// [github.com/Azure/azure-sdk-for-go/sdk/azcore/log.EventAuthentication] events.
+	// By default, the policy refreshes tokens when it authorizes a request close to the token's expiration.
+	BackgroundRefresh bool
+
 	// InsecureAllowCredentialWithHTTP enables authenticated requests over HTTP.
 	// By default, authenticated requests to an HTTP endpoint are rejected by the client.
 	// WARNING: setting this to true will allow sending the bearer token in clear text. Use with caution.
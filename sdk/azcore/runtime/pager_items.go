@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"iter"
+)
+
+// PagerItemsOptions contains the optional values for [PagerItems].
+type PagerItemsOptions struct {
+	// Prefetch indicates the next page is fetched in the background
+	// while the items of the current page are being iterated.
+	// The default value is false.
+	Prefetch bool
+}
+
+// PagerItems returns an iterator over the items of all pages retrieved by the pager.
+// Iteration stops after the last page, or after yielding the first error encountered
+// when fetching a page. The pager must not be used by the caller while iterating.
+//   - ctx is the [context.Context] controlling the lifetime of the HTTP operations
+//   - pager is the [Pager] that retrieves the pages
+//   - items returns the items contained in a page
+//   - options contains any optional parameters, pass nil to accept the default values
+func PagerItems[T, Item any](ctx context.Context, pager *Pager[T], items func(T) []Item, options *PagerItemsOptions) iter.Seq2[Item, error] {
+	if options == nil {
+		options = &PagerItemsOptions{}
+	}
+	return func(yield func(Item, error) bool) {
+		type pageResult struct {
+			page T
+			err  error
+		}
+
+		// fetch retrieves the next page. when prefetching is enabled, the page is
+		// retrieved on a separate goroutine and the returned func waits for it.
+		// the pager is only ever accessed by one goroutine at a time.
+		fetch := func(ctx context.Context) func() (T, error) {
+			if !options.Prefetch {
+				return func() (T, error) { return pager.NextPage(ctx) }
+			}
+			ch := make(chan pageResult, 1)
+			go func() {
+				page, err := pager.NextPage(ctx)
+				ch <- pageResult{page: page, err: err}
+			}()
+			return func() (T, error) {
+				r := <-ch
+				return r.page, r.err
+			}
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		if !pager.More() {
+			return
+		}
+		next := fetch(ctx)
+		for next != nil {
+			page, err := next()
+			if err != nil {
+				yield(*new(Item), err)
+				return
+			}
+			next = nil
+			if pager.More() {
+				next = fetch(ctx)
+			}
+			for _, item := range items(page) {
+				if !yield(item, nil) {
+					if next != nil && options.Prefetch {
+						// abandon the page being prefetched
+						cancel()
+						_, _ = next()
+					}
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestItemsPager(srv *mock.Server, onFetch func()) *Pager[PageResponse] {
+	pl := exported.NewPipeline(srv)
+	return NewPager(PagingHandler[PageResponse]{
+		More: func(current PageResponse) bool {
+			return current.NextPage
+		},
+		Fetcher: func(ctx context.Context, current *PageResponse) (PageResponse, error) {
+			if onFetch != nil {
+				onFetch()
+			}
+			return pageResponseFetcher(ctx, pl, srv.URL())
+		},
+	})
+}
+
+func pageValues(page PageResponse) []int {
+	return page.Values
+}
+
+func TestPagerItems(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("prefetch=%t", prefetch), func(t *testing.T) {
+			srv, close := mock.NewServer()
+			defer close()
+			srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [1, 2, 3], "next": true}`)))
+			srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [], "next": true}`)))
+			srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [4, 5]}`)))
+
+			var items []int
+			for item, err := range PagerItems(context.Background(), newTestItemsPager(srv, nil), pageValues, &PagerItemsOptions{Prefetch: prefetch}) {
+				require.NoError(t, err)
+				items = append(items, item)
+			}
+			require.Equal(t, []int{1, 2, 3, 4, 5}, items)
+			require.EqualValues(t, 3, srv.Requests())
+		})
+	}
+}
+
+func TestPagerItemsPrefetch(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [1, 2], "next": true}`)))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [3]}`)))
+
+	fetched := make(chan struct{}, 2)
+	pager := newTestItemsPager(srv, func() { fetched <- struct{}{} })
+	var items []int
+	for item, err := range PagerItems(context.Background(), pager, pageValues, &PagerItemsOptions{Prefetch: true}) {
+		require.NoError(t, err)
+		if item == 1 {
+			// the first page has been fetched, the second page is fetched while this item is being processed
+			<-fetched
+			<-fetched
+		}
+		items = append(items, item)
+	}
+	require.Equal(t, []int{1, 2, 3}, items)
+	require.False(t, pager.More())
+}
+
+func TestPagerItemsBreak(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("prefetch=%t", prefetch), func(t *testing.T) {
+			srv, close := mock.NewServer()
+			defer close()
+			srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [1, 2, 3], "next": true}`)))
+			srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [4, 5]}`)))
+
+			pager := newTestItemsPager(srv, nil)
+			for item, err := range PagerItems(context.Background(), pager, pageValues, &PagerItemsOptions{Prefetch: prefetch}) {
+				require.NoError(t, err)
+				if item == 2 {
+					break
+				}
+			}
+			// when prefetching, the second page might have been requested. either way, the
+			// iterator must be done with the pager (the race detector catches any misuse)
+			require.LessOrEqual(t, srv.Requests(), 2)
+			_ = pager.More()
+		})
+	}
+}
+
+func TestPagerItemsError(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("prefetch=%t", prefetch), func(t *testing.T) {
+			srv, close := mock.NewServer()
+			defer close()
+			srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [1, 2], "next": true}`)))
+			srv.AppendResponse(mock.WithStatusCode(http.StatusBadRequest), mock.WithBody([]byte(`{"error": {"code": "BadRequest"}}`)))
+
+			var items []int
+			var errs []error
+			for item, err := range PagerItems(context.Background(), newTestItemsPager(srv, nil), pageValues, &PagerItemsOptions{Prefetch: prefetch}) {
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				items = append(items, item)
+			}
+			require.Equal(t, []int{1, 2}, items)
+			require.Len(t, errs, 1)
+			var respErr *exported.ResponseError
+			require.True(t, errors.As(errs[0], &respErr))
+			require.Equal(t, "BadRequest", respErr.ErrorCode)
+		})
+	}
+}
+
+func TestPagerItemsLRO(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"values": [3]}`)))
+	pager := newTestItemsPager(srv, nil)
+	// the first page has already been retrieved
+	require.NoError(t, json.Unmarshal([]byte(`{"values": [1, 2], "next": true}`), pager))
+
+	var items []int
+	for item, err := range PagerItems(context.Background(), pager, pageValues, nil) {
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	require.Equal(t, []int{1, 2, 3}, items)
+	require.EqualValues(t, 1, srv.Requests())
+}
@@ -41,6 +41,7 @@ type PipelineOptions struct {
 // TracingOptions contains tracing options for SDK developers.
 type TracingOptions struct {
 	// Namespace contains the value to use for the az.namespace span attribute.
+	// It's also applied as an attribute to the HTTP request metrics and structured log entries.
 	Namespace string
 }
 
@@ -71,6 +72,9 @@ func NewPipeline(module, version string, plOpts PipelineOptions, options *policy
 	// we put the includeResponsePolicy at the very beginning so that the raw response
 	// is populated with the final response (some policies might mutate the response)
 	policies := []policy.Policy{exported.PolicyFunc(includeResponsePolicy)}
+	if cp.MaxResponseBodySize > 0 {
+		policies = append(policies, newMaxResponseBodySizePolicy(cp.MaxResponseBodySize))
+	}
 	if cp.APIVersion != "" {
 		policies = append(policies, newAPIVersionPolicy(cp.APIVersion, &plOpts.APIVersion))
 	}
@@ -79,12 +83,22 @@ func NewPipeline(module, version string, plOpts PipelineOptions, options *policy
 	}
 	policies = append(policies, plOpts.PerCall...)
 	policies = append(policies, cp.PerCallPolicies...)
-	policies = append(policies, NewRetryPolicy(&cp.Retry))
+	auditPolicy, auditTryPolicy := newAuditPolicies(&cp.Audit, &cp.Logging)
+	if auditPolicy != nil {
+		policies = append(policies, auditPolicy)
+	}
+	policies = append(policies, newRetryPolicy(&cp.Retry, plOpts.Tracing.Namespace))
 	policies = append(policies, plOpts.PerRetry...)
 	policies = append(policies, cp.PerRetryPolicies...)
 	policies = append(policies, exported.PolicyFunc(httpHeaderPolicy))
+	if auditTryPolicy != nil {
+		policies = append(policies, auditTryPolicy)
+	}
 	policies = append(policies, newHTTPTracePolicy(cp.Logging.AllowedQueryParams))
-	policies = append(policies, NewLogPolicy(&cp.Logging))
+	if meter := cp.MeterProvider.NewMeter(module, version); meter.Enabled() {
+		policies = append(policies, newHTTPMetricsPolicy(meter, plOpts.Tracing.Namespace))
+	}
+	policies = append(policies, newLogPolicy(&cp.Logging, plOpts.Tracing.Namespace))
 	policies = append(policies, exported.PolicyFunc(bodyDownloadPolicy))
 	transport := cp.Transport
 	if transport == nil {
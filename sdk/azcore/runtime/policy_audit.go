@@ -0,0 +1,168 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// auditPolicy calls the audit hook with a record of each request after all its tries.
+// It's placed before the retry policy, and its companion auditTryPolicy is placed
+// after the per-retry policies so that it observes each try as it's sent.
+type auditPolicy struct {
+	hook           func(context.Context, policy.AuditRecord)
+	allowedHeaders map[string]struct{}
+	allowedQP      map[string]struct{}
+	now            func() time.Time
+}
+
+// auditTryPolicy records the history of each try in the operation's auditHistory
+type auditTryPolicy struct {
+	audit *auditPolicy
+}
+
+// newAuditPolicies returns the policies that implement the audit hook, or nil when o.Hook is nil.
+// Header values and query parameters are redacted as configured by logOpts.
+func newAuditPolicies(o *policy.AuditOptions, logOpts *policy.LogOptions) (*auditPolicy, *auditTryPolicy) {
+	if o == nil || o.Hook == nil {
+		return nil, nil
+	}
+	if logOpts == nil {
+		logOpts = &policy.LogOptions{}
+	}
+	ap := &auditPolicy{
+		hook:           o.Hook,
+		allowedHeaders: getAllowedHeaders(logOpts.AllowedHeaders),
+		allowedQP:      getAllowedQueryParams(logOpts.AllowedQueryParams),
+		now:            time.Now,
+	}
+	return ap, &auditTryPolicy{audit: ap}
+}
+
+// auditHistory is the per-operation value shared by the audit policies.
+// It's shared with concurrent copies of the request, e.g. hedged requests, so it's guarded by a mutex.
+type auditHistory struct {
+	mu sync.Mutex
+	// header is a redacted copy of the headers of the most recent try
+	header http.Header
+	tries  []policy.AuditTry
+}
+
+func (p *auditPolicy) Do(req *policy.Request) (*http.Response, error) {
+	history := &auditHistory{}
+	req.SetOperationValue(history)
+
+	start := p.now()
+	resp, err := req.Next()
+	record := policy.AuditRecord{
+		Method:   req.Raw().Method,
+		URL:      getSanitizedURL(*req.Raw().URL, p.allowedQP),
+		Err:      p.sanitize(err),
+		Start:    start,
+		Duration: p.now().Sub(start),
+	}
+	if resp != nil {
+		record.StatusCode = resp.StatusCode
+		record.ResponseHeader = p.redact(resp.Header)
+	}
+
+	history.mu.Lock()
+	record.RequestHeader = history.header
+	record.Tries = history.tries
+	history.mu.Unlock()
+	if record.RequestHeader == nil {
+		// the request failed before it was sent, e.g. in a per-call policy
+		record.RequestHeader = p.redact(req.Raw().Header)
+	}
+
+	p.hook(req.Raw().Context(), record)
+	return resp, err
+}
+
+func (p *auditTryPolicy) Do(req *policy.Request) (*http.Response, error) {
+	var history *auditHistory
+	if !req.OperationValue(&history) {
+		return req.Next()
+	}
+	header := p.audit.redact(req.Raw().Header)
+	start := p.audit.now()
+	resp, err := req.Next()
+	try := policy.AuditTry{
+		Start:    start,
+		Duration: p.audit.now().Sub(start),
+		Err:      p.audit.sanitize(err),
+	}
+	if resp != nil {
+		try.StatusCode = resp.StatusCode
+	}
+
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	if n := len(history.tries); n > 0 {
+		prev := history.tries[n-1]
+		if delay := start.Sub(prev.Start.Add(prev.Duration)); delay > 0 {
+			try.Delay = delay
+		}
+	}
+	history.header = header
+	history.tries = append(history.tries, try)
+	return resp, err
+}
+
+// redact returns a copy of header with the values of headers not in the allow-list REDACTED
+func (p *auditPolicy) redact(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for k, values := range header {
+		if _, ok := p.allowedHeaders[strings.ToLower(k)]; ok {
+			redacted[k] = append([]string(nil), values...)
+			continue
+		}
+		redacted[k] = make([]string, len(values))
+		for i := range values {
+			redacted[k][i] = redactedValue
+		}
+	}
+	return redacted
+}
+
+// sanitize returns err with the query parameters not in the allow-list REDACTED from the URL
+// of any *url.Error it contains, e.g. the SAS signature of a request that failed to send.
+// Errors without a *url.Error are returned unchanged.
+func (p *auditPolicy) sanitize(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	sanitizedURL := redactedValue
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		sanitizedURL = getSanitizedURL(*u, p.allowedQP)
+	}
+	return &sanitizedError{
+		msg: strings.ReplaceAll(err.Error(), urlErr.URL, sanitizedURL),
+		err: &url.Error{Op: urlErr.Op, URL: sanitizedURL, Err: urlErr.Err},
+	}
+}
+
+// sanitizedError is an error whose message has been redacted.
+// It wraps a copy of the original *url.Error having the redacted URL.
+type sanitizedError struct {
+	msg string
+	err *url.Error
+}
+
+func (e *sanitizedError) Error() string {
+	return e.msg
+}
+
+func (e *sanitizedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,186 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHook(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusCreated), mock.WithHeader("x-ms-request-id", "abc"), mock.WithHeader("x-secret", "s3cret"))
+
+	type ctxKey struct{}
+	var records []policy.AuditRecord
+	pl := newTestPipeline(&policy.ClientOptions{
+		Audit: policy.AuditOptions{
+			Hook: func(ctx context.Context, record policy.AuditRecord) {
+				require.Equal(t, "caller", ctx.Value(ctxKey{}))
+				records = append(records, record)
+			},
+		},
+		Logging: policy.LogOptions{
+			AllowedHeaders:     []string{"x-allowed"},
+			AllowedQueryParams: []string{"name"},
+		},
+		PerRetryPolicies: []policy.Policy{exported.PolicyFunc(func(req *policy.Request) (*http.Response, error) {
+			req.Raw().Header.Set("Authorization", "Bearer token")
+			return req.Next()
+		})},
+		Retry:     *testRetryOptions(),
+		Transport: srv,
+	})
+
+	req, err := NewRequest(context.WithValue(context.Background(), ctxKey{}, "caller"), http.MethodPut, srv.URL()+"?name=widget&sig=s3cret")
+	require.NoError(t, err)
+	req.Raw().Header.Set("x-allowed", "visible")
+	require.NoError(t, req.SetBody(streaming.NopCloser(strings.NewReader(`{}`)), "application/json"))
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	require.Len(t, records, 1)
+	record := records[0]
+	require.Equal(t, http.MethodPut, record.Method)
+	require.Equal(t, srv.URL()+"?name=widget&sig=REDACTED", record.URL)
+	require.Equal(t, "visible", record.RequestHeader.Get("x-allowed"))
+	require.Equal(t, "application/json", record.RequestHeader.Get("Content-Type"))
+	// headers set by per-retry policies are included and redacted
+	require.Equal(t, redactedValue, record.RequestHeader.Get("Authorization"))
+	require.Equal(t, http.StatusCreated, record.StatusCode)
+	require.Equal(t, "abc", record.ResponseHeader.Get("x-ms-request-id"))
+	require.Equal(t, redactedValue, record.ResponseHeader.Get("x-secret"))
+	require.NoError(t, record.Err)
+	require.False(t, record.Start.IsZero())
+
+	require.Len(t, record.Tries, 2)
+	require.Equal(t, http.StatusServiceUnavailable, record.Tries[0].StatusCode)
+	require.Zero(t, record.Tries[0].Delay)
+	require.Equal(t, http.StatusCreated, record.Tries[1].StatusCode)
+	require.Positive(t, record.Tries[1].Delay)
+	require.False(t, record.Tries[1].Start.Before(record.Tries[0].Start.Add(record.Tries[0].Duration)))
+	require.GreaterOrEqual(t, record.Duration, record.Tries[0].Duration+record.Tries[1].Delay+record.Tries[1].Duration)
+
+	// the record doesn't share state with the request or response
+	record.RequestHeader.Set("x-allowed", "changed")
+	record.ResponseHeader.Set("x-ms-request-id", "changed")
+	require.Equal(t, "visible", req.Raw().Header.Get("x-allowed"))
+	require.Equal(t, "abc", resp.Header.Get("x-ms-request-id"))
+	require.Empty(t, req.Raw().Header.Get("Authorization"))
+}
+
+func TestAuditHookFailedRequest(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetError(errors.New("connection reset"))
+
+	var records []policy.AuditRecord
+	pl := newTestPipeline(&policy.ClientOptions{
+		Audit: policy.AuditOptions{
+			Hook: func(ctx context.Context, record policy.AuditRecord) {
+				records = append(records, record)
+			},
+		},
+		Retry:     policy.RetryOptions{MaxRetries: 2, RetryDelay: time.Millisecond},
+		Transport: srv,
+	})
+	req, err := NewRequest(context.Background(), http.MethodDelete, srv.URL())
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.Error(t, err)
+
+	require.Len(t, records, 1)
+	require.Equal(t, http.MethodDelete, records[0].Method)
+	require.Zero(t, records[0].StatusCode)
+	require.Nil(t, records[0].ResponseHeader)
+	require.ErrorIs(t, records[0].Err, err)
+	require.Len(t, records[0].Tries, 3)
+	for _, try := range records[0].Tries {
+		require.Zero(t, try.StatusCode)
+		require.ErrorContains(t, try.Err, "connection reset")
+	}
+}
+
+func TestAuditHookRedactsErrorURL(t *testing.T) {
+	var records []policy.AuditRecord
+	pl := newTestPipeline(&policy.ClientOptions{
+		Audit: policy.AuditOptions{
+			Hook: func(ctx context.Context, record policy.AuditRecord) {
+				records = append(records, record)
+			},
+		},
+		Retry: policy.RetryOptions{MaxRetries: -1},
+		Transport: shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, &url.Error{Op: "Get", URL: req.URL.String(), Err: context.DeadlineExceeded}
+		}),
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.blob.core.windows.net/c/b?api-version=1&sig=s3cret")
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.ErrorContains(t, err, "s3cret")
+
+	require.Len(t, records, 1)
+	require.Len(t, records[0].Tries, 1)
+	for _, recordErr := range []error{records[0].Err, records[0].Tries[0].Err} {
+		require.NotContains(t, recordErr.Error(), "s3cret")
+		require.Contains(t, recordErr.Error(), "sig=REDACTED")
+		require.Contains(t, recordErr.Error(), "api-version=1")
+		require.ErrorIs(t, recordErr, context.DeadlineExceeded)
+		var urlErr *url.Error
+		require.ErrorAs(t, recordErr, &urlErr)
+		require.NotContains(t, urlErr.URL, "s3cret")
+	}
+}
+
+func TestAuditHookPerCallFailure(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+
+	var records []policy.AuditRecord
+	pl := newTestPipeline(&policy.ClientOptions{
+		Audit: policy.AuditOptions{
+			Hook: func(ctx context.Context, record policy.AuditRecord) {
+				records = append(records, record)
+			},
+		},
+		PerRetryPolicies: []policy.Policy{exported.PolicyFunc(func(req *policy.Request) (*http.Response, error) {
+			return nil, errors.New("no token")
+		})},
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+		Transport: srv,
+	})
+	req, err := NewRequest(context.Background(), http.MethodPost, srv.URL())
+	require.NoError(t, err)
+	req.Raw().Header.Set("x-secret", "s3cret")
+	_, err = pl.Do(req)
+	require.EqualError(t, err, "no token")
+
+	// the request was never sent
+	require.Len(t, records, 1)
+	require.Empty(t, records[0].Tries)
+	require.Equal(t, redactedValue, records[0].RequestHeader.Get("x-secret"))
+	require.Zero(t, srv.Requests())
+}
+
+func TestAuditHookDisabled(t *testing.T) {
+	ap, atp := newAuditPolicies(&policy.AuditOptions{}, nil)
+	require.Nil(t, ap)
+	require.Nil(t, atp)
+}
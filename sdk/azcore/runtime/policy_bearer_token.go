@@ -4,15 +4,18 @@
 package runtime
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/errorinfo"
@@ -28,6 +31,8 @@ import (
 type BearerTokenPolicy struct {
 	// mainResource is the resource to be retreived using the tenant specified in the credential
 	mainResource *temporal.Resource[exported.AccessToken, acquiringResourceState]
+	// refresher refreshes mainResource in the background; it's nil unless background refresh is enabled
+	refresher *backgroundRefresher
 	// the following fields are read-only
 	authzHandler policy.AuthorizationHandler
 	cred         exported.TokenCredential
@@ -44,20 +49,39 @@ type acquiringResourceState struct {
 // acquire acquires or updates the resource; only one
 // thread/goroutine at a time ever calls this function
 func acquire(state acquiringResourceState) (newResource exported.AccessToken, newExpiration time.Time, err error) {
-	tk, err := state.p.cred.GetToken(&shared.ContextWithDeniedValues{Context: state.req.Raw().Context()}, state.tro)
+	r := state.p.refresher
+	if r != nil {
+		if tk, ok := r.takePending(state.tro); ok {
+			r.schedule(tk)
+			return tk, tk.ExpiresOn, nil
+		}
+	}
+	var ctx context.Context = context.Background()
+	if state.req != nil {
+		ctx = &shared.ContextWithDeniedValues{Context: state.req.Raw().Context()}
+	}
+	tk, err := state.p.cred.GetToken(ctx, state.tro)
 	if err != nil {
 		return exported.AccessToken{}, time.Time{}, err
 	}
+	if r != nil {
+		r.schedule(tk)
+	}
 	return tk, tk.ExpiresOn, nil
 }
 
 // shouldRefresh determines whether the token should be refreshed. It's a variable so tests can replace it.
 var shouldRefresh = func(tk exported.AccessToken, _ acquiringResourceState) bool {
+	return refreshTime(tk).Before(time.Now())
+}
+
+// refreshTime returns the time at which tk should be refreshed
+func refreshTime(tk exported.AccessToken) time.Time {
 	if tk.RefreshOn.IsZero() {
-		return tk.ExpiresOn.Add(-5 * time.Minute).Before(time.Now())
+		return tk.ExpiresOn.Add(-5 * time.Minute)
 	}
 	// no offset in this case because the authority suggested a refresh window--between RefreshOn and ExpiresOn
-	return tk.RefreshOn.Before(time.Now())
+	return tk.RefreshOn
 }
 
 // NewBearerTokenPolicy creates a policy object that authorizes requests with bearer tokens.
@@ -78,16 +102,25 @@ func NewBearerTokenPolicy(cred exported.TokenCredential, scopes []string, opts *
 			return authNZ(policy.TokenRequestOptions{Scopes: scopes})
 		}
 	}
-	mr := temporal.NewResourceWithOptions(acquire, temporal.ResourceOptions[exported.AccessToken, acquiringResourceState]{
+	ro := temporal.ResourceOptions[exported.AccessToken, acquiringResourceState]{
 		ShouldRefresh: shouldRefresh,
-	})
-	return &BearerTokenPolicy{
+	}
+	if opts.BackgroundRefresh {
+		// the refresher updates the token before it expires, so requests
+		// should use the current token until it does
+		ro.ShouldRefresh = func(exported.AccessToken, acquiringResourceState) bool { return false }
+	}
+	b := &BearerTokenPolicy{
 		authzHandler: ah,
 		cred:         cred,
 		scopes:       scopes,
-		mainResource: mr,
+		mainResource: temporal.NewResourceWithOptions(acquire, ro),
 		allowHTTP:    opts.InsecureAllowCredentialWithHTTP,
 	}
+	if opts.BackgroundRefresh {
+		b.refresher = &backgroundRefresher{p: b, retryDelay: backgroundRefreshRetryDelay}
+	}
+	return b
 }
 
 // authenticateAndAuthorize returns a function which authorizes req with a token from the policy's credential
@@ -99,6 +132,9 @@ func (b *BearerTokenPolicy) authenticateAndAuthorize(req *policy.Request) func(p
 		if err != nil {
 			return err
 		}
+		if b.refresher != nil {
+			b.refresher.touch(tk, tro)
+		}
 		req.Raw().Header.Set(shared.HeaderAuthorization, shared.BearerTokenPrefix+tk.Token)
 		return nil
 	}
@@ -178,6 +214,153 @@ func (b *BearerTokenPolicy) handleChallenge(req *policy.Request, res *http.Respo
 	return res, err
 }
 
+// backgroundRefreshRetryDelay is the minimum time between background refresh attempts.
+// It's a variable so tests can replace it.
+var backgroundRefreshRetryDelay = 30 * time.Second
+
+// backgroundRefreshFailureThreshold is the number of consecutive background refresh failures after which
+// the refresher logs each failure
+const backgroundRefreshFailureThreshold = 3
+
+// backgroundRefresher refreshes a BearerTokenPolicy's token before it expires. A timer fires at the token's
+// refresh time; when it does, the refresher acquires a new token from the credential and installs it in the
+// policy's temporal.Resource. The refresher stops when the policy hasn't used the token since the last
+// refresh, so it doesn't keep an abandoned policy alive, and restarts when the policy uses the token again.
+type backgroundRefresher struct {
+	p          *BearerTokenPolicy
+	retryDelay time.Duration
+
+	// mu guards the following fields
+	mu sync.Mutex
+	// expiresOn is the expiration of the token most recently installed in the policy
+	expiresOn time.Time
+	// failures counts consecutive failed refresh attempts
+	failures int
+	// lastAttempt is when the refresher last requested a token
+	lastAttempt time.Time
+	// pending is a token the refresher acquired, for acquire to install in the policy's resource
+	pending *exported.AccessToken
+	// pendingTRO is the TokenRequestOptions the refresher used to acquire pending
+	pendingTRO policy.TokenRequestOptions
+	// timer fires at the next refresh time; it's nil when the refresher is stopped
+	timer *time.Timer
+	// tro is the most recent TokenRequestOptions the policy used to authorize a request
+	tro policy.TokenRequestOptions
+	// used indicates whether the policy authorized a request since the last refresh
+	used bool
+}
+
+// touch records that the policy authorized a request with tk, restarting the refresher if it's stopped
+func (r *backgroundRefresher) touch(tk exported.AccessToken, tro policy.TokenRequestOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// claims are specific to the challenge that prompted the request for them
+	tro.Claims = ""
+	r.tro = tro
+	r.used = true
+	if r.timer == nil {
+		r.scheduleLocked(tk)
+	}
+}
+
+// schedule sets the timer to refresh tk at its refresh time
+func (r *backgroundRefresher) schedule(tk exported.AccessToken) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scheduleLocked(tk)
+}
+
+func (r *backgroundRefresher) scheduleLocked(tk exported.AccessToken) {
+	r.expiresOn = tk.ExpiresOn
+	at := refreshTime(tk)
+	// a credential may return a cached token that's already due for refresh, so
+	// limit the attempt rate to avoid requesting tokens in a tight loop
+	if earliest := r.lastAttempt.Add(r.retryDelay); at.Before(earliest) {
+		at = earliest
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.timer = time.AfterFunc(time.Until(at), r.refresh)
+}
+
+// takePending returns the token acquired by a refresh in progress, if there is one and the refresh
+// requested it with the same options as tro. In particular, a token acquired without claims can't
+// satisfy a CAE claims challenge.
+func (r *backgroundRefresher) takePending(tro policy.TokenRequestOptions) (exported.AccessToken, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil || !sameTokenRequest(r.pendingTRO, tro) {
+		return exported.AccessToken{}, false
+	}
+	tk := *r.pending
+	r.pending = nil
+	return tk, true
+}
+
+// refresh acquires a new token and installs it in the policy's resource. The timer calls it.
+func (r *backgroundRefresher) refresh() {
+	r.mu.Lock()
+	if !r.used {
+		// the policy hasn't used the token since the last refresh
+		r.timer = nil
+		r.mu.Unlock()
+		return
+	}
+	r.used = false
+	r.lastAttempt = time.Now()
+	tro := r.tro
+	// a token acquired after the current one expires is no use to the refresher, so the
+	// attempt is bounded by the current token's remaining lifetime. Requests authorized
+	// after then acquire a token themselves.
+	timeout := time.Until(r.expiresOn)
+	r.mu.Unlock()
+	if timeout < r.retryDelay {
+		timeout = r.retryDelay
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	tk, err := r.p.cred.GetToken(ctx, tro)
+	cancel()
+
+	r.mu.Lock()
+	if err != nil {
+		r.failures++
+		if r.failures >= backgroundRefreshFailureThreshold {
+			log.Writef(log.EventAuthentication, "BearerTokenPolicy failed to refresh a token %d consecutive times: %v", r.failures, err)
+		}
+		if time.Now().Add(r.retryDelay).Before(r.expiresOn) {
+			// retry while the current token is valid. The retry stops the refresher
+			// if the policy doesn't use the token before then.
+			r.timer = time.AfterFunc(r.retryDelay, r.refresh)
+		} else {
+			// the policy will acquire a token when it next authorizes a request
+			r.timer = nil
+		}
+		r.mu.Unlock()
+		return
+	}
+	r.failures = 0
+	r.pending = &tk
+	r.pendingTRO = tro
+	r.mu.Unlock()
+
+	// expiring the resource ensures the next Get acquires a token, which it takes from pending
+	r.p.mainResource.Expire()
+	_, _ = r.p.mainResource.Get(acquiringResourceState{p: r.p, tro: tro})
+
+	// another goroutine may have acquired a token before this one's Get; discard
+	// the pending token so it can't replace a token acquired later
+	r.mu.Lock()
+	r.pending = nil
+	r.mu.Unlock()
+}
+
+// sameTokenRequest returns true if a and b request the same token
+func sameTokenRequest(a, b policy.TokenRequestOptions) bool {
+	return a.Claims == b.Claims && a.EnableCAE == b.EnableCAE && a.TenantID == b.TenantID && slices.Equal(a.Scopes, b.Scopes)
+}
+
 func checkHTTPSForAuth(req *policy.Request, allowHTTP bool) error {
 	if strings.ToLower(req.Raw().URL.Scheme) != "https" && !allowHTTP {
 		return errorinfo.NonRetriableError(errors.New("authenticated requests are not permitted for non TLS protected (https) endpoints"))
@@ -12,10 +12,13 @@ import (
 
 	"errors"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
@@ -693,3 +696,212 @@ func TestBearerTokenPolicy_NilCredential(t *testing.T) {
 	_, err = pl.Do(req)
 	require.NoError(t, err)
 }
+
+// newBackgroundRefreshTestPipeline returns a background refreshing BearerTokenPolicy and a func that
+// sends a request through a pipeline containing the policy, returning the token that authorized it
+func newBackgroundRefreshTestPipeline(t *testing.T, cred exported.TokenCredential) (*BearerTokenPolicy, func() string) {
+	before := backgroundRefreshRetryDelay
+	backgroundRefreshRetryDelay = 10 * time.Millisecond
+	t.Cleanup(func() { backgroundRefreshRetryDelay = before })
+	b := NewBearerTokenPolicy(cred, []string{scope}, &policy.BearerTokenOptions{BackgroundRefresh: true})
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerRetryPolicies: []policy.Policy{b},
+		Transport: shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Token": req.Header.Values(shared.HeaderAuthorization)}, Body: http.NoBody}, nil
+		}),
+	})
+	return b, func() string {
+		req, err := NewRequest(context.Background(), http.MethodGet, "https://localhost")
+		require.NoError(t, err)
+		res, err := pl.Do(req)
+		require.NoError(t, err)
+		return strings.TrimPrefix(res.Header.Get("Token"), shared.BearerTokenPrefix)
+	}
+}
+
+func TestBearerTokenPolicy_BackgroundRefresh(t *testing.T) {
+	calls := atomic.Int32{}
+	cred := mockCredential{
+		getTokenImpl: func(ctx context.Context, tro policy.TokenRequestOptions) (exported.AccessToken, error) {
+			require.Equal(t, []string{scope}, tro.Scopes)
+			require.True(t, tro.EnableCAE)
+			n := calls.Add(1)
+			return exported.AccessToken{
+				Token:     fmt.Sprint(n),
+				ExpiresOn: time.Now().Add(time.Hour),
+				RefreshOn: time.Now().Add(50 * time.Millisecond),
+			}, nil
+		},
+	}
+	_, send := newBackgroundRefreshTestPipeline(t, cred)
+	require.Equal(t, "1", send())
+	require.Eventually(t, func() bool { return calls.Load() > 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return send() != "1" }, 5*time.Second, 10*time.Millisecond)
+
+	// the refresher should stop when the policy isn't authorizing requests
+	time.Sleep(200 * time.Millisecond)
+	n := calls.Load()
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(t, n, calls.Load())
+
+	// and restart when it authorizes another
+	send()
+	require.Eventually(t, func() bool { return calls.Load() > n }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBearerTokenPolicy_BackgroundRefreshServesCurrentToken(t *testing.T) {
+	calls := atomic.Int32{}
+	refreshing, release := make(chan struct{}), make(chan struct{})
+	cred := mockCredential{
+		getTokenImpl: func(ctx context.Context, tro policy.TokenRequestOptions) (exported.AccessToken, error) {
+			if calls.Add(1) == 1 {
+				return exported.AccessToken{Token: "1", ExpiresOn: time.Now().Add(time.Hour), RefreshOn: time.Now()}, nil
+			}
+			close(refreshing)
+			<-release
+			return exported.AccessToken{Token: "2", ExpiresOn: time.Now().Add(time.Hour)}, nil
+		},
+	}
+	_, send := newBackgroundRefreshTestPipeline(t, cred)
+	require.Equal(t, "1", send())
+	select {
+	case <-refreshing:
+	case <-time.After(5 * time.Second):
+		t.Fatal("policy didn't refresh the token")
+	}
+	// requests shouldn't wait for the refresh
+	require.Equal(t, "1", send())
+	close(release)
+	require.Eventually(t, func() bool { return send() == "2" }, 5*time.Second, 10*time.Millisecond)
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestBearerTokenPolicy_BackgroundRefreshTimeout(t *testing.T) {
+	calls := atomic.Int32{}
+	expiresOn := time.Now().Add(time.Hour)
+	deadlines := make(chan time.Time, 1)
+	cred := mockCredential{
+		getTokenImpl: func(ctx context.Context, tro policy.TokenRequestOptions) (exported.AccessToken, error) {
+			if calls.Add(1) == 1 {
+				return exported.AccessToken{Token: "1", ExpiresOn: expiresOn, RefreshOn: time.Now()}, nil
+			}
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok, "background refresh should have a deadline")
+			select {
+			case deadlines <- deadline:
+			default:
+			}
+			return exported.AccessToken{}, errors.New("refresh failed")
+		},
+	}
+	_, send := newBackgroundRefreshTestPipeline(t, cred)
+	require.Equal(t, "1", send())
+	select {
+	case deadline := <-deadlines:
+		// the refresh is bounded by the current token's lifetime
+		require.WithinDuration(t, expiresOn, deadline, time.Second)
+	case <-time.After(5 * time.Second):
+		t.Fatal("policy didn't refresh the token")
+	}
+}
+
+func TestBearerTokenPolicy_BackgroundRefreshDuringChallenge(t *testing.T) {
+	claims := base64.StdEncoding.EncodeToString([]byte("claims"))
+	calls := atomic.Int32{}
+	cred := mockCredential{
+		getTokenImpl: func(ctx context.Context, tro policy.TokenRequestOptions) (exported.AccessToken, error) {
+			calls.Add(1)
+			tk := exported.AccessToken{Token: "1", ExpiresOn: time.Now().Add(time.Hour), RefreshOn: time.Now().Add(time.Hour)}
+			if tro.Claims != "" {
+				require.Equal(t, "claims", tro.Claims)
+				tk.Token = "claims"
+			}
+			return tk, nil
+		},
+	}
+	b := NewBearerTokenPolicy(cred, []string{scope}, &policy.BearerTokenOptions{BackgroundRefresh: true})
+	challenge := atomic.Bool{}
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerRetryPolicies: []policy.Policy{b},
+		Retry:            policy.RetryOptions{MaxRetries: -1},
+		Transport: shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+			auth := strings.TrimPrefix(req.Header.Get(shared.HeaderAuthorization), shared.BearerTokenPrefix)
+			if challenge.Load() && auth != "claims" {
+				header := http.Header{}
+				header.Set(shared.HeaderWWWAuthenticate, fmt.Sprintf(`Bearer error="insufficient_claims", claims=%q`, claims))
+				return &http.Response{StatusCode: http.StatusUnauthorized, Header: header, Body: http.NoBody}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Token": {auth}}, Body: http.NoBody}, nil
+		}),
+	})
+	send := func() *http.Response {
+		req, err := NewRequest(context.Background(), http.MethodGet, "https://localhost")
+		require.NoError(t, err)
+		res, err := pl.Do(req)
+		require.NoError(t, err)
+		return res
+	}
+	require.Equal(t, "1", send().Header.Get("Token"))
+
+	// a background refresh acquired a token but hasn't installed it when the service challenges for claims
+	b.refresher.mu.Lock()
+	b.refresher.pending = &exported.AccessToken{Token: "refreshed", ExpiresOn: time.Now().Add(time.Hour)}
+	b.refresher.pendingTRO = b.refresher.tro
+	b.refresher.mu.Unlock()
+	challenge.Store(true)
+
+	// the challenge must not get the refreshed token, which has no claims
+	res := send()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "claims", res.Header.Get("Token"))
+	require.EqualValues(t, 2, calls.Load())
+
+	// the refreshed token remains available to a request without claims
+	tk, ok := b.refresher.takePending(b.refresher.tro)
+	require.True(t, ok)
+	require.Equal(t, "refreshed", tk.Token)
+}
+
+func TestBearerTokenPolicy_BackgroundRefreshFailures(t *testing.T) {
+	mu := sync.Mutex{}
+	logged := []string{}
+	log.SetListener(func(e log.Event, msg string) {
+		if e == log.EventAuthentication {
+			mu.Lock()
+			defer mu.Unlock()
+			logged = append(logged, msg)
+		}
+	})
+	t.Cleanup(func() { log.SetListener(nil) })
+
+	calls := atomic.Int32{}
+	cred := mockCredential{
+		getTokenImpl: func(ctx context.Context, tro policy.TokenRequestOptions) (exported.AccessToken, error) {
+			if calls.Add(1) == 1 {
+				return exported.AccessToken{Token: "1", ExpiresOn: time.Now().Add(time.Hour), RefreshOn: time.Now()}, nil
+			}
+			return exported.AccessToken{}, errors.New("refresh failed")
+		},
+	}
+	b, send := newBackgroundRefreshTestPipeline(t, cred)
+	require.Equal(t, "1", send())
+	require.Eventually(t, func() bool {
+		// the refresher retries only while the policy is using the token
+		require.Equal(t, "1", send())
+		mu.Lock()
+		defer mu.Unlock()
+		return len(logged) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	require.Contains(t, logged[0], fmt.Sprintf("%d consecutive", backgroundRefreshFailureThreshold))
+	require.Contains(t, logged[0], "refresh failed")
+	mu.Unlock()
+	require.GreaterOrEqual(t, calls.Load(), int32(backgroundRefreshFailureThreshold+1))
+
+	// the refresher should stop retrying when the policy stops using the token
+	require.Eventually(t, func() bool {
+		b.refresher.mu.Lock()
+		defer b.refresher.mu.Unlock()
+		return b.refresher.timer == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
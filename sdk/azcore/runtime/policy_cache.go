@@ -0,0 +1,403 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	headerCacheControl = "Cache-Control"
+	headerETag         = "ETag"
+	headerIfMatch      = "If-Match"
+	headerIfNoneMatch  = "If-None-Match"
+	headerVary         = "Vary"
+
+	defaultCacheMaxBodySize = 1024 * 1024
+
+	// maxCachedVariants is the maximum number of responses cached for a URL
+	maxCachedVariants = 8
+)
+
+// CachedResponse is an HTTP response stored in a [ResponseCache].
+// Values must not be modified once they've been added to a cache.
+type CachedResponse struct {
+	// StatusCode is the status code of the response.
+	StatusCode int
+
+	// Header contains the response headers.
+	Header http.Header
+
+	// Body contains the response body.
+	Body []byte
+
+	// ETag is the value of the ETag response header, it's empty when the response had none.
+	ETag string
+
+	// Expires is the time until which the response can be served without revalidating it
+	// with the service. It's the zero value when the response must always be revalidated.
+	Expires time.Time
+
+	// RequestHeader contains the values of the request headers named by the response's Vary
+	// header. The response is served only to requests having the same values.
+	RequestHeader http.Header
+}
+
+// ResponseCache stores the responses cached by a [CachePolicy]. Each key has one or more responses,
+// more than one when the responses have a Vary header and were received for requests with different
+// values of the headers it names. Slices must not be modified once they've been added to a cache.
+// Implementations must be safe for concurrent use.
+type ResponseCache interface {
+	// Get returns the responses for the specified key, or false if there are none.
+	Get(key string) ([]*CachedResponse, bool)
+
+	// Set adds or replaces the responses for the specified key.
+	Set(key string, resps []*CachedResponse)
+
+	// Delete removes the responses for the specified key, if any.
+	Delete(key string)
+}
+
+// CachePolicyOptions contains the optional values for [NewCachePolicy].
+type CachePolicyOptions struct {
+	// Cache stores the cached responses.
+	// The default value is an [LRUResponseCache] with a capacity of 1000 URLs.
+	Cache ResponseCache
+
+	// MaxBodySize is the maximum size in bytes of the body of a cached response.
+	// Responses with larger bodies aren't cached. The default value is 1 MiB.
+	MaxBodySize int64
+}
+
+// CachePolicy caches the responses of GET requests and revalidates them using their ETag. When the
+// service responds to a revalidation with status code 304 (Not Modified), the cached response is
+// returned. A response is cached when its status code is 200, its Cache-Control header doesn't
+// contain no-store, it has either an ETag or a positive max-age, and its body isn't larger than
+// MaxBodySize. Responses with a positive max-age are served from the cache, without contacting the
+// service, until they expire. A response having a Vary header is served only to requests with the
+// same values for the headers it names; up to 8 such responses, for different values, are cached for
+// a URL. Responses with "Vary: *" aren't cached.
+//
+// Requests that contain If-Match or If-None-Match headers, or a Cache-Control header with no-cache
+// or no-store, bypass the cache, as do requests whose response body isn't downloaded, see
+// [SkipBodyDownload]. A successful request with any other method invalidates the cached
+// responses for its URL.
+//
+// Add the policy to [policy.ClientOptions.PerCallPolicies]. Responses are cached by URL, so don't
+// share a [ResponseCache] between clients with different credentials.
+// Don't use this type directly, use [NewCachePolicy] instead.
+type CachePolicy struct {
+	cache       ResponseCache
+	maxBodySize int64
+	now         func() time.Time
+}
+
+// NewCachePolicy creates a new instance of [CachePolicy].
+//   - options contains optional configuration, pass nil to accept the default values
+func NewCachePolicy(options *CachePolicyOptions) *CachePolicy {
+	if options == nil {
+		options = &CachePolicyOptions{}
+	}
+	cache := options.Cache
+	if cache == nil {
+		cache = NewLRUResponseCache(1000)
+	}
+	maxBodySize := options.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultCacheMaxBodySize
+	}
+	return &CachePolicy{
+		cache:       cache,
+		maxBodySize: maxBodySize,
+		now:         time.Now,
+	}
+}
+
+// Do implements the [policy.Policy] interface for [CachePolicy].
+func (c *CachePolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	key := raw.URL.String()
+	if raw.Method != http.MethodGet {
+		resp, err := req.Next()
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			c.cache.Delete(key)
+		}
+		return resp, err
+	}
+	if raw.Header.Get(headerIfMatch) != "" || raw.Header.Get(headerIfNoneMatch) != "" {
+		// the caller is making its own conditional request
+		return req.Next()
+	}
+	if reqCC := parseCacheControl(raw.Header.Get(headerCacheControl)); reqCC.noCache || reqCC.noStore {
+		return req.Next()
+	}
+	var opValues bodyDownloadPolicyOpValues
+	if req.OperationValue(&opValues); opValues.Skip {
+		// the caller streams the response body, e.g. a blob download
+		return req.Next()
+	}
+
+	// select the response for a request with the same values of the headers named by Vary
+	variants, _ := c.cache.Get(key)
+	var cached *CachedResponse
+	if i := slices.IndexFunc(variants, func(v *CachedResponse) bool { return v.matches(raw.Header) }); i >= 0 {
+		cached = variants[i]
+	}
+	ok := cached != nil
+	if ok && !cached.Expires.IsZero() && c.now().Before(cached.Expires) {
+		log.Writef(log.EventRequest, "Serving cached response for %s", getSanitizedURL(*raw.URL, getAllowedQueryParams(nil)))
+		return cached.response(raw, http.StatusOK), nil
+	}
+	if ok && cached.ETag != "" {
+		raw.Header.Set(headerIfNoneMatch, cached.ETag)
+		defer raw.Header.Del(headerIfNoneMatch)
+	}
+
+	resp, err := req.Next()
+	if err != nil {
+		return resp, err
+	}
+
+	if ok && resp.StatusCode == http.StatusNotModified {
+		Drain(resp)
+		// the 304 can update the headers of the cached response, e.g. Cache-Control
+		header := cached.Header.Clone()
+		for k, v := range resp.Header {
+			switch k {
+			case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+				// these describe the 304's empty body
+			default:
+				header[k] = v
+			}
+		}
+		updated := c.newCachedResponse(raw.Header, cached.StatusCode, header)
+		if updated == nil {
+			c.setVariant(key, variants, raw.Header, nil)
+			updated = cached
+		} else {
+			updated.Body = cached.Body
+			c.setVariant(key, variants, raw.Header, updated)
+		}
+		log.Writef(log.EventResponse, "Response for %s not modified, serving cached response", getSanitizedURL(*raw.URL, getAllowedQueryParams(nil)))
+		return updated.response(raw, cached.StatusCode), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	// decide whether the response is cacheable before reading its body
+	entry := c.newCachedResponse(raw.Header, resp.StatusCode, resp.Header.Clone())
+	if entry == nil || resp.ContentLength > c.maxBodySize {
+		c.setVariant(key, variants, raw.Header, nil)
+		return resp, nil
+	}
+	body, err := Payload(resp)
+	if err != nil {
+		return resp, err
+	}
+	if int64(len(body)) > c.maxBodySize {
+		c.setVariant(key, variants, raw.Header, nil)
+		return resp, nil
+	}
+	entry.Body = body
+	c.setVariant(key, variants, raw.Header, entry)
+	return resp, nil
+}
+
+// setVariant stores entry as the most recent response for key, replacing the responses in variants
+// that match reqHeader and dropping the oldest ones over maxCachedVariants. A nil entry only removes
+// the responses matching reqHeader.
+func (c *CachePolicy) setVariant(key string, variants []*CachedResponse, reqHeader http.Header, entry *CachedResponse) {
+	updated := make([]*CachedResponse, 0, len(variants)+1)
+	if entry != nil {
+		updated = append(updated, entry)
+	}
+	for _, v := range variants {
+		if len(updated) < maxCachedVariants && !v.matches(reqHeader) {
+			updated = append(updated, v)
+		}
+	}
+	if len(updated) == 0 {
+		c.cache.Delete(key)
+		return
+	}
+	c.cache.Set(key, updated)
+}
+
+// newCachedResponse returns a *CachedResponse without a body if the response can be cached, else nil.
+// reqHeader contains the headers of the request for which the response was received.
+func (c *CachePolicy) newCachedResponse(reqHeader http.Header, statusCode int, header http.Header) *CachedResponse {
+	cc := parseCacheControl(header.Get(headerCacheControl))
+	if cc.noStore {
+		return nil
+	}
+	entry := &CachedResponse{
+		StatusCode: statusCode,
+		Header:     header,
+		ETag:       header.Get(headerETag),
+	}
+	for _, vary := range header.Values(headerVary) {
+		for _, name := range strings.Split(vary, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if name == "*" {
+				// the response varies on more than request headers
+				return nil
+			}
+			if entry.RequestHeader == nil {
+				entry.RequestHeader = http.Header{}
+			}
+			entry.RequestHeader[name] = reqHeader.Values(name)
+		}
+	}
+	if cc.maxAge > 0 && !cc.noCache {
+		entry.Expires = c.now().Add(cc.maxAge)
+	}
+	if entry.ETag == "" && entry.Expires.IsZero() {
+		// there's no way to know if the response is still valid
+		return nil
+	}
+	return entry
+}
+
+// matches returns true when the request header has the values of the headers named by
+// the response's Vary header that the response was received for
+func (c *CachedResponse) matches(reqHeader http.Header) bool {
+	for name, values := range c.RequestHeader {
+		if !slices.Equal(values, reqHeader.Values(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// response creates an *http.Response from the cached response
+func (c *CachedResponse) response(req *http.Request, statusCode int) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
+
+// cacheControl contains the Cache-Control directives used by the CachePolicy
+type cacheControl struct {
+	noCache bool
+	noStore bool
+	maxAge  time.Duration
+}
+
+func parseCacheControl(value string) cacheControl {
+	cc := cacheControl{}
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache":
+			cc.noCache = true
+		case "no-store":
+			cc.noStore = true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil && seconds > 0 {
+				cc.maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return cc
+}
+
+// LRUResponseCache is a [ResponseCache] that keeps the responses for up to a fixed number of keys in
+// memory, evicting the responses for the least recently used key when it's full.
+// Don't use this type directly, use [NewLRUResponseCache] instead.
+type LRUResponseCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is the value of the elements in LRUResponseCache.order
+type lruEntry struct {
+	key   string
+	resps []*CachedResponse
+}
+
+// NewLRUResponseCache creates a new instance of [LRUResponseCache].
+//   - capacity is the maximum number of keys in the cache, values less than one are treated as one
+func NewLRUResponseCache(capacity int) *LRUResponseCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRUResponseCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+// Get implements the [ResponseCache] interface for [LRUResponseCache].
+func (l *LRUResponseCache) Get(key string) ([]*CachedResponse, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(e)
+	return e.Value.(*lruEntry).resps, true
+}
+
+// Set implements the [ResponseCache] interface for [LRUResponseCache].
+func (l *LRUResponseCache) Set(key string, resps []*CachedResponse) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[key]; ok {
+		e.Value.(*lruEntry).resps = resps
+		l.order.MoveToFront(e)
+		return
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, resps: resps})
+	if l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Delete implements the [ResponseCache] interface for [LRUResponseCache].
+func (l *LRUResponseCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[key]; ok {
+		l.order.Remove(e)
+		delete(l.entries, key)
+	}
+}
+
+// Len returns the number of keys in the cache.
+func (l *LRUResponseCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
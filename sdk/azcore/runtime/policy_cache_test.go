@@ -0,0 +1,276 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func sendCacheRequest(t *testing.T, pl exported.Pipeline, method, url string, header http.Header) (*http.Response, string) {
+	req, err := NewRequest(context.Background(), method, url)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Raw().Header[k] = v
+	}
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	body, err := Payload(resp)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestCachePolicyETag(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerETag, `"v1"`), mock.WithBody([]byte(`{"value": 1}`)))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusNotModified), mock.WithHeader(headerETag, `"v1"`))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerETag, `"v2"`), mock.WithBody([]byte(`{"value": 2}`)))
+
+	var ifNoneMatch []string
+	capture := exported.PolicyFunc(func(req *policy.Request) (*http.Response, error) {
+		ifNoneMatch = append(ifNoneMatch, req.Raw().Header.Get(headerIfNoneMatch))
+		return req.Next()
+	})
+	cache := NewLRUResponseCache(10)
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies:  []policy.Policy{NewCachePolicy(&CachePolicyOptions{Cache: cache})},
+		PerRetryPolicies: []policy.Policy{capture},
+		Transport:        srv,
+	})
+
+	resp, body := sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"value": 1}`, body)
+	require.Equal(t, 1, cache.Len())
+
+	// revalidated, the cached body is returned
+	resp, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"value": 1}`, body)
+	require.Equal(t, `"v1"`, resp.Header.Get(headerETag))
+	require.Equal(t, "12", resp.Header.Get("Content-Length"))
+
+	// modified, the new response is returned and cached
+	resp, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"value": 2}`, body)
+	cached, ok := cache.Get(srv.URL())
+	require.True(t, ok)
+	require.Len(t, cached, 1)
+	require.Equal(t, `"v2"`, cached[0].ETag)
+
+	require.Equal(t, []string{"", `"v1"`, `"v1"`}, ifNoneMatch)
+	require.EqualValues(t, 3, srv.Requests())
+}
+
+func TestCachePolicyMaxAge(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerCacheControl, "private, max-age=60"), mock.WithBody([]byte("first")))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte("second")))
+
+	cp := NewCachePolicy(nil)
+	now := time.Now()
+	cp.now = func() time.Time { return now }
+	pl := newTestPipeline(&policy.ClientOptions{PerCallPolicies: []policy.Policy{cp}, Transport: srv})
+
+	_, body := sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.Equal(t, "first", body)
+	// served from the cache without sending a request
+	_, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.Equal(t, "first", body)
+	require.EqualValues(t, 1, srv.Requests())
+
+	// expired, the response has no ETag so it isn't cached
+	now = now.Add(time.Minute)
+	_, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.Equal(t, "second", body)
+	_, ok := cp.cache.Get(srv.URL())
+	require.False(t, ok)
+}
+
+func TestCachePolicyBypass(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerETag, `"v1"`), mock.WithHeader(headerCacheControl, "max-age=60"), mock.WithBody([]byte("body")))
+
+	cache := NewLRUResponseCache(10)
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies: []policy.Policy{NewCachePolicy(&CachePolicyOptions{Cache: cache})},
+		Transport:       srv,
+	})
+	sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.EqualValues(t, 1, srv.Requests())
+
+	for _, header := range []http.Header{
+		{headerCacheControl: []string{"no-cache"}},
+		{headerIfNoneMatch: []string{`"v0"`}},
+		{headerIfMatch: []string{`"v1"`}},
+	} {
+		sendCacheRequest(t, pl, http.MethodGet, srv.URL(), header)
+	}
+	require.EqualValues(t, 4, srv.Requests())
+
+	// other methods invalidate the cached response
+	sendCacheRequest(t, pl, http.MethodPut, srv.URL(), nil)
+	require.Zero(t, cache.Len())
+}
+
+func TestCachePolicyNoStore(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerETag, `"v1"`), mock.WithHeader(headerCacheControl, "no-store"))
+
+	cache := NewLRUResponseCache(10)
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies: []policy.Policy{NewCachePolicy(&CachePolicyOptions{Cache: cache})},
+		Transport:       srv,
+	})
+	sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.Zero(t, cache.Len())
+}
+
+func TestCachePolicySkipBodyDownload(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerETag, `"v1"`), mock.WithBody([]byte("blob")))
+
+	cache := NewLRUResponseCache(10)
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies: []policy.Policy{NewCachePolicy(&CachePolicyOptions{Cache: cache})},
+		Transport:       srv,
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	SkipBodyDownload(req)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Zero(t, cache.Len())
+}
+
+func TestCachePolicyMaxBodySize(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerETag, `"v1"`), mock.WithBody([]byte("0123456789")))
+
+	cache := NewLRUResponseCache(10)
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies: []policy.Policy{NewCachePolicy(&CachePolicyOptions{Cache: cache, MaxBodySize: 5})},
+		Transport:       srv,
+	})
+	_, body := sendCacheRequest(t, pl, http.MethodGet, srv.URL(), nil)
+	require.Equal(t, "0123456789", body)
+	require.Zero(t, cache.Len())
+
+	require.EqualValues(t, defaultCacheMaxBodySize, NewCachePolicy(nil).maxBodySize)
+}
+
+func TestCachePolicyVary(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerCacheControl, "max-age=60"), mock.WithHeader(headerVary, "Accept, x-ms-version"), mock.WithBody([]byte("json")))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerCacheControl, "max-age=60"), mock.WithHeader(headerVary, "Accept, x-ms-version"), mock.WithBody([]byte("xml")))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerCacheControl, "max-age=60"), mock.WithHeader(headerVary, "*"), mock.WithBody([]byte("any")))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+
+	cache := NewLRUResponseCache(10)
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies: []policy.Policy{NewCachePolicy(&CachePolicyOptions{Cache: cache})},
+		Transport:       srv,
+	})
+	jsonHeader := http.Header{"Accept": {"application/json"}, "X-Ms-Version": {"2025-01-05"}}
+	_, body := sendCacheRequest(t, pl, http.MethodGet, srv.URL(), jsonHeader)
+	require.Equal(t, "json", body)
+	_, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL(), jsonHeader)
+	require.Equal(t, "json", body)
+	require.EqualValues(t, 1, srv.Requests())
+
+	// a different Accept header doesn't match the cached response
+	xmlHeader := http.Header{"Accept": {"application/xml"}, "X-Ms-Version": {"2025-01-05"}}
+	_, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL(), xmlHeader)
+	require.Equal(t, "xml", body)
+	require.EqualValues(t, 2, srv.Requests())
+
+	// both variants are cached
+	for i := 0; i < 2; i++ {
+		_, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL(), jsonHeader)
+		require.Equal(t, "json", body)
+		_, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL(), xmlHeader)
+		require.Equal(t, "xml", body)
+	}
+	require.EqualValues(t, 2, srv.Requests())
+	variants, ok := cache.Get(srv.URL())
+	require.True(t, ok)
+	require.Len(t, variants, 2)
+
+	// Vary: * isn't cached
+	_, body = sendCacheRequest(t, pl, http.MethodGet, srv.URL()+"/other", nil)
+	require.Equal(t, "any", body)
+	_, ok = cache.Get(srv.URL() + "/other")
+	require.False(t, ok)
+
+	// other methods invalidate all the variants
+	sendCacheRequest(t, pl, http.MethodPut, srv.URL(), nil)
+	_, ok = cache.Get(srv.URL())
+	require.False(t, ok)
+}
+
+func TestCachePolicyMaxVariants(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(headerCacheControl, "max-age=60"), mock.WithHeader(headerVary, "Accept-Language"))
+
+	cache := NewLRUResponseCache(10)
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies: []policy.Policy{NewCachePolicy(&CachePolicyOptions{Cache: cache})},
+		Transport:       srv,
+	})
+	for i := 0; i <= maxCachedVariants; i++ {
+		sendCacheRequest(t, pl, http.MethodGet, srv.URL(), http.Header{"Accept-Language": {strconv.Itoa(i)}})
+	}
+	variants, ok := cache.Get(srv.URL())
+	require.True(t, ok)
+	require.Len(t, variants, maxCachedVariants)
+	// the oldest variant was dropped
+	require.Equal(t, []string{strconv.Itoa(maxCachedVariants)}, variants[0].RequestHeader.Values("Accept-Language"))
+	require.Equal(t, []string{"1"}, variants[maxCachedVariants-1].RequestHeader.Values("Accept-Language"))
+}
+
+func TestLRUResponseCache(t *testing.T) {
+	cache := NewLRUResponseCache(2)
+	cache.Set("a", []*CachedResponse{{ETag: "a"}})
+	cache.Set("b", []*CachedResponse{{ETag: "b"}})
+	// a becomes the most recently used
+	_, ok := cache.Get("a")
+	require.True(t, ok)
+	cache.Set("c", []*CachedResponse{{ETag: "c"}})
+	require.Equal(t, 2, cache.Len())
+	_, ok = cache.Get("b")
+	require.False(t, ok)
+
+	cache.Set("a", []*CachedResponse{{ETag: "a2"}, {ETag: "a3"}})
+	resps, ok := cache.Get("a")
+	require.True(t, ok)
+	require.Len(t, resps, 2)
+	require.Equal(t, "a2", resps[0].ETag)
+	cache.Delete("a")
+	cache.Delete("missing")
+	require.Equal(t, 1, cache.Len())
+}
+
+func TestParseCacheControl(t *testing.T) {
+	require.Equal(t, cacheControl{}, parseCacheControl(""))
+	require.Equal(t, cacheControl{maxAge: 30 * time.Second}, parseCacheControl("public, max-age=30"))
+	require.Equal(t, cacheControl{noCache: true, noStore: true}, parseCacheControl("No-Cache,no-store, max-age=bad"))
+}
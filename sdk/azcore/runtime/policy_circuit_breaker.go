@@ -0,0 +1,302 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	defaultCircuitFailureThreshold = 5
+	defaultCircuitOpenDuration     = 30 * time.Second
+	defaultCircuitHalfOpenRequests = 1
+
+	// circuitIdleTimeout is how long a closed circuit goes without requests before it's evicted
+	circuitIdleTimeout = 10 * time.Minute
+)
+
+// CircuitBreakerPolicyOptions contains the optional values configuring [CircuitBreakerPolicy].
+// Zero-value fields will have their specified default values applied during use.
+type CircuitBreakerPolicyOptions struct {
+	// FailureThreshold is the number of consecutive failures to a host that opens its circuit.
+	// The default value is five.
+	FailureThreshold int
+
+	// OpenDuration is the amount of time a circuit stays open before allowing probe requests.
+	// The default value is 30 seconds.
+	OpenDuration time.Duration
+
+	// HalfOpenMaxRequests is the maximum number of concurrent probe requests allowed
+	// to a host while its circuit is half-open. The default value is one.
+	HalfOpenMaxRequests int
+
+	// IsFailure evaluates if the outcome of a request counts as a failure.
+	// When nil, errors and the following HTTP status codes are considered failures.
+	//   http.StatusRequestTimeout      408
+	//   http.StatusTooManyRequests     429
+	//   http.StatusInternalServerError 500
+	//   http.StatusBadGateway          502
+	//   http.StatusServiceUnavailable  503
+	//   http.StatusGatewayTimeout      504
+	// Cancellation of the request's context is never considered a failure.
+	// The *http.Response and error parameters are mutually exclusive, i.e.
+	// if one is nil, the other is not nil.
+	IsFailure func(*http.Response, error) bool
+}
+
+// CircuitBreakerPolicy fails requests fast when the host they target has been failing.
+// Failures are tracked per host. After FailureThreshold consecutive failures, the host's
+// circuit opens and requests to it fail with a [*CircuitOpenError] without being sent.
+// Once OpenDuration has elapsed, the circuit becomes half-open and up to HalfOpenMaxRequests
+// probe requests are sent. A successful probe closes the circuit, a failed one reopens it.
+//
+// Add the policy to [policy.ClientOptions.PerRetryPolicies] so that each try is evaluated.
+// A single instance can be shared across clients to share the health of common hosts.
+// Closed circuits are discarded after ten minutes without requests, so the policy doesn't
+// accumulate state for hosts it no longer sends requests to.
+type CircuitBreakerPolicy struct {
+	// the following fields are read-only
+	threshold int
+	openFor   time.Duration
+	maxProbes int
+	isFailure func(*http.Response, error) bool
+	now       func() time.Time
+
+	circuitsMu sync.Mutex
+	circuits   map[string]*circuit
+	// lastEviction is when idle circuits were last evicted, guarded by circuitsMu
+	lastEviction time.Time
+}
+
+// NewCircuitBreakerPolicy creates a new instance of [CircuitBreakerPolicy].
+//   - options contains optional configuration, pass nil to accept the default values
+func NewCircuitBreakerPolicy(options *CircuitBreakerPolicyOptions) *CircuitBreakerPolicy {
+	if options == nil {
+		options = &CircuitBreakerPolicyOptions{}
+	}
+	cb := &CircuitBreakerPolicy{
+		threshold: options.FailureThreshold,
+		openFor:   options.OpenDuration,
+		maxProbes: options.HalfOpenMaxRequests,
+		isFailure: options.IsFailure,
+		now:       time.Now,
+		circuits:  map[string]*circuit{},
+	}
+	cb.lastEviction = cb.now()
+	if cb.threshold <= 0 {
+		cb.threshold = defaultCircuitFailureThreshold
+	}
+	if cb.openFor <= 0 {
+		cb.openFor = defaultCircuitOpenDuration
+	}
+	if cb.maxProbes <= 0 {
+		cb.maxProbes = defaultCircuitHalfOpenRequests
+	}
+	if cb.isFailure == nil {
+		cb.isFailure = func(resp *http.Response, err error) bool {
+			if err != nil {
+				return true
+			}
+			return HasStatusCode(resp,
+				http.StatusRequestTimeout,
+				http.StatusTooManyRequests,
+				http.StatusInternalServerError,
+				http.StatusBadGateway,
+				http.StatusServiceUnavailable,
+				http.StatusGatewayTimeout,
+			)
+		}
+	}
+	return cb
+}
+
+// Do implements the Do method on the [policy.Policy] interface.
+func (cb *CircuitBreakerPolicy) Do(req *policy.Request) (*http.Response, error) {
+	host := strings.ToLower(req.Raw().URL.Host)
+	c := cb.circuitFor(host)
+
+	probe, err := c.allow(cb, host)
+	if err != nil {
+		log.Writef(log.EventRetryPolicy, "circuit for %s is open, failing request", host)
+		return nil, err
+	}
+
+	resp, err := req.Next()
+
+	if err != nil && errors.Is(req.Raw().Context().Err(), context.Canceled) {
+		// the caller gave up on the request, this says nothing about the health of the host.
+		// note that an expired deadline (e.g. RetryOptions.TryTimeout) is considered a failure.
+		c.release(probe)
+		return resp, err
+	}
+
+	if cb.isFailure(resp, err) {
+		if c.failure(cb, probe) {
+			log.Writef(log.EventRetryPolicy, "circuit for %s opened for %s", host, cb.openFor)
+		}
+	} else if c.success(probe) {
+		log.Writef(log.EventRetryPolicy, "circuit for %s closed", host)
+	}
+	return resp, err
+}
+
+func (cb *CircuitBreakerPolicy) circuitFor(host string) *circuit {
+	cb.circuitsMu.Lock()
+	defer cb.circuitsMu.Unlock()
+	if now := cb.now(); now.Sub(cb.lastEviction) >= circuitIdleTimeout {
+		cb.evictIdle(now)
+		cb.lastEviction = now
+	}
+	c, ok := cb.circuits[host]
+	if !ok {
+		c = &circuit{}
+		cb.circuits[host] = c
+	}
+	return c
+}
+
+// evictIdle removes closed circuits having no requests since circuitIdleTimeout before now.
+// Open and half-open circuits are kept so that failing hosts continue failing fast.
+// evictIdle must be called with circuitsMu held.
+func (cb *CircuitBreakerPolicy) evictIdle(now time.Time) {
+	for host, c := range cb.circuits {
+		c.mu.Lock()
+		idle := c.state == circuitClosed && c.active == 0 && now.Sub(c.lastUsed) >= circuitIdleTimeout
+		c.mu.Unlock()
+		if idle {
+			delete(cb.circuits, host)
+		}
+	}
+}
+
+// CircuitOpenError is returned by [CircuitBreakerPolicy] when a request
+// isn't sent because the circuit for its host is open.
+// Use errors.As() to access this type in the error chain.
+type CircuitOpenError struct {
+	// Host is the host the request was sent to.
+	Host string
+
+	// RetryAfter is the remaining time until the circuit allows probe requests.
+	// A zero value indicates the maximum number of probe requests are in flight.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface for type CircuitOpenError.
+func (e *CircuitOpenError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("circuit for host %s is open, retry after %s", e.Host, e.RetryAfter)
+	}
+	return fmt.Sprintf("circuit for host %s is half-open and the maximum number of probe requests are in flight", e.Host)
+}
+
+// NonRetriable indicates this error is non-transient so the retry policy won't retry the request.
+func (*CircuitOpenError) NonRetriable() {
+	// marker method
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuit tracks the health of a single host
+type circuit struct {
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probes   int
+	// active is the number of requests in flight
+	active int
+	// lastUsed is when the most recent request was allowed
+	lastUsed time.Time
+}
+
+// allow returns a non-nil error if the request must not be sent.
+// the returned bool indicates the request is a probe for a half-open circuit.
+func (c *circuit) allow(cb *CircuitBreakerPolicy, host string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := cb.now()
+	if c.state == circuitOpen {
+		remaining := c.openedAt.Add(cb.openFor).Sub(now)
+		if remaining > 0 {
+			return false, &CircuitOpenError{Host: host, RetryAfter: remaining}
+		}
+		c.state = circuitHalfOpen
+		c.probes = 0
+	}
+	if c.state == circuitHalfOpen {
+		if c.probes >= cb.maxProbes {
+			return false, &CircuitOpenError{Host: host}
+		}
+		c.probes++
+		c.active++
+		c.lastUsed = now
+		return true, nil
+	}
+	c.active++
+	c.lastUsed = now
+	return false, nil
+}
+
+// failure records a failed request. returns true if the circuit transitioned to open.
+func (c *circuit) failure(cb *CircuitBreakerPolicy, probe bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.releaseProbe(probe)
+	if c.state == circuitOpen {
+		// another request already opened the circuit
+		return false
+	}
+	c.failures++
+	if c.state == circuitHalfOpen || c.failures >= cb.threshold {
+		c.state = circuitOpen
+		c.openedAt = cb.now()
+		return true
+	}
+	return false
+}
+
+// success records a successful request. returns true if the circuit transitioned to closed.
+func (c *circuit) success(probe bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.releaseProbe(probe)
+	c.failures = 0
+	if c.state == circuitHalfOpen {
+		c.state = circuitClosed
+		return true
+	}
+	return false
+}
+
+// release is called when the outcome of a request is indeterminate
+func (c *circuit) release(probe bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.releaseProbe(probe)
+}
+
+// releaseProbe records the end of a request, it must be called with mu held
+func (c *circuit) releaseProbe(probe bool) {
+	c.active--
+	// the count is reset when the circuit becomes half-open so a
+	// probe from a previous half-open period must not decrement it
+	if probe && c.probes > 0 {
+		c.probes--
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/errorinfo"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerPolicyOpens(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	cb := NewCircuitBreakerPolicy(&CircuitBreakerPolicyOptions{FailureThreshold: 2})
+	pl := exported.NewPipeline(srv, cb)
+
+	for range 2 {
+		req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+		require.NoError(t, err)
+		resp, err := pl.Do(req)
+		require.NoError(t, err)
+		require.EqualValues(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.Nil(t, resp)
+	var coErr *CircuitOpenError
+	require.ErrorAs(t, err, &coErr)
+	require.Equal(t, req.Raw().URL.Host, coErr.Host)
+	require.Greater(t, coErr.RetryAfter, time.Duration(0))
+	var nre errorinfo.NonRetriable
+	require.ErrorAs(t, err, &nre)
+	require.EqualValues(t, 2, srv.Requests())
+}
+
+func TestCircuitBreakerPolicySuccessResetsFailures(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusInternalServerError))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusInternalServerError))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	pl := exported.NewPipeline(srv, NewCircuitBreakerPolicy(&CircuitBreakerPolicyOptions{FailureThreshold: 2}))
+
+	for range 4 {
+		req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+		require.NoError(t, err)
+		_, err = pl.Do(req)
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 4, srv.Requests())
+}
+
+func TestCircuitBreakerPolicyHalfOpen(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	cb := NewCircuitBreakerPolicy(&CircuitBreakerPolicyOptions{FailureThreshold: 1, OpenDuration: time.Minute})
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+	pl := exported.NewPipeline(srv, cb)
+
+	send := func() (*http.Response, error) {
+		req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+		require.NoError(t, err)
+		return pl.Do(req)
+	}
+
+	srv.AppendError(errors.New("connection reset"))
+	_, err := send()
+	require.Error(t, err)
+	_, err = send()
+	var coErr *CircuitOpenError
+	require.ErrorAs(t, err, &coErr)
+
+	// the failed probe reopens the circuit
+	now = now.Add(time.Minute)
+	srv.AppendResponse(mock.WithStatusCode(http.StatusBadGateway))
+	resp, err := send()
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusBadGateway, resp.StatusCode)
+	_, err = send()
+	require.ErrorAs(t, err, &coErr)
+
+	// the successful probe closes the circuit
+	now = now.Add(time.Minute)
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	for range 3 {
+		resp, err = send()
+		require.NoError(t, err)
+		require.EqualValues(t, http.StatusOK, resp.StatusCode)
+	}
+	require.EqualValues(t, 5, srv.Requests())
+}
+
+func TestCircuitBreakerPolicyHalfOpenMaxRequests(t *testing.T) {
+	cb := NewCircuitBreakerPolicy(&CircuitBreakerPolicyOptions{FailureThreshold: 1})
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+	c := cb.circuitFor("contoso.com")
+	require.True(t, c.failure(cb, false))
+	now = now.Add(defaultCircuitOpenDuration)
+
+	probe, err := c.allow(cb, "contoso.com")
+	require.NoError(t, err)
+	require.True(t, probe)
+	_, err = c.allow(cb, "contoso.com")
+	var coErr *CircuitOpenError
+	require.ErrorAs(t, err, &coErr)
+	require.Zero(t, coErr.RetryAfter)
+
+	// an indeterminate outcome frees up the probe
+	c.release(probe)
+	probe, err = c.allow(cb, "contoso.com")
+	require.NoError(t, err)
+	require.True(t, probe)
+}
+
+func TestCircuitBreakerPolicyEvictsIdleCircuits(t *testing.T) {
+	cb := NewCircuitBreakerPolicy(&CircuitBreakerPolicyOptions{FailureThreshold: 1})
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+	failing := shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "failing.contoso.com" {
+			return nil, errors.New("no such host")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	pl := exported.NewPipeline(failing, cb)
+	for _, host := range []string{"failing.contoso.com", "healthy.contoso.com", "busy.contoso.com"} {
+		req, err := NewRequest(context.Background(), http.MethodGet, "https://"+host)
+		require.NoError(t, err)
+		_, _ = pl.Do(req)
+	}
+	require.Len(t, cb.circuits, 3)
+
+	// a request in flight keeps its circuit
+	busy := cb.circuitFor("busy.contoso.com")
+	_, err := busy.allow(cb, "busy.contoso.com")
+	require.NoError(t, err)
+
+	now = now.Add(circuitIdleTimeout)
+	cb.circuitFor("new.contoso.com")
+	require.Len(t, cb.circuits, 3)
+	require.Contains(t, cb.circuits, "failing.contoso.com", "open circuits shouldn't be evicted")
+	require.Contains(t, cb.circuits, "busy.contoso.com")
+	require.Contains(t, cb.circuits, "new.contoso.com")
+	require.NotContains(t, cb.circuits, "healthy.contoso.com")
+}
+
+func TestCircuitBreakerPolicyPerHost(t *testing.T) {
+	cb := NewCircuitBreakerPolicy(&CircuitBreakerPolicyOptions{FailureThreshold: 1})
+	pl := exported.NewPipeline(shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "failing.contoso.com" {
+			return nil, errors.New("no such host")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	}), cb)
+
+	for _, host := range []string{"failing.contoso.com", "FAILING.contoso.com"} {
+		req, err := NewRequest(context.Background(), http.MethodGet, "https://"+host)
+		require.NoError(t, err)
+		_, err = pl.Do(req)
+		require.Error(t, err)
+	}
+	var coErr *CircuitOpenError
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://failing.contoso.com")
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.ErrorAs(t, err, &coErr)
+
+	req, err = NewRequest(context.Background(), http.MethodGet, "https://healthy.contoso.com")
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCircuitBreakerPolicyCanceledIsNotFailure(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithSlowResponse(time.Second))
+	cb := NewCircuitBreakerPolicy(&CircuitBreakerPolicyOptions{FailureThreshold: 1})
+	pl := exported.NewPipeline(srv, cb)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := NewRequest(ctx, http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	cancel()
+	_, err = pl.Do(req)
+	require.ErrorIs(t, err, context.Canceled)
+
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	req, err = NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCircuitBreakerPolicyWithRetryPolicy(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	pl := newTestPipeline(&policy.ClientOptions{
+		Transport:        srv,
+		Retry:            *testRetryOptions(),
+		PerRetryPolicies: []policy.Policy{NewCircuitBreakerPolicy(&CircuitBreakerPolicyOptions{FailureThreshold: 2})},
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	var coErr *CircuitOpenError
+	require.ErrorAs(t, err, &coErr)
+	// the retry policy stops once the circuit opens
+	require.EqualValues(t, 2, srv.Requests())
+}
@@ -0,0 +1,198 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	internalexported "github.com/Azure/azure-sdk-for-go/sdk/internal/exported"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+
+	defaultMinCompressedRequestBodySize = 1024
+)
+
+// ContentEncoding is a content coding used to compress HTTP bodies.
+type ContentEncoding string
+
+const (
+	// ContentEncodingGzip is the gzip content coding.
+	ContentEncodingGzip ContentEncoding = "gzip"
+
+	// ContentEncodingDeflate is the deflate content coding, i.e. the zlib format.
+	ContentEncodingDeflate ContentEncoding = "deflate"
+)
+
+// CompressionPolicyOptions contains the optional values configuring [CompressionPolicy].
+// Zero-value fields will have their specified default values applied during use.
+type CompressionPolicyOptions struct {
+	// RequestEncoding is the content coding used to compress request bodies. Only set it
+	// for services that accept request bodies with a Content-Encoding header.
+	// The default value is empty, which doesn't compress request bodies.
+	RequestEncoding ContentEncoding
+
+	// MinRequestBodySize is the size, in bytes, below which request bodies aren't compressed.
+	// The default value is 1024.
+	MinRequestBodySize int64
+}
+
+// CompressionPolicy compresses request bodies and decompresses response bodies.
+//
+// Responses are always decoded by the policy, also when the [policy.Transporter] disabled
+// the automatic decompression of the [http.Transport]. The policy sets the Accept-Encoding
+// header to advertise the gzip and deflate content codings, and decodes response bodies with
+// either of them. Requests that already contain an Accept-Encoding header are left unchanged,
+// as are their responses.
+//
+// Add the policy to [policy.ClientOptions.PerCallPolicies].
+// Don't use this type directly, use [NewCompressionPolicy] instead.
+type CompressionPolicy struct {
+	requestEncoding ContentEncoding
+	minRequestSize  int64
+}
+
+// NewCompressionPolicy creates a new instance of [CompressionPolicy].
+//   - options contains optional configuration, pass nil to accept the default values
+func NewCompressionPolicy(options *CompressionPolicyOptions) (*CompressionPolicy, error) {
+	if options == nil {
+		options = &CompressionPolicyOptions{}
+	}
+	switch options.RequestEncoding {
+	case "", ContentEncodingGzip, ContentEncodingDeflate:
+	default:
+		return nil, fmt.Errorf("unsupported request encoding %q", options.RequestEncoding)
+	}
+	cp := &CompressionPolicy{
+		requestEncoding: options.RequestEncoding,
+		minRequestSize:  options.MinRequestBodySize,
+	}
+	if cp.minRequestSize <= 0 {
+		cp.minRequestSize = defaultMinCompressedRequestBodySize
+	}
+	return cp, nil
+}
+
+// Do implements the [policy.Policy] interface for [CompressionPolicy].
+func (c *CompressionPolicy) Do(req *policy.Request) (*http.Response, error) {
+	// work on a copy so the caller's request is unchanged
+	req = req.WithContext(req.Raw().Context())
+	raw := req.Raw()
+	raw.Header = raw.Header.Clone()
+	if err := c.compressBody(req); err != nil {
+		return nil, err
+	}
+	decode := raw.Header.Get(headerAcceptEncoding) == ""
+	if decode {
+		raw.Header.Set(headerAcceptEncoding, string(ContentEncodingGzip)+", "+string(ContentEncodingDeflate))
+	}
+
+	resp, err := req.Next()
+	if err != nil || !decode {
+		return resp, err
+	}
+	encoding := ContentEncoding(strings.ToLower(strings.TrimSpace(resp.Header.Get(headerContentEncoding))))
+	if encoding != ContentEncodingGzip && encoding != ContentEncodingDeflate {
+		return resp, nil
+	}
+	downloaded := internalexported.PayloadDownloaded(resp)
+	resp.Body = &decompressingBody{body: resp.Body, encoding: encoding}
+	resp.Header.Del(headerContentEncoding)
+	resp.Header.Del(shared.HeaderContentLength)
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	if downloaded {
+		// keep the decoded body downloaded
+		if _, err := Payload(resp); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// compressBody compresses the request's body when it's large enough and not already encoded
+func (c *CompressionPolicy) compressBody(req *policy.Request) error {
+	raw := req.Raw()
+	body := req.Body()
+	if c.requestEncoding == "" || body == nil || raw.ContentLength < c.minRequestSize || raw.Header.Get(headerContentEncoding) != "" {
+		return nil
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	if c.requestEncoding == ContentEncodingGzip {
+		w = gzip.NewWriter(buf)
+	} else {
+		w = zlib.NewWriter(buf)
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := req.SetBody(streaming.NopCloser(bytes.NewReader(buf.Bytes())), raw.Header.Get(shared.HeaderContentType)); err != nil {
+		return err
+	}
+	raw.Header.Set(headerContentEncoding, string(c.requestEncoding))
+	return nil
+}
+
+// decompressingBody decodes a response body. The decoder is created on the first
+// call to Read so that reading the encoding's header doesn't block the policy.
+type decompressingBody struct {
+	body     io.ReadCloser
+	encoding ContentEncoding
+	r        io.Reader
+	err      error
+}
+
+func (d *decompressingBody) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = newDecompressor(d.encoding, d.body)
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+func (d *decompressingBody) Close() error {
+	return d.body.Close()
+}
+
+func newDecompressor(encoding ContentEncoding, body io.Reader) (io.Reader, error) {
+	if encoding == ContentEncodingGzip {
+		return gzip.NewReader(body)
+	}
+	// some services send raw deflate data instead of the zlib format required by RFC 9110
+	br := bufio.NewReader(body)
+	header, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(header) == 0 {
+		// an empty body, e.g. the response to a HEAD request
+		return br, nil
+	}
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,239 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "deflate":
+		w = zlib.NewWriter(buf)
+	case "raw-deflate":
+		var err error
+		w, err = flate.NewWriter(buf, flate.DefaultCompression)
+		require.NoError(t, err)
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// compressedResponseTransport returns body compressed with encoding and records the requests it receives
+func compressedResponseTransport(t *testing.T, encoding string, body []byte, requests *[]*http.Request) policy.Transporter {
+	return shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req)
+		header := http.Header{}
+		body := body
+		if encoding != "" {
+			header.Set(headerContentEncoding, strings.TrimPrefix(encoding, "raw-"))
+			body = compress(t, encoding, body)
+		}
+		header.Set(shared.HeaderContentLength, strconv.Itoa(len(body)))
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	})
+}
+
+func TestCompressionPolicyDecodesResponses(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", ""} {
+		name := encoding
+		if name == "" {
+			name = "identity"
+		}
+		t.Run(name, func(t *testing.T) {
+			cp, err := NewCompressionPolicy(nil)
+			require.NoError(t, err)
+			var requests []*http.Request
+			pl := newTestPipeline(&policy.ClientOptions{
+				PerCallPolicies: []policy.Policy{cp},
+				Transport:       compressedResponseTransport(t, encoding, []byte(`{"size": 42}`), &requests),
+			})
+			req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com/widgets")
+			require.NoError(t, err)
+			resp, err := pl.Do(req)
+			require.NoError(t, err)
+			require.Len(t, requests, 1)
+			require.Equal(t, "gzip, deflate", requests[0].Header.Get(headerAcceptEncoding))
+			require.Empty(t, req.Raw().Header.Get(headerAcceptEncoding))
+			require.Empty(t, resp.Header.Get(headerContentEncoding))
+
+			var w widget
+			require.NoError(t, UnmarshalAsJSON(resp, &w))
+			require.Equal(t, 42, w.Size)
+		})
+	}
+}
+
+func TestCompressionPolicyStreamingResponse(t *testing.T) {
+	cp, err := NewCompressionPolicy(nil)
+	require.NoError(t, err)
+	var requests []*http.Request
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies: []policy.Policy{cp},
+		Transport:       compressedResponseTransport(t, "gzip", []byte("streamed content"), &requests),
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com/blob")
+	require.NoError(t, err)
+	SkipBodyDownload(req)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "streamed content", string(body))
+	require.NoError(t, resp.Body.Close())
+}
+
+func TestCompressionPolicyCallerAcceptEncoding(t *testing.T) {
+	cp, err := NewCompressionPolicy(nil)
+	require.NoError(t, err)
+	var requests []*http.Request
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerCallPolicies: []policy.Policy{cp},
+		Transport:       compressedResponseTransport(t, "gzip", []byte("content"), &requests),
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com/blob")
+	require.NoError(t, err)
+	req.Raw().Header.Set(headerAcceptEncoding, "gzip")
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	// the caller asked for the encoded body
+	require.Equal(t, "gzip", resp.Header.Get(headerContentEncoding))
+	body, err := Payload(resp)
+	require.NoError(t, err)
+	require.Equal(t, compress(t, "gzip", []byte("content")), body)
+}
+
+func TestCompressionPolicyCompressesRequests(t *testing.T) {
+	large := strings.Repeat("widget", 200)
+	for _, encoding := range []ContentEncoding{ContentEncodingGzip, ContentEncodingDeflate} {
+		t.Run(string(encoding), func(t *testing.T) {
+			cp, err := NewCompressionPolicy(&CompressionPolicyOptions{RequestEncoding: encoding})
+			require.NoError(t, err)
+			srv, close := mock.NewServer()
+			defer close()
+			srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+			srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+			srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+
+			var bodies [][]byte
+			var headers []http.Header
+			pl := newTestPipeline(&policy.ClientOptions{
+				PerCallPolicies: []policy.Policy{cp},
+				PerRetryPolicies: []policy.Policy{exported.PolicyFunc(func(req *policy.Request) (*http.Response, error) {
+					body, err := io.ReadAll(req.Raw().Body)
+					require.NoError(t, err)
+					require.NoError(t, req.RewindBody())
+					bodies = append(bodies, body)
+					headers = append(headers, req.Raw().Header.Clone())
+					return req.Next()
+				})},
+				Retry:     *testRetryOptions(),
+				Transport: srv,
+			})
+
+			req, err := NewRequest(context.Background(), http.MethodPut, srv.URL())
+			require.NoError(t, err)
+			require.NoError(t, req.SetBody(streaming.NopCloser(strings.NewReader(large)), "text/plain"))
+			resp, err := pl.Do(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			// the caller's request is unchanged
+			require.Empty(t, req.Raw().Header.Get(headerContentEncoding))
+			require.EqualValues(t, len(large), req.Raw().ContentLength)
+
+			// the compressed body is sent on every try
+			require.Len(t, bodies, 2)
+			for i, body := range bodies {
+				require.Equal(t, string(encoding), headers[i].Get(headerContentEncoding))
+				require.Equal(t, "text/plain", headers[i].Get(shared.HeaderContentType))
+				require.Equal(t, strconv.Itoa(len(body)), headers[i].Get(shared.HeaderContentLength))
+				require.Less(t, len(body), len(large))
+				r, err := newDecompressor(encoding, bytes.NewReader(body))
+				require.NoError(t, err)
+				decoded, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.Equal(t, large, string(decoded))
+			}
+
+			// small bodies aren't compressed
+			bodies, headers = nil, nil
+			req, err = NewRequest(context.Background(), http.MethodPut, srv.URL())
+			require.NoError(t, err)
+			require.NoError(t, req.SetBody(streaming.NopCloser(strings.NewReader("widget")), "text/plain"))
+			_, err = pl.Do(req)
+			require.NoError(t, err)
+			require.Equal(t, "widget", string(bodies[0]))
+			require.Empty(t, headers[0].Get(headerContentEncoding))
+		})
+	}
+}
+
+func TestCompressionPolicyMaxResponseBodySize(t *testing.T) {
+	// the compressed body is small but decodes to a large body
+	cp, err := NewCompressionPolicy(nil)
+	require.NoError(t, err)
+	var requests []*http.Request
+	pl := newTestPipeline(&policy.ClientOptions{
+		MaxResponseBodySize: 100,
+		PerCallPolicies:     []policy.Policy{cp},
+		Retry:               *testRetryOptions(),
+		Transport:           compressedResponseTransport(t, "gzip", bytes.Repeat([]byte("a"), 101), &requests),
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com/widgets")
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	var tooLarge *ResponseBodyTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	require.EqualValues(t, 100, tooLarge.MaxSize)
+	// the error isn't retried
+	require.Len(t, requests, 1)
+
+	// decoded bodies at the limit are read
+	requests = nil
+	pl = newTestPipeline(&policy.ClientOptions{
+		MaxResponseBodySize: 100,
+		PerCallPolicies:     []policy.Policy{cp},
+		Transport:           compressedResponseTransport(t, "gzip", bytes.Repeat([]byte("a"), 100), &requests),
+	})
+	req, err = NewRequest(context.Background(), http.MethodGet, "https://contoso.com/widgets")
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	body, err := Payload(resp)
+	require.NoError(t, err)
+	require.Len(t, body, 100)
+}
+
+func TestNewCompressionPolicyErrors(t *testing.T) {
+	_, err := NewCompressionPolicy(&CompressionPolicyOptions{RequestEncoding: "br"})
+	require.Error(t, err)
+}
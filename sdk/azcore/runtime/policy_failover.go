@@ -0,0 +1,162 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const defaultFailoverCoolDown = time.Minute
+
+// FailoverPolicyOptions contains the optional values configuring [FailoverPolicy].
+// Zero-value fields will have their specified default values applied during use.
+type FailoverPolicyOptions struct {
+	// CoolDown is the amount of time an endpoint is skipped after a failure. Once it has
+	// elapsed, requests fail back to the endpoint if it's preferred over the current one.
+	// The default value is one minute.
+	CoolDown time.Duration
+
+	// IsFailure evaluates if the outcome of a request counts as a failure of the endpoint.
+	// When nil, errors and HTTP status codes greater than or equal to 500 are considered failures.
+	// Cancellation of the request's context is never considered a failure.
+	// The *http.Response and error parameters are mutually exclusive, i.e.
+	// if one is nil, the other is not nil.
+	IsFailure func(*http.Response, error) bool
+}
+
+// FailoverPolicy sends requests to the first healthy endpoint in an ordered list of equivalent
+// endpoints, such as the primary and secondary hosts of a storage account, or the endpoints of
+// geo-paired regions. The endpoints can come from the cloud.ServiceConfiguration of several
+// cloud.Configuration values. Only requests to one of the endpoints are affected; their scheme
+// and host are rewritten, the path and query are unchanged.
+//
+// When a request to an endpoint fails, the endpoint is marked unhealthy for the CoolDown period
+// and the failure is returned, so the retry policy can retry the request on the next healthy
+// endpoint. When all endpoints are unhealthy, the one whose cool-down ends first is used.
+//
+// Add the policy to [policy.ClientOptions.PerRetryPolicies] so that each try is evaluated.
+// Only use it for operations that are safe to send to any of the endpoints; e.g. the secondary
+// endpoint of a read-access geo-redundant storage account is read-only.
+// Don't use this type directly, use [NewFailoverPolicy] instead.
+type FailoverPolicy struct {
+	// the following fields are read-only
+	endpoints []*url.URL
+	coolDown  time.Duration
+	isFailure func(*http.Response, error) bool
+	now       func() time.Time
+
+	mu sync.Mutex
+	// unhealthyUntil contains the time until which each endpoint is skipped.
+	// the zero value means the endpoint is healthy.
+	unhealthyUntil []time.Time
+}
+
+// NewFailoverPolicy creates a new instance of [FailoverPolicy].
+//   - endpoints contains the absolute URLs of the endpoints in order of preference; only the scheme and host are used
+//   - options contains optional configuration, pass nil to accept the default values
+func NewFailoverPolicy(endpoints []string, options *FailoverPolicyOptions) (*FailoverPolicy, error) {
+	if options == nil {
+		options = &FailoverPolicyOptions{}
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.New("at least one endpoint is required")
+	}
+	fp := &FailoverPolicy{
+		endpoints:      make([]*url.URL, 0, len(endpoints)),
+		coolDown:       options.CoolDown,
+		isFailure:      options.IsFailure,
+		now:            time.Now,
+		unhealthyUntil: make([]time.Time, len(endpoints)),
+	}
+	for _, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("endpoint %q must be an absolute URL", endpoint)
+		}
+		fp.endpoints = append(fp.endpoints, &url.URL{Scheme: strings.ToLower(u.Scheme), Host: strings.ToLower(u.Host)})
+	}
+	if fp.coolDown <= 0 {
+		fp.coolDown = defaultFailoverCoolDown
+	}
+	if fp.isFailure == nil {
+		fp.isFailure = func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= http.StatusInternalServerError
+		}
+	}
+	return fp, nil
+}
+
+// Do implements the [policy.Policy] interface for [FailoverPolicy].
+func (f *FailoverPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	if f.indexOf(raw.URL) < 0 {
+		// the request isn't for one of the endpoints
+		return req.Next()
+	}
+
+	i := f.selectEndpoint()
+	endpoint := f.endpoints[i]
+	if !strings.EqualFold(raw.URL.Scheme, endpoint.Scheme) || !strings.EqualFold(raw.URL.Host, endpoint.Host) {
+		log.Writef(log.EventRetryPolicy, "Failover: sending request for %s to %s", raw.URL.Host, endpoint.Host)
+		raw.URL.Scheme = endpoint.Scheme
+		raw.URL.Host = endpoint.Host
+		raw.Host = ""
+	}
+
+	resp, err := req.Next()
+	if errors.Is(err, context.Canceled) {
+		return resp, err
+	}
+	// don't hold the lock while calling the user's IsFailure
+	var until time.Time
+	if f.isFailure(resp, err) {
+		until = f.now().Add(f.coolDown)
+		log.Writef(log.EventRetryPolicy, "Failover: endpoint %s is unhealthy until %s", endpoint.Host, until.Format(time.RFC3339))
+	}
+	f.mu.Lock()
+	f.unhealthyUntil[i] = until
+	f.mu.Unlock()
+	return resp, err
+}
+
+// indexOf returns the index of the endpoint matching u or -1
+func (f *FailoverPolicy) indexOf(u *url.URL) int {
+	for i, endpoint := range f.endpoints {
+		if strings.EqualFold(u.Scheme, endpoint.Scheme) && strings.EqualFold(u.Host, endpoint.Host) {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectEndpoint returns the index of the first healthy endpoint, or
+// the endpoint whose cool-down ends first when none are healthy
+func (f *FailoverPolicy) selectEndpoint() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	soonest := 0
+	for i, until := range f.unhealthyUntil {
+		if !now.Before(until) {
+			return i
+		}
+		if until.Before(f.unhealthyUntil[soonest]) {
+			soonest = i
+		}
+	}
+	return soonest
+}
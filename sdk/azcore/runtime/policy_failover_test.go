@@ -0,0 +1,171 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+// failoverTransport returns the responses configured per host and records the hosts requests were sent to
+type failoverTransport struct {
+	mu        sync.Mutex
+	hosts     []string
+	responses map[string]func() (*http.Response, error)
+}
+
+func (f *failoverTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts = append(f.hosts, req.URL.Host)
+	resp, err := f.responses[req.URL.Host]()
+	if resp != nil {
+		resp.Request = req
+		resp.Body = http.NoBody
+	}
+	return resp, err
+}
+
+func (f *failoverTransport) sentTo() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hosts := f.hosts
+	f.hosts = nil
+	return hosts
+}
+
+func newFailoverTransport() *failoverTransport {
+	ok := func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusOK}, nil }
+	return &failoverTransport{
+		responses: map[string]func() (*http.Response, error){
+			"primary.contoso.com":   ok,
+			"secondary.contoso.com": ok,
+			"other.contoso.com":     ok,
+		},
+	}
+}
+
+func sendFailoverRequest(t *testing.T, pl Pipeline, url string) int {
+	req, err := NewRequest(context.Background(), http.MethodGet, url)
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestFailoverPolicy(t *testing.T) {
+	fp, err := NewFailoverPolicy([]string{"https://primary.contoso.com", "https://SECONDARY.contoso.com/ignored"}, &FailoverPolicyOptions{CoolDown: time.Minute})
+	require.NoError(t, err)
+	now := time.Now()
+	fp.now = func() time.Time { return now }
+
+	transport := newFailoverTransport()
+	transport.responses["primary.contoso.com"] = func() (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+	}
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerRetryPolicies: []policy.Policy{fp},
+		Retry:            *testRetryOptions(),
+		Transport:        transport,
+	})
+
+	// the primary fails, the request is retried on the secondary
+	require.Equal(t, http.StatusOK, sendFailoverRequest(t, pl, "https://primary.contoso.com/widgets?api-version=1"))
+	require.Equal(t, []string{"primary.contoso.com", "secondary.contoso.com"}, transport.sentTo())
+
+	// the primary is cooling down, requests for either endpoint go to the secondary
+	require.Equal(t, http.StatusOK, sendFailoverRequest(t, pl, "https://primary.contoso.com/widgets"))
+	require.Equal(t, http.StatusOK, sendFailoverRequest(t, pl, "https://secondary.contoso.com/widgets"))
+	require.Equal(t, []string{"secondary.contoso.com", "secondary.contoso.com"}, transport.sentTo())
+
+	// other hosts aren't affected
+	require.Equal(t, http.StatusOK, sendFailoverRequest(t, pl, "https://other.contoso.com/widgets"))
+	require.Equal(t, []string{"other.contoso.com"}, transport.sentTo())
+
+	// after the cool-down, requests fail back to the primary
+	transport.responses["primary.contoso.com"] = transport.responses["secondary.contoso.com"]
+	now = now.Add(time.Minute)
+	require.Equal(t, http.StatusOK, sendFailoverRequest(t, pl, "https://secondary.contoso.com/widgets"))
+	require.Equal(t, []string{"primary.contoso.com"}, transport.sentTo())
+}
+
+func TestFailoverPolicyConnectionFailure(t *testing.T) {
+	fp, err := NewFailoverPolicy([]string{"https://primary.contoso.com", "https://secondary.contoso.com"}, nil)
+	require.NoError(t, err)
+	transport := newFailoverTransport()
+	transport.responses["primary.contoso.com"] = func() (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerRetryPolicies: []policy.Policy{fp},
+		Retry:            *testRetryOptions(),
+		Transport:        transport,
+	})
+	require.Equal(t, http.StatusOK, sendFailoverRequest(t, pl, "https://primary.contoso.com/"))
+	require.Equal(t, []string{"primary.contoso.com", "secondary.contoso.com"}, transport.sentTo())
+}
+
+func TestFailoverPolicyAllUnhealthy(t *testing.T) {
+	fp, err := NewFailoverPolicy([]string{"https://primary.contoso.com", "https://secondary.contoso.com"}, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	fp.now = func() time.Time {
+		// time passes between tries so the cool-downs end at different times
+		now = now.Add(time.Second)
+		return now
+	}
+
+	transport := newFailoverTransport()
+	fail := func() (*http.Response, error) { return &http.Response{StatusCode: http.StatusInternalServerError}, nil }
+	transport.responses["primary.contoso.com"] = fail
+	transport.responses["secondary.contoso.com"] = fail
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerRetryPolicies: []policy.Policy{fp},
+		Retry:            policy.RetryOptions{MaxRetries: 2, RetryDelay: time.Millisecond},
+		Transport:        transport,
+	})
+	require.Equal(t, http.StatusInternalServerError, sendFailoverRequest(t, pl, "https://primary.contoso.com/"))
+	// the third try goes to the endpoint whose cool-down ends first
+	require.Equal(t, []string{"primary.contoso.com", "secondary.contoso.com", "primary.contoso.com"}, transport.sentTo())
+}
+
+func TestFailoverPolicyIsFailure(t *testing.T) {
+	var fp *FailoverPolicy
+	fp, err := NewFailoverPolicy([]string{"https://primary.contoso.com", "https://secondary.contoso.com"}, &FailoverPolicyOptions{
+		IsFailure: func(resp *http.Response, err error) bool {
+			// the policy's lock isn't held, so the policy can be used here
+			require.Equal(t, 0, fp.selectEndpoint())
+			return resp.StatusCode == http.StatusNotFound
+		},
+	})
+	require.NoError(t, err)
+	transport := newFailoverTransport()
+	transport.responses["primary.contoso.com"] = func() (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotFound}, nil
+	}
+	pl := newTestPipeline(&policy.ClientOptions{
+		PerRetryPolicies: []policy.Policy{fp},
+		Retry:            *testRetryOptions(),
+		Transport:        transport,
+	})
+	require.Equal(t, http.StatusNotFound, sendFailoverRequest(t, pl, "https://primary.contoso.com/"))
+	// the primary is cooling down
+	require.Equal(t, 1, fp.selectEndpoint())
+}
+
+func TestNewFailoverPolicyErrors(t *testing.T) {
+	_, err := NewFailoverPolicy(nil, nil)
+	require.Error(t, err)
+	_, err = NewFailoverPolicy([]string{"primary.contoso.com"}, nil)
+	require.Error(t, err)
+	_, err = NewFailoverPolicy([]string{"https://primary.contoso.com", "://bad"}, nil)
+	require.Error(t, err)
+}
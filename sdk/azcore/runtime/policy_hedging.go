@@ -0,0 +1,252 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	internalexported "github.com/Azure/azure-sdk-for-go/sdk/internal/exported"
+)
+
+const (
+	defaultHedgingPercentile   = 95
+	defaultHedgingInitialDelay = 500 * time.Millisecond
+	defaultHedgingMaxRequests  = 1
+
+	// the number of latencies used to calculate the hedging delay
+	hedgingLatencyWindow = 100
+	// the number of latencies required before the percentile is used
+	hedgingMinLatencies = 20
+)
+
+// HedgingPolicyOptions contains the optional values configuring [HedgingPolicy].
+// Zero-value fields will have their specified default values applied during use.
+type HedgingPolicyOptions struct {
+	// Percentile of recently observed latencies after which a hedged request is sent.
+	// Valid values are greater than zero and less than 100. The default value is 95.
+	Percentile float64
+
+	// InitialDelay is the delay before sending a hedged request, used until enough
+	// latencies have been observed to calculate Percentile.
+	// The default value is 500 milliseconds.
+	InitialDelay time.Duration
+
+	// MinDelay is the minimum delay before sending a hedged request.
+	// The default value is zero.
+	MinDelay time.Duration
+
+	// MaxHedgedRequests is the maximum number of hedged requests sent in addition to the original request.
+	// The default value is one.
+	MaxHedgedRequests int
+}
+
+// HedgingPolicy reduces tail latency of idempotent reads by sending hedged requests.
+// When the response to a GET or HEAD request hasn't been received within a percentile of
+// recently observed latencies, a copy of the request is sent. The first response to arrive
+// that the retry policy wouldn't retry is returned and the remaining requests are cancelled.
+// Errors and retriable responses, such as 503 (Service Unavailable), are returned only when
+// no other request is in flight. Requests with other methods, or with a body, are sent unmodified.
+//
+// Latencies are measured from when the original request was sent, including when a hedged
+// request responds first, so that hedging doesn't lower the percentile it's based on.
+//
+// Add the policy to [policy.ClientOptions.PerRetryPolicies] so that each try is hedged.
+// Latencies are tracked per policy instance, so share an instance only between clients
+// with similar latency characteristics.
+type HedgingPolicy struct {
+	// the following fields are read-only
+	percentile   float64
+	initialDelay time.Duration
+	minDelay     time.Duration
+	maxHedges    int
+
+	latenciesMu sync.Mutex
+	latencies   []time.Duration
+	next        int
+}
+
+// NewHedgingPolicy creates a new instance of [HedgingPolicy].
+//   - options contains optional configuration, pass nil to accept the default values
+func NewHedgingPolicy(options *HedgingPolicyOptions) *HedgingPolicy {
+	if options == nil {
+		options = &HedgingPolicyOptions{}
+	}
+	h := &HedgingPolicy{
+		percentile:   options.Percentile,
+		initialDelay: options.InitialDelay,
+		minDelay:     options.MinDelay,
+		maxHedges:    options.MaxHedgedRequests,
+		latencies:    make([]time.Duration, 0, hedgingLatencyWindow),
+	}
+	if h.percentile <= 0 || h.percentile >= 100 {
+		h.percentile = defaultHedgingPercentile
+	}
+	if h.initialDelay <= 0 {
+		h.initialDelay = defaultHedgingInitialDelay
+	}
+	if h.maxHedges <= 0 {
+		h.maxHedges = defaultHedgingMaxRequests
+	}
+	return h
+}
+
+// hedgedResult is the outcome of a single request sent by the hedging policy
+type hedgedResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+// Do implements the Do method on the [policy.Policy] interface.
+func (h *HedgingPolicy) Do(req *policy.Request) (*http.Response, error) {
+	if m := req.Raw().Method; (m != http.MethodGet && m != http.MethodHead) || req.Body() != nil {
+		return req.Next()
+	}
+
+	parentCtx := req.Raw().Context()
+	// hedged requests are copied from a snapshot so they don't race with the original request
+	snapshot := exported.ConcurrentClone(req, parentCtx)
+	results := make(chan hedgedResult, h.maxHedges+1)
+	cancels := make([]context.CancelFunc, 0, h.maxHedges+1)
+	send := func() {
+		ctx, cancel := context.WithCancel(parentCtx)
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+		r := req.WithContext(ctx)
+		if attempt > 0 {
+			r = exported.ConcurrentClone(snapshot, ctx)
+		}
+		go func() {
+			resp, err := r.Next()
+			results <- hedgedResult{attempt: attempt, resp: resp, err: err}
+		}()
+	}
+
+	start := time.Now()
+	send()
+	inflight := 1
+	delay := h.delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	// last is the most recent error or retriable response
+	var last *hedgedResult
+	for {
+		select {
+		case <-timer.C:
+			if parentCtx.Err() != nil {
+				// the in-flight requests will return shortly
+				continue
+			}
+			log.Writef(log.EventRetryPolicy, "no response after %s, sending hedged %s request", delay, req.Raw().Method)
+			send()
+			inflight++
+			if len(cancels) <= h.maxHedges {
+				timer.Reset(delay)
+			}
+		case res := <-results:
+			inflight--
+			if res.err != nil || isHedgingRetriable(res.resp) {
+				if last != nil {
+					discardHedgedResult(*last, cancels)
+				}
+				last = &res
+				if inflight == 0 {
+					return deliverHedgedResult(res, cancels)
+				}
+				// wait for the remaining requests
+				continue
+			}
+			h.record(time.Since(start))
+			if res.attempt > 0 {
+				log.Writef(log.EventRetryPolicy, "hedged request %d responded first", res.attempt)
+			}
+			if last != nil {
+				discardHedgedResult(*last, cancels)
+			}
+			for i, cancel := range cancels {
+				if i != res.attempt {
+					cancel()
+				}
+			}
+			if inflight > 0 {
+				// clean up the cancelled requests in the background
+				go func(n int) {
+					for range n {
+						Drain((<-results).resp)
+					}
+				}(inflight)
+			}
+			return deliverHedgedResult(res, cancels)
+		}
+	}
+}
+
+// isHedgingRetriable returns true when the retry policy would retry resp by default,
+// in which case another request in flight may yet succeed
+func isHedgingRetriable(resp *http.Response) bool {
+	return HasStatusCode(resp,
+		http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	)
+}
+
+// discardHedgedResult releases the resources of a result that won't be returned
+func discardHedgedResult(res hedgedResult, cancels []context.CancelFunc) {
+	Drain(res.resp)
+	cancels[res.attempt]()
+}
+
+// deliverHedgedResult returns the outcome of res, cancelling its context once it's no longer needed
+func deliverHedgedResult(res hedgedResult, cancels []context.CancelFunc) (*http.Response, error) {
+	if res.err != nil {
+		cancels[res.attempt]()
+		return nil, res.err
+	}
+	if internalexported.PayloadDownloaded(res.resp) {
+		cancels[res.attempt]()
+	} else {
+		// must cancel the context after the body has been read and closed
+		res.resp.Body = &contextCancelReadCloser{cf: cancels[res.attempt], body: res.resp.Body}
+	}
+	return res.resp, nil
+}
+
+// delay returns the duration to wait before sending a hedged request
+func (h *HedgingPolicy) delay() time.Duration {
+	h.latenciesMu.Lock()
+	if len(h.latencies) < hedgingMinLatencies {
+		h.latenciesMu.Unlock()
+		return max(h.initialDelay, h.minDelay)
+	}
+	sorted := slices.Clone(h.latencies)
+	h.latenciesMu.Unlock()
+	slices.Sort(sorted)
+	i := int(math.Ceil(h.percentile/100*float64(len(sorted)))) - 1
+	return max(sorted[max(i, 0)], h.minDelay)
+}
+
+// record adds the latency of a request that responded first to the window of observed latencies
+func (h *HedgingPolicy) record(latency time.Duration) {
+	h.latenciesMu.Lock()
+	defer h.latenciesMu.Unlock()
+	if len(h.latencies) < hedgingLatencyWindow {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgingLatencyWindow
+}
@@ -0,0 +1,203 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// slowFirstTransport blocks the first request until its context is cancelled
+type slowFirstTransport struct {
+	count     atomic.Int32
+	cancelled atomic.Bool
+}
+
+func (s *slowFirstTransport) Do(req *http.Request) (*http.Response, error) {
+	n := s.count.Add(1)
+	if n == 1 {
+		select {
+		case <-req.Context().Done():
+			s.cancelled.Store(true)
+			return nil, req.Context().Err()
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("request wasn't cancelled")
+		}
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("hedged")),
+		Header:     http.Header{},
+		Request:    req,
+	}, nil
+}
+
+func TestHedgingPolicyDefaults(t *testing.T) {
+	h := NewHedgingPolicy(nil)
+	require.EqualValues(t, defaultHedgingPercentile, h.percentile)
+	require.Equal(t, defaultHedgingInitialDelay, h.initialDelay)
+	require.Equal(t, defaultHedgingMaxRequests, h.maxHedges)
+	require.Equal(t, defaultHedgingInitialDelay, h.delay())
+
+	h = NewHedgingPolicy(&HedgingPolicyOptions{Percentile: 100, MinDelay: time.Second})
+	require.EqualValues(t, defaultHedgingPercentile, h.percentile)
+	require.Equal(t, time.Second, h.delay())
+}
+
+func TestHedgingPolicyDelay(t *testing.T) {
+	h := NewHedgingPolicy(&HedgingPolicyOptions{Percentile: 90, InitialDelay: time.Hour})
+	for i := 1; i < hedgingMinLatencies; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	require.Equal(t, time.Hour, h.delay())
+	h.record(hedgingMinLatencies * time.Millisecond)
+	require.Equal(t, 18*time.Millisecond, h.delay())
+
+	// old latencies fall out of the window
+	for range hedgingLatencyWindow {
+		h.record(time.Second)
+	}
+	require.Len(t, h.latencies, hedgingLatencyWindow)
+	require.Equal(t, time.Second, h.delay())
+}
+
+func TestHedgingPolicyNoHedge(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	pl := exported.NewPipeline(srv, NewHedgingPolicy(&HedgingPolicyOptions{InitialDelay: time.Minute}))
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+	require.EqualValues(t, 1, srv.Requests())
+}
+
+func TestHedgingPolicyHedgedResponseWins(t *testing.T) {
+	trans := &slowFirstTransport{}
+	pl := newTestPipeline(&policy.ClientOptions{
+		Transport:        trans,
+		PerRetryPolicies: []policy.Policy{NewHedgingPolicy(&HedgingPolicyOptions{InitialDelay: 10 * time.Millisecond})},
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	body, err := Payload(resp)
+	require.NoError(t, err)
+	require.Equal(t, "hedged", string(body))
+	require.EqualValues(t, 2, trans.count.Load())
+	require.Eventually(t, trans.cancelled.Load, time.Second, time.Millisecond)
+}
+
+func TestHedgingPolicyMaxHedgedRequests(t *testing.T) {
+	var count atomic.Int32
+	pl := exported.NewPipeline(shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		if count.Add(1) < 3 {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	}), NewHedgingPolicy(&HedgingPolicyOptions{InitialDelay: 5 * time.Millisecond, MaxHedgedRequests: 2}))
+	req, err := NewRequest(context.Background(), http.MethodHead, "https://contoso.com")
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusOK, resp.StatusCode)
+	require.EqualValues(t, 3, count.Load())
+}
+
+func TestHedgingPolicyNotIdempotent(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithSlowResponse(50*time.Millisecond))
+	pl := exported.NewPipeline(srv, NewHedgingPolicy(&HedgingPolicyOptions{InitialDelay: time.Millisecond}))
+	for _, method := range []string{http.MethodPut, http.MethodPost, http.MethodDelete} {
+		req, err := NewRequest(context.Background(), method, srv.URL())
+		require.NoError(t, err)
+		_, err = pl.Do(req)
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 3, srv.Requests())
+}
+
+func TestHedgingPolicyAllFail(t *testing.T) {
+	var count atomic.Int32
+	pl := exported.NewPipeline(shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		if count.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			return nil, errors.New("first")
+		}
+		return nil, errors.New("second")
+	}), NewHedgingPolicy(&HedgingPolicyOptions{InitialDelay: time.Millisecond}))
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.Nil(t, resp)
+	require.EqualError(t, err, "first")
+	require.EqualValues(t, 2, count.Load())
+}
+
+func TestHedgingPolicyRetriableResponseDoesntWin(t *testing.T) {
+	var count atomic.Int32
+	pl := exported.NewPipeline(shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		if count.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+	}), NewHedgingPolicy(&HedgingPolicyOptions{InitialDelay: time.Millisecond}))
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusOK, resp.StatusCode)
+	require.EqualValues(t, 2, count.Load())
+
+	// a retriable response is returned when there's nothing better
+	count.Store(1)
+	resp, err = pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestHedgingPolicyLatencyIncludesHedgeDelay(t *testing.T) {
+	trans := &slowFirstTransport{}
+	h := NewHedgingPolicy(&HedgingPolicyOptions{InitialDelay: 20 * time.Millisecond})
+	pl := exported.NewPipeline(trans, h)
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	Drain(resp)
+	require.Len(t, h.latencies, 1)
+	require.GreaterOrEqual(t, h.latencies[0], 20*time.Millisecond)
+}
+
+func TestHedgingPolicyContextCancelled(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithSlowResponse(time.Second))
+	pl := exported.NewPipeline(srv, NewHedgingPolicy(&HedgingPolicyOptions{InitialDelay: time.Minute}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := NewRequest(ctx, http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/metrics"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	internalexported "github.com/Azure/azure-sdk-for-go/sdk/internal/exported"
+)
+
+const (
+	metricHTTPClientRequestDuration  = "http.client.request.duration"
+	metricHTTPClientResponseBodySize = "http.client.response.body.size"
+	metricAZClientRetryCount         = "az.client.retry.count"
+	metricAZClientThrottledCount     = "az.client.throttled.count"
+
+	attrHTTPRequestMethod      = "http.request.method"
+	attrHTTPResponseStatusCode = "http.response.status_code"
+	attrServerAddress          = "server.address"
+	attrErrorType              = "error.type"
+)
+
+// newHTTPMetricsPolicy creates a new instance of the httpMetricsPolicy.
+//   - meter is used to create the instruments; it must be enabled
+//   - namespace contains the value for the az.namespace attribute; it's omitted when empty
+func newHTTPMetricsPolicy(meter metrics.Meter, namespace string) exported.Policy {
+	return &httpMetricsPolicy{
+		duration: meter.NewHistogram(metricHTTPClientRequestDuration, &metrics.InstrumentOptions{
+			Description: "Duration of HTTP client requests.",
+			Unit:        "s",
+		}),
+		bodySize: meter.NewHistogram(metricHTTPClientResponseBodySize, &metrics.InstrumentOptions{
+			Description: "Size of HTTP client response bodies.",
+			Unit:        "By",
+		}),
+		retries: meter.NewCounter(metricAZClientRetryCount, &metrics.InstrumentOptions{
+			Description: "Number of HTTP requests that were retries of a previous request.",
+			Unit:        "{request}",
+		}),
+		throttled: meter.NewCounter(metricAZClientThrottledCount, &metrics.InstrumentOptions{
+			Description: "Number of HTTP requests throttled by the service.",
+			Unit:        "{request}",
+		}),
+		namespace: namespace,
+	}
+}
+
+// httpMetricsPolicy is a policy that records metrics for the HTTP request and its response
+type httpMetricsPolicy struct {
+	duration  metrics.Histogram
+	bodySize  metrics.Histogram
+	retries   metrics.Counter
+	throttled metrics.Counter
+	namespace string
+}
+
+// httpMetricsOpValues is the struct containing the per-operation values
+type httpMetricsOpValues struct {
+	try int32
+}
+
+// Do implements the pipeline.Policy interfaces for the httpMetricsPolicy type.
+func (h *httpMetricsPolicy) Do(req *policy.Request) (*http.Response, error) {
+	// the try count persists across retries as it's stored in the operation values
+	var opValues httpMetricsOpValues
+	req.OperationValue(&opValues)
+	opValues.try++
+	req.SetOperationValue(opValues)
+
+	attrs := make([]metrics.Attribute, 0, 5)
+	if h.namespace != "" {
+		attrs = append(attrs, metrics.Attribute{Key: shared.TracingNamespaceAttrName, Value: h.namespace})
+	}
+	attrs = append(attrs,
+		metrics.Attribute{Key: attrHTTPRequestMethod, Value: req.Raw().Method},
+		metrics.Attribute{Key: attrServerAddress, Value: req.Raw().URL.Hostname()},
+	)
+
+	ctx := req.Raw().Context()
+	if opValues.try > 1 {
+		h.retries.Add(ctx, 1, attrs...)
+	}
+
+	start := time.Now()
+	resp, err := req.Next()
+	elapsed := time.Since(start)
+
+	if resp != nil {
+		attrs = append(attrs, metrics.Attribute{Key: attrHTTPResponseStatusCode, Value: resp.StatusCode})
+		if resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode == http.StatusServiceUnavailable && shared.RetryAfter(resp) > 0) {
+			h.throttled.Add(ctx, 1, attrs...)
+		}
+		if internalexported.PayloadDownloaded(resp) {
+			if body, err := Payload(resp); err == nil {
+				h.bodySize.Record(ctx, float64(len(body)), attrs...)
+			}
+		} else if resp.Body != nil && resp.Body != http.NoBody {
+			// the caller streams the body, e.g. after SkipBodyDownload, so its size
+			// is known only after it has been read. ContentLength is -1 for chunked
+			// and compressed responses.
+			bodyAttrs := attrs
+			resp.Body = &bodySizeReadCloser{body: resp.Body, record: func(size int64) {
+				h.bodySize.Record(ctx, float64(size), bodyAttrs...)
+			}}
+		} else {
+			h.bodySize.Record(ctx, 0, attrs...)
+		}
+	} else if err != nil {
+		attrs = append(attrs, metrics.Attribute{Key: attrErrorType, Value: strings.Replace(fmt.Sprintf("%T", err), "*exported.", "*azcore.", 1)})
+	}
+	h.duration.Record(ctx, elapsed.Seconds(), attrs...)
+	return resp, err
+}
+
+// bodySizeReadCloser counts the bytes read from a response body and
+// calls record with the count when it reaches the end of the body
+type bodySizeReadCloser struct {
+	body     io.ReadCloser
+	size     int64
+	record   func(int64)
+	recorded bool
+}
+
+func (b *bodySizeReadCloser) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.size += int64(n)
+	if err == io.EOF && !b.recorded {
+		b.recorded = true
+		b.record(b.size)
+	}
+	return n, err
+}
+
+// Close closes the body. A body closed before it has been read to the end isn't recorded
+// because its size is unknown.
+func (b *bodySizeReadCloser) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,163 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/metrics"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type measurement struct {
+	value float64
+	attrs []metrics.Attribute
+}
+
+// testMeter records all measurements by instrument name
+type testMeter struct {
+	mu           sync.Mutex
+	measurements map[string][]measurement
+}
+
+func (tm *testMeter) record(name string, value float64, attrs []metrics.Attribute) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.measurements[name] = append(tm.measurements[name], measurement{value: value, attrs: attrs})
+}
+
+func newTestMeterProvider(tm *testMeter) metrics.Provider {
+	tm.measurements = map[string][]measurement{}
+	return metrics.NewProvider(func(name, version string) metrics.Meter {
+		return metrics.NewMeter(metrics.MeterImpl{
+			NewHistogram: func(name string, _ *metrics.InstrumentOptions) metrics.Histogram {
+				return metrics.NewHistogram(metrics.HistogramImpl{
+					Record: func(_ context.Context, v float64, attrs ...metrics.Attribute) { tm.record(name, v, attrs) },
+				})
+			},
+			NewCounter: func(name string, _ *metrics.InstrumentOptions) metrics.Counter {
+				return metrics.NewCounter(metrics.CounterImpl{
+					Add: func(_ context.Context, v int64, attrs ...metrics.Attribute) { tm.record(name, float64(v), attrs) },
+				})
+			},
+		})
+	}, nil)
+}
+
+func TestHTTPMetricsPolicy(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusTooManyRequests), mock.WithHeader(shared.HeaderRetryAfterMS, "1"))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte("payload")))
+
+	tm := &testMeter{}
+	pl := NewPipeline("module", "v1.0.0", PipelineOptions{Tracing: TracingOptions{Namespace: "Microsoft.Test"}}, &policy.ClientOptions{
+		MeterProvider: newTestMeterProvider(tm),
+		Retry:         *testRetryOptions(),
+		Transport:     srv,
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusOK, resp.StatusCode)
+
+	host := req.Raw().URL.Hostname()
+	baseAttrs := []metrics.Attribute{
+		{Key: shared.TracingNamespaceAttrName, Value: "Microsoft.Test"},
+		{Key: attrHTTPRequestMethod, Value: http.MethodGet},
+		{Key: attrServerAddress, Value: host},
+	}
+	throttledAttrs := append(append([]metrics.Attribute{}, baseAttrs...), metrics.Attribute{Key: attrHTTPResponseStatusCode, Value: http.StatusTooManyRequests})
+	okAttrs := append(append([]metrics.Attribute{}, baseAttrs...), metrics.Attribute{Key: attrHTTPResponseStatusCode, Value: http.StatusOK})
+
+	durations := tm.measurements[metricHTTPClientRequestDuration]
+	require.Len(t, durations, 2)
+	require.Equal(t, throttledAttrs, durations[0].attrs)
+	require.Equal(t, okAttrs, durations[1].attrs)
+	require.Equal(t, []measurement{{value: 1, attrs: baseAttrs}}, tm.measurements[metricAZClientRetryCount])
+	require.Equal(t, []measurement{{value: 1, attrs: throttledAttrs}}, tm.measurements[metricAZClientThrottledCount])
+	sizes := tm.measurements[metricHTTPClientResponseBodySize]
+	require.Len(t, sizes, 2)
+	require.EqualValues(t, 0, sizes[0].value)
+	require.Equal(t, measurement{value: float64(len("payload")), attrs: okAttrs}, sizes[1])
+}
+
+func TestHTTPMetricsPolicyChunkedResponse(t *testing.T) {
+	tm := &testMeter{}
+	pl := NewPipeline("module", "v1.0.0", PipelineOptions{}, &policy.ClientOptions{
+		MeterProvider: newTestMeterProvider(tm),
+		Transport: shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+			// a chunked response has no Content-Length
+			return &http.Response{
+				StatusCode:       http.StatusOK,
+				Header:           http.Header{},
+				Body:             io.NopCloser(strings.NewReader("chunked payload")),
+				ContentLength:    -1,
+				TransferEncoding: []string{"chunked"},
+				Request:          req,
+			}, nil
+		}),
+	})
+
+	// the pipeline downloads the body
+	req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+	sizes := tm.measurements[metricHTTPClientResponseBodySize]
+	require.Len(t, sizes, 1)
+	require.EqualValues(t, len("chunked payload"), sizes[0].value)
+
+	// the caller streams the body
+	req, err = NewRequest(context.Background(), http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	SkipBodyDownload(req)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.Len(t, tm.measurements[metricHTTPClientResponseBodySize], 1, "the size isn't known until the body is read")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "chunked payload", string(body))
+	sizes = tm.measurements[metricHTTPClientResponseBodySize]
+	require.Len(t, sizes, 2)
+	require.EqualValues(t, len("chunked payload"), sizes[1].value)
+}
+
+func TestHTTPMetricsPolicyError(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetError(errors.New("boom"))
+
+	tm := &testMeter{}
+	pl := NewPipeline("module", "v1.0.0", PipelineOptions{}, &policy.ClientOptions{
+		MeterProvider: newTestMeterProvider(tm),
+		Retry:         policy.RetryOptions{MaxRetries: -1},
+		Transport:     srv,
+	})
+	req, err := NewRequest(context.Background(), http.MethodPut, srv.URL())
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.Error(t, err)
+
+	durations := tm.measurements[metricHTTPClientRequestDuration]
+	require.Len(t, durations, 1)
+	require.Equal(t, []metrics.Attribute{
+		{Key: attrHTTPRequestMethod, Value: http.MethodPut},
+		{Key: attrServerAddress, Value: req.Raw().URL.Hostname()},
+		{Key: attrErrorType, Value: "*errors.errorString"},
+	}, durations[0].attrs)
+	require.Empty(t, tm.measurements[metricAZClientRetryCount])
+	require.Empty(t, tm.measurements[metricHTTPClientResponseBodySize])
+}
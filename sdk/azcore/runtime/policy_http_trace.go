@@ -65,6 +65,7 @@ func (h *httpTracePolicy) Do(req *policy.Request) (resp *http.Response, err erro
 			Kind:       tracing.SpanKindClient,
 			Attributes: attributes,
 		})
+		tracer.Inject(ctx, tracing.HTTPHeaderCarrier(req.Raw().Header))
 
 		defer func() {
 			if resp != nil {
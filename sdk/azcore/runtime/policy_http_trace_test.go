@@ -294,3 +294,63 @@ func TestStartSpanWithKind(t *testing.T) {
 	require.True(t, startCalled)
 	require.True(t, endCalled)
 }
+
+func TestHTTPTracePolicyPropagation(t *testing.T) {
+	type spanKey struct{}
+	sc := tracing.SpanContext{
+		TraceID:    [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: tracing.TraceFlagsSampled,
+	}
+	provider := tracing.NewProvider(func(name, version string) tracing.Tracer {
+		return tracing.NewTracer(func(ctx context.Context, spanName string, options *tracing.SpanOptions) (context.Context, tracing.Span) {
+			span := tracing.NewSpan(tracing.SpanImpl{
+				SpanContext: func() tracing.SpanContext { return sc },
+			})
+			return context.WithValue(ctx, spanKey{}, span), span
+		}, &tracing.TracerOptions{
+			SpanFromContext: func(ctx context.Context) tracing.Span {
+				span, _ := ctx.Value(spanKey{}).(tracing.Span)
+				return span
+			},
+		})
+	}, &tracing.ProviderOptions{
+		Propagator: tracing.NewCompositePropagator(tracing.NewTraceContextPropagator(), tracing.NewBaggagePropagator()),
+	})
+
+	var header http.Header
+	pl := exported.NewPipeline(shared.TransportFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header.Clone()
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	}), newHTTPTracePolicy(nil))
+
+	ctx := context.WithValue(context.Background(), shared.CtxWithTracingTracer{}, provider.NewTracer("module", "v1.0.0"))
+	ctx = tracing.ContextWithBaggage(ctx, tracing.Baggage{"tenant": "contoso"})
+	req, err := exported.NewRequest(ctx, http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", header.Get("traceparent"))
+	require.Equal(t, "tenant=contoso", header.Get("baggage"))
+
+	// an extracted remote span context isn't sent as the client span's
+	remote := provider.NewTracer("module", "v1.0.0").Extract(context.Background(), tracing.MapCarrier{"traceparent": "00-11111111111111111111111111111111-2222222222222222-01"})
+	noSpanContext := tracing.NewProvider(func(name, version string) tracing.Tracer {
+		return tracing.NewTracer(func(ctx context.Context, spanName string, options *tracing.SpanOptions) (context.Context, tracing.Span) {
+			return ctx, tracing.Span{}
+		}, nil)
+	}, &tracing.ProviderOptions{Propagator: tracing.NewTraceContextPropagator()})
+	req, err = exported.NewRequest(context.WithValue(remote, shared.CtxWithTracingTracer{}, noSpanContext.NewTracer("module", "v1.0.0")), http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+	require.Empty(t, header.Get("traceparent"))
+
+	// without a propagator, no headers are added
+	tr := tracing.NewProvider(func(name, version string) tracing.Tracer { return provider.NewTracer(name, version) }, nil).NewTracer("module", "v1.0.0")
+	req, err = exported.NewRequest(context.WithValue(context.Background(), shared.CtxWithTracingTracer{}, tr), http.MethodGet, "https://contoso.com")
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+	require.Empty(t, header.Get("traceparent"))
+}
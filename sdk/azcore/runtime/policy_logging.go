@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"sort"
@@ -26,14 +27,31 @@ type logPolicy struct {
 	includeBody    bool
 	allowedHeaders map[string]struct{}
 	allowedQP      map[string]struct{}
+	namespace      string
 }
 
 // NewLogPolicy creates a request/response logging policy object configured using the specified options.
 // Pass nil to accept the default values; this is the same as passing a zero-value options.
 func NewLogPolicy(o *policy.LogOptions) policy.Policy {
+	return newLogPolicy(o, "")
+}
+
+// newLogPolicy creates a logging policy that includes the namespace in its structured log entries.
+func newLogPolicy(o *policy.LogOptions, namespace string) policy.Policy {
 	if o == nil {
 		o = &policy.LogOptions{}
 	}
+	return &logPolicy{
+		includeBody:    o.IncludeBody,
+		allowedHeaders: getAllowedHeaders(o.AllowedHeaders),
+		allowedQP:      getAllowedQueryParams(o.AllowedQueryParams),
+		namespace:      namespace,
+	}
+}
+
+// getAllowedHeaders merges the default set of allowed headers
+// with a custom set (usually comes from client options).
+func getAllowedHeaders(customAllowedHeaders []string) map[string]struct{} {
 	// construct default hash set of allowed headers
 	allowedHeaders := map[string]struct{}{
 		"accept":                        {},
@@ -63,16 +81,10 @@ func NewLogPolicy(o *policy.LogOptions) policy.Policy {
 		"x-ms-return-client-request-id": {},
 	}
 	// add any caller-specified allowed headers to the set
-	for _, ah := range o.AllowedHeaders {
+	for _, ah := range customAllowedHeaders {
 		allowedHeaders[strings.ToLower(ah)] = struct{}{}
 	}
-	// now do the same thing for query params
-	allowedQP := getAllowedQueryParams(o.AllowedQueryParams)
-	return &logPolicy{
-		includeBody:    o.IncludeBody,
-		allowedHeaders: allowedHeaders,
-		allowedQP:      allowedQP,
-	}
+	return allowedHeaders
 }
 
 // getAllowedQueryParams merges the default set of allowed query parameters
@@ -103,7 +115,7 @@ func (p *logPolicy) Do(req *policy.Request) (*http.Response, error) {
 	req.SetOperationValue(opValues)
 
 	// Log the outgoing request as informational
-	if log.Should(log.EventRequest) {
+	if log.ShouldListener(log.EventRequest) {
 		b := &bytes.Buffer{}
 		fmt.Fprintf(b, "==> OUTGOING REQUEST (Try=%d)\n", opValues.try)
 		p.writeRequestWithResponse(b, req, nil, nil)
@@ -111,11 +123,17 @@ func (p *logPolicy) Do(req *policy.Request) (*http.Response, error) {
 		if p.includeBody {
 			err = writeReqBody(req, b)
 		}
-		log.Write(log.EventRequest, b.String())
+		log.WriteListener(log.EventRequest, b.String())
 		if err != nil {
 			return nil, err
 		}
 	}
+	ctx := req.Raw().Context()
+	if log.ShouldAttrs(ctx, log.EventRequest) {
+		attrs := requestLogAttrs(req, opValues.try, p.namespace, p.allowedQP)
+		attrs = append(attrs, slog.Any(attrHTTPRequestHeader, p.headerLogValue(req.Raw().Header)))
+		log.WriteAttrs(ctx, log.EventRequest, "Sending request", attrs...)
+	}
 
 	// Set the time for this particular retry operation and then Do the operation.
 	tryStart := time.Now()
@@ -124,7 +142,7 @@ func (p *logPolicy) Do(req *policy.Request) (*http.Response, error) {
 	tryDuration := tryEnd.Sub(tryStart)
 	opDuration := tryEnd.Sub(opValues.start)
 
-	if log.Should(log.EventResponse) {
+	if log.ShouldListener(log.EventResponse) {
 		// We're going to log this; build the string to log
 		b := &bytes.Buffer{}
 		fmt.Fprintf(b, "==> REQUEST/RESPONSE (Try=%d/%v, OpTime=%v) -- ", opValues.try, tryDuration, opDuration)
@@ -141,13 +159,80 @@ func (p *logPolicy) Do(req *policy.Request) (*http.Response, error) {
 		} else if p.includeBody {
 			err = writeRespBody(response, b)
 		}
-		log.Write(log.EventResponse, b.String())
+		log.WriteListener(log.EventResponse, b.String())
+	}
+	if log.ShouldAttrs(ctx, log.EventResponse) {
+		attrs := requestLogAttrs(req, opValues.try, p.namespace, p.allowedQP)
+		attrs = append(attrs, slog.Duration(attrTryDuration, tryDuration), slog.Duration(attrOperationDuration, opDuration))
+		msg := "Received response"
+		if response != nil {
+			attrs = append(attrs, slog.Int(attrHTTPResponseStatusCode, response.StatusCode))
+			if reqID := response.Header.Get(shared.HeaderXMSRequestID); reqID != "" {
+				attrs = append(attrs, slog.String(attrAZServiceReqID, reqID))
+			}
+			attrs = append(attrs, slog.Any(attrHTTPResponseHeader, p.headerLogValue(response.Header)))
+		}
+		if err != nil {
+			msg = "Request failed"
+			attrs = append(attrs, slog.Any(attrError, err))
+		}
+		log.WriteAttrs(ctx, log.EventResponse, msg, attrs...)
 	}
 	return response, err
 }
 
+// requestLogAttrs returns the attributes describing a request in structured log entries.
+// The URL is sanitized with the specified allow-list of query parameters.
+func requestLogAttrs(req *policy.Request, try int32, namespace string, allowedQP map[string]struct{}) []slog.Attr {
+	attrs := make([]slog.Attr, 0, 10)
+	if namespace != "" {
+		attrs = append(attrs, slog.String(shared.TracingNamespaceAttrName, namespace))
+	}
+	attrs = append(attrs,
+		slog.String(attrHTTPRequestMethod, req.Raw().Method),
+		slog.String(attrURLFull, getSanitizedURL(*req.Raw().URL, allowedQP)),
+		slog.Int(attrTryCount, int(try)),
+	)
+	if reqID := req.Raw().Header.Get(shared.HeaderXMSClientRequestID); reqID != "" {
+		attrs = append(attrs, slog.String(attrAZClientReqID, reqID))
+	}
+	return attrs
+}
+
+// headerLogValue returns a group containing the first value of each header, redacting values not in the allow-list.
+func (p *logPolicy) headerLogValue(header http.Header) slog.Value {
+	attrs := make([]slog.Attr, 0, len(header))
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := header[k][0]
+		if _, ok := p.allowedHeaders[strings.ToLower(k)]; !ok {
+			value = redactedValue
+		}
+		attrs = append(attrs, slog.String(k, value))
+	}
+	return slog.GroupValue(attrs...)
+}
+
 const redactedValue = "REDACTED"
 
+// attribute names for structured log entries. see also the metrics and tracing attributes
+const (
+	attrURLFull            = "url.full"
+	attrHTTPRequestHeader  = "http.request.header"
+	attrHTTPResponseHeader = "http.response.header"
+	attrTryCount           = "az.try_count"
+	attrTryDuration        = "az.try_duration"
+	attrOperationDuration  = "az.operation_duration"
+	attrRetryDelay         = "az.retry_delay"
+	attrMaxRetries         = "az.max_retries"
+	attrMaxRetryDelay      = "az.max_retry_delay"
+	attrError              = "error"
+)
+
 // getSanitizedURL returns a sanitized string for the provided url.URL
 func getSanitizedURL(u url.URL, allowedQueryParams map[string]struct{}) string {
 	// redact applicable query params
@@ -11,13 +11,16 @@ import (
 	"context"
 	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	azlog "github.com/Azure/azure-sdk-for-go/sdk/azcore/log"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
 	"github.com/stretchr/testify/require"
@@ -308,3 +311,103 @@ func TestWriteRespBodyReadError(t *testing.T) {
 	require.Error(t, writeRespBody(resp, &buf))
 	require.Contains(t, buf.String(), "Failed to read response body: read failed")
 }
+
+// slogRecorder is a slog.Handler that records the entries written to it
+type slogRecorder struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (s *slogRecorder) Enabled(context.Context, slog.Level) bool { return true }
+
+func (s *slogRecorder) Handle(_ context.Context, r slog.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, r.Clone())
+	return nil
+}
+
+func (s *slogRecorder) WithAttrs([]slog.Attr) slog.Handler { return s }
+
+func (s *slogRecorder) WithGroup(string) slog.Handler { return s }
+
+// find returns the attributes of the entries with the specified message
+func (s *slogRecorder) find(msg string) []map[string]slog.Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := []map[string]slog.Value{}
+	for _, r := range s.records {
+		if r.Message != msg {
+			continue
+		}
+		attrs := map[string]slog.Value{}
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value
+			return true
+		})
+		found = append(found, attrs)
+	}
+	return found
+}
+
+func TestPolicyLoggingSlog(t *testing.T) {
+	rec := &slogRecorder{}
+	azlog.SetSlogLogger(slog.New(rec), nil)
+	defer azlog.SetSlogLogger(nil, nil)
+	log.SetListener(nil)
+
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader(shared.HeaderXMSRequestID, "service-id"))
+
+	pl := NewPipeline("module", "v1.0.0", PipelineOptions{Tracing: TracingOptions{Namespace: "Microsoft.Test"}}, &policy.ClientOptions{
+		Retry:     *testRetryOptions(),
+		Transport: srv,
+	})
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL()+"?api-version=1&sig=secret")
+	require.NoError(t, err)
+	req.Raw().Header.Set(shared.HeaderXMSClientRequestID, "client-id")
+	req.Raw().Header.Set(shared.HeaderAuthorization, "Bearer secret")
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+
+	require.Len(t, rec.find("Starting try"), 2)
+	sent := rec.find("Sending request")
+	require.Len(t, sent, 2)
+	for i, attrs := range sent {
+		require.EqualValues(t, i+1, attrs[attrTryCount].Int64())
+		require.Equal(t, "Microsoft.Test", attrs[shared.TracingNamespaceAttrName].String())
+		require.Equal(t, http.MethodGet, attrs[attrHTTPRequestMethod].String())
+		require.Contains(t, attrs[attrURLFull].String(), "api-version=1")
+		require.Contains(t, attrs[attrURLFull].String(), "sig=REDACTED")
+		require.Equal(t, "client-id", attrs[attrAZClientReqID].String())
+		require.Equal(t, string(log.EventRequest), attrs["az.event"].String())
+		headers := map[string]string{}
+		for _, a := range attrs[attrHTTPRequestHeader].Group() {
+			headers[a.Key] = a.Value.String()
+		}
+		require.Equal(t, "REDACTED", headers[shared.HeaderAuthorization])
+		require.Equal(t, "client-id", headers[http.CanonicalHeaderKey(shared.HeaderXMSClientRequestID)])
+	}
+
+	received := []map[string]slog.Value{}
+	for _, attrs := range rec.find("Received response") {
+		if attrs["az.event"].String() == string(log.EventResponse) {
+			received = append(received, attrs)
+		}
+	}
+	require.Len(t, received, 2)
+	require.EqualValues(t, 1, received[0][attrTryCount].Int64())
+	require.EqualValues(t, http.StatusServiceUnavailable, received[0][attrHTTPResponseStatusCode].Int64())
+	require.EqualValues(t, 2, received[1][attrTryCount].Int64())
+	require.EqualValues(t, http.StatusOK, received[1][attrHTTPResponseStatusCode].Int64())
+	require.Equal(t, "service-id", received[1][attrAZServiceReqID].String())
+	require.Equal(t, slog.KindDuration, received[1][attrTryDuration].Kind())
+	require.Equal(t, slog.KindDuration, received[1][attrOperationDuration].Kind())
+
+	retrying := rec.find("Retrying request")
+	require.Len(t, retrying, 1)
+	require.EqualValues(t, 1, retrying[0][attrTryCount].Int64())
+	require.Equal(t, slog.KindDuration, retrying[0][attrRetryDelay].Kind())
+}
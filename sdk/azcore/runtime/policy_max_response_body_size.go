@@ -0,0 +1,87 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// newMaxResponseBodySizePolicy creates a policy that limits the size of response bodies read by [Payload].
+//   - maxSize is the maximum size in bytes, it must be greater than zero
+func newMaxResponseBodySizePolicy(maxSize int64) *maxResponseBodySizePolicy {
+	return &maxResponseBodySizePolicy{maxSize: maxSize}
+}
+
+// maxResponseBodySizePolicy adds the maximum size of response bodies to the request's context
+type maxResponseBodySizePolicy struct {
+	maxSize int64
+}
+
+// Do implements the [policy.Policy] interface for maxResponseBodySizePolicy.
+func (m *maxResponseBodySizePolicy) Do(req *policy.Request) (*http.Response, error) {
+	return req.WithContext(context.WithValue(req.Raw().Context(), shared.CtxMaxResponseBodySizeKey{}, m.maxSize)).Next()
+}
+
+// ResponseBodyTooLargeError is returned when reading a response body that's
+// larger than the maximum size configured with [policy.ClientOptions].
+// Use errors.As() to access this type in the error chain.
+type ResponseBodyTooLargeError struct {
+	// MaxSize is the maximum size of response bodies, in bytes.
+	MaxSize int64
+}
+
+// Error implements the error interface for type ResponseBodyTooLargeError.
+func (e *ResponseBodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the maximum size of %d bytes", e.MaxSize)
+}
+
+// NonRetriable indicates this error is non-transient so the retry policy won't retry the request.
+func (*ResponseBodyTooLargeError) NonRetriable() {
+	// marker method
+}
+
+// maxResponseBodySize returns the maximum size of resp's body or zero when it's unlimited
+func maxResponseBodySize(resp *http.Response) int64 {
+	if resp.Request == nil {
+		return 0
+	}
+	size, _ := resp.Request.Context().Value(shared.CtxMaxResponseBodySizeKey{}).(int64)
+	return size
+}
+
+// limitedBody fails reads that exceed the maximum size of a response body
+type limitedBody struct {
+	body      io.ReadCloser
+	max       int64
+	remaining int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, &ResponseBodyTooLargeError{MaxSize: l.max}
+	}
+	// read one byte more than remaining to detect bodies exceeding the limit
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.body.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n - 1, &ResponseBodyTooLargeError{MaxSize: l.max}
+	}
+	return n, err
+}
+
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
@@ -0,0 +1,80 @@
+//go:build go1.18
+// +build go1.18
+
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxResponseBodySize(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		size    int
+		maxSize int64
+		skip    bool
+		fails   bool
+	}{
+		{name: "larger", size: 101, maxSize: 100, fails: true},
+		{name: "larger and skip download", size: 101, maxSize: 100, skip: true, fails: true},
+		{name: "at the limit", size: 100, maxSize: 100},
+		{name: "unlimited", size: 101},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []*http.Request
+			// no CompressionPolicy is required to limit the size of response bodies
+			pl := newTestPipeline(&policy.ClientOptions{
+				MaxResponseBodySize: test.maxSize,
+				Retry:               *testRetryOptions(),
+				Transport:           compressedResponseTransport(t, "", bytes.Repeat([]byte("a"), test.size), &requests),
+			})
+			req, err := NewRequest(context.Background(), http.MethodGet, "https://contoso.com/widgets")
+			require.NoError(t, err)
+			if test.skip {
+				SkipBodyDownload(req)
+			}
+			resp, err := pl.Do(req)
+			if test.skip {
+				require.NoError(t, err)
+				_, err = Payload(resp)
+			}
+			if !test.fails {
+				require.NoError(t, err)
+				body, err := Payload(resp)
+				require.NoError(t, err)
+				require.Len(t, body, test.size)
+				return
+			}
+			var tooLarge *ResponseBodyTooLargeError
+			require.ErrorAs(t, err, &tooLarge)
+			require.EqualValues(t, test.maxSize, tooLarge.MaxSize)
+			// the error isn't retried
+			require.Len(t, requests, 1)
+		})
+	}
+}
+
+func TestPayloadMaxResponseBodySizeUnknownLength(t *testing.T) {
+	req, err := http.NewRequestWithContext(context.WithValue(context.Background(), shared.CtxMaxResponseBodySizeKey{}, int64(4)), http.MethodGet, "https://contoso.com", nil)
+	require.NoError(t, err)
+	resp := &http.Response{
+		Body:          io.NopCloser(strings.NewReader("12345")),
+		ContentLength: -1,
+		Request:       req,
+	}
+	_, err = Payload(resp)
+	var tooLarge *ResponseBodyTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+}
@@ -0,0 +1,241 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	defaultRateLimitRequestsPerSecond = 10
+
+	// the prefix of headers containing the number of requests remaining before throttling occurs.
+	// e.g. x-ms-ratelimit-remaining-subscription-reads
+	headerXMSRateLimitRemainingPrefix = "x-ms-ratelimit-remaining-"
+)
+
+// RateLimitPolicyOptions contains the optional values configuring [RateLimitPolicy].
+// Zero-value fields will have their specified default values applied during use.
+type RateLimitPolicyOptions struct {
+	// RequestsPerSecond is the sustained rate at which requests are sent.
+	// The default value is 10.
+	RequestsPerSecond float64
+
+	// Burst is the maximum number of requests that can be sent at once.
+	// The default value is RequestsPerSecond rounded up to the nearest integer.
+	Burst int
+
+	// MinRequestsPerSecond is the lowest rate to which the policy will reduce
+	// RequestsPerSecond after being throttled.
+	// The default value is one tenth of RequestsPerSecond.
+	MinRequestsPerSecond float64
+
+	// PerHost indicates that each host has its own budget.
+	// By default, all requests sent through the policy share a single budget.
+	PerHost bool
+}
+
+// RateLimitPolicy limits the rate at which requests are sent using a token bucket.
+// Requests wait for a token to become available, or for their context to be cancelled.
+//
+// The policy adapts to the responses it observes. A 429 response, or a 503 response with
+// a Retry-After header, halves the rate and pauses all requests sharing the budget until
+// the Retry-After period has elapsed. The rate then recovers gradually with successful
+// responses. Values of x-ms-ratelimit-remaining-* response headers cap the number of
+// requests that can be sent at once.
+//
+// Add the policy to [policy.ClientOptions.PerRetryPolicies] so that each try is limited.
+// A single instance can be shared across clients to share a budget.
+type RateLimitPolicy struct {
+	// the following fields are read-only
+	maxRate float64
+	minRate float64
+	burst   float64
+	perHost bool
+	now     func() time.Time
+
+	bucketsMu sync.Mutex
+	buckets   map[string]*tokenBucket
+}
+
+// NewRateLimitPolicy creates a new instance of [RateLimitPolicy].
+//   - options contains optional configuration, pass nil to accept the default values
+func NewRateLimitPolicy(options *RateLimitPolicyOptions) *RateLimitPolicy {
+	if options == nil {
+		options = &RateLimitPolicyOptions{}
+	}
+	rl := &RateLimitPolicy{
+		maxRate: options.RequestsPerSecond,
+		minRate: options.MinRequestsPerSecond,
+		burst:   float64(options.Burst),
+		perHost: options.PerHost,
+		now:     time.Now,
+		buckets: map[string]*tokenBucket{},
+	}
+	if rl.maxRate <= 0 {
+		rl.maxRate = defaultRateLimitRequestsPerSecond
+	}
+	if rl.minRate <= 0 {
+		rl.minRate = rl.maxRate / 10
+	} else if rl.minRate > rl.maxRate {
+		rl.minRate = rl.maxRate
+	}
+	if rl.burst <= 0 {
+		rl.burst = math.Ceil(rl.maxRate)
+	}
+	return rl
+}
+
+// Do implements the Do method on the [policy.Policy] interface.
+func (rl *RateLimitPolicy) Do(req *policy.Request) (*http.Response, error) {
+	b := rl.bucketFor(req.Raw().URL.Host)
+
+	if wait := b.reserve(rl); wait > 0 {
+		log.Writef(log.EventRetryPolicy, "rate limit delaying request by %s", wait)
+		if err := shared.Delay(req.Raw().Context(), wait); err != nil {
+			b.cancel(rl)
+			return nil, err
+		}
+	}
+
+	resp, err := req.Next()
+	if err != nil {
+		return resp, err
+	}
+
+	if remaining, ok := rateLimitRemaining(resp); ok {
+		b.limit(rl, remaining)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode == http.StatusServiceUnavailable && shared.RetryAfter(resp) > 0) {
+		rate := b.throttle(rl, shared.RetryAfter(resp))
+		log.Writef(log.EventRetryPolicy, "rate limit reduced to %.2f requests per second", rate)
+	} else if resp.StatusCode < http.StatusBadRequest {
+		b.recover(rl)
+	}
+	return resp, nil
+}
+
+func (rl *RateLimitPolicy) bucketFor(host string) *tokenBucket {
+	key := ""
+	if rl.perHost {
+		key = strings.ToLower(host)
+	}
+	rl.bucketsMu.Lock()
+	defer rl.bucketsMu.Unlock()
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{rate: rl.maxRate, tokens: rl.burst, last: rl.now()}
+		rl.buckets[key] = b
+	}
+	return b
+}
+
+// rateLimitRemaining returns the smallest value of any x-ms-ratelimit-remaining-* header.
+// some of these headers contain a list of policy;count pairs.
+// e.g. x-ms-ratelimit-remaining-resource: Microsoft.Compute/HighCostGet3Min;107,Microsoft.Compute/HighCostGet30Min;567
+func rateLimitRemaining(resp *http.Response) (int, bool) {
+	remaining, found := math.MaxInt, false
+	for k, vv := range resp.Header {
+		if !strings.HasPrefix(strings.ToLower(k), headerXMSRateLimitRemainingPrefix) {
+			continue
+		}
+		for _, v := range vv {
+			for _, entry := range strings.Split(v, ",") {
+				if i := strings.LastIndex(entry, ";"); i > -1 {
+					entry = entry[i+1:]
+				}
+				n, err := strconv.Atoi(strings.TrimSpace(entry))
+				if err != nil || n < 0 {
+					continue
+				}
+				remaining, found = min(remaining, n), true
+			}
+		}
+	}
+	return remaining, found
+}
+
+// tokenBucket is the budget for requests sharing a rate limit
+type tokenBucket struct {
+	mu          sync.Mutex
+	rate        float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// refill must be called with mu held
+func (b *tokenBucket) refill(rl *RateLimitPolicy, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(rl.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// reserve takes a token from the bucket and returns how long the caller must wait before using it
+func (b *tokenBucket) reserve(rl *RateLimitPolicy) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := rl.now()
+	b.refill(rl, now)
+	b.tokens--
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if paused := b.pausedUntil.Sub(now); paused > wait {
+		wait = paused
+	}
+	return wait
+}
+
+// cancel returns a token that was reserved but not used
+func (b *tokenBucket) cancel(rl *RateLimitPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(rl, rl.now())
+	b.tokens = min(rl.burst, b.tokens+1)
+}
+
+// limit caps the available tokens to the number of requests the service will accept
+func (b *tokenBucket) limit(rl *RateLimitPolicy, remaining int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(rl, rl.now())
+	b.tokens = min(b.tokens, float64(remaining))
+}
+
+// throttle reduces the rate and pauses the bucket for the specified duration.
+// returns the new rate.
+func (b *tokenBucket) throttle(rl *RateLimitPolicy, pause time.Duration) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := rl.now()
+	b.refill(rl, now)
+	b.rate = max(rl.minRate, b.rate/2)
+	b.tokens = min(b.tokens, 0)
+	if until := now.Add(pause); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	return b.rate
+}
+
+// recover gradually restores the rate after throttling
+func (b *tokenBucket) recover(rl *RateLimitPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate < rl.maxRate {
+		b.refill(rl, rl.now())
+		b.rate = min(rl.maxRate, b.rate+rl.maxRate/20)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/shared"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitPolicyDefaults(t *testing.T) {
+	rl := NewRateLimitPolicy(nil)
+	require.EqualValues(t, defaultRateLimitRequestsPerSecond, rl.maxRate)
+	require.EqualValues(t, 1, rl.minRate)
+	require.EqualValues(t, defaultRateLimitRequestsPerSecond, rl.burst)
+	require.False(t, rl.perHost)
+
+	rl = NewRateLimitPolicy(&RateLimitPolicyOptions{RequestsPerSecond: 2.5, MinRequestsPerSecond: 5})
+	require.EqualValues(t, 2.5, rl.minRate)
+	require.EqualValues(t, 3, rl.burst)
+}
+
+func TestRateLimitPolicyReserve(t *testing.T) {
+	rl := NewRateLimitPolicy(&RateLimitPolicyOptions{RequestsPerSecond: 2, Burst: 2})
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+	b := rl.bucketFor("contoso.com")
+
+	require.Zero(t, b.reserve(rl))
+	require.Zero(t, b.reserve(rl))
+	require.Equal(t, 500*time.Millisecond, b.reserve(rl))
+	require.Equal(t, time.Second, b.reserve(rl))
+
+	// unused reservations are returned to the bucket
+	b.cancel(rl)
+	require.Equal(t, time.Second, b.reserve(rl))
+
+	now = now.Add(2 * time.Second)
+	require.Zero(t, b.reserve(rl))
+}
+
+func TestRateLimitPolicyThrottle(t *testing.T) {
+	rl := NewRateLimitPolicy(&RateLimitPolicyOptions{RequestsPerSecond: 8, MinRequestsPerSecond: 3})
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+	b := rl.bucketFor("contoso.com")
+
+	require.EqualValues(t, 4, b.throttle(rl, 5*time.Second))
+	require.Equal(t, 5*time.Second, b.reserve(rl))
+	require.EqualValues(t, 3, b.throttle(rl, 0))
+
+	for range 3 {
+		b.recover(rl)
+	}
+	require.EqualValues(t, 4.2, b.rate)
+	for range 20 {
+		b.recover(rl)
+	}
+	require.EqualValues(t, 8, b.rate)
+}
+
+func TestRateLimitPolicyRetryAfter(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusTooManyRequests), mock.WithHeader(shared.HeaderRetryAfterMS, "100"))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	rl := NewRateLimitPolicy(&RateLimitPolicyOptions{RequestsPerSecond: 1000})
+	pl := exported.NewPipeline(srv, rl)
+
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	start := time.Now()
+	req, err = NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err = pl.Do(req)
+	require.NoError(t, err)
+	require.EqualValues(t, http.StatusOK, resp.StatusCode)
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestRateLimitPolicyServiceUnavailable(t *testing.T) {
+	rl := NewRateLimitPolicy(&RateLimitPolicyOptions{RequestsPerSecond: 100})
+	for _, test := range []struct {
+		header   string
+		expected float64
+	}{
+		{expected: 100},
+		{header: "1", expected: 50},
+	} {
+		srv, close := mock.NewServer()
+		defer close()
+		if test.header == "" {
+			srv.SetResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+		} else {
+			srv.SetResponse(mock.WithStatusCode(http.StatusServiceUnavailable), mock.WithHeader(shared.HeaderRetryAfter, test.header))
+		}
+		req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+		require.NoError(t, err)
+		_, err = exported.NewPipeline(srv, rl).Do(req)
+		require.NoError(t, err)
+		require.EqualValues(t, test.expected, rl.bucketFor("").rate)
+	}
+}
+
+func TestRateLimitPolicyRemainingHeaders(t *testing.T) {
+	for _, test := range []struct {
+		headers  map[string]string
+		expected int
+		found    bool
+	}{
+		{},
+		{headers: map[string]string{"x-ms-ratelimit-remaining-subscription-reads": "11999"}, expected: 11999, found: true},
+		{
+			headers: map[string]string{
+				"x-ms-ratelimit-remaining-subscription-reads":  "11999",
+				"x-ms-ratelimit-remaining-subscription-writes": "1199",
+			},
+			expected: 1199,
+			found:    true,
+		},
+		{
+			headers:  map[string]string{"x-ms-ratelimit-remaining-resource": "Microsoft.Compute/HighCostGet3Min;107,Microsoft.Compute/HighCostGet30Min;567"},
+			expected: 107,
+			found:    true,
+		},
+		{headers: map[string]string{"x-ms-ratelimit-remaining-resource": "invalid"}},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		for k, v := range test.headers {
+			resp.Header.Set(k, v)
+		}
+		remaining, found := rateLimitRemaining(resp)
+		require.Equal(t, test.found, found)
+		if found {
+			require.Equal(t, test.expected, remaining)
+		}
+	}
+}
+
+func TestRateLimitPolicyLimitsBurst(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK), mock.WithHeader("x-ms-ratelimit-remaining-subscription-reads", "0"))
+	rl := NewRateLimitPolicy(&RateLimitPolicyOptions{RequestsPerSecond: 20, Burst: 20})
+	pl := exported.NewPipeline(srv, rl)
+
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+
+	// the service reported no remaining requests so the next request can't burst
+	start := time.Now()
+	req, err = NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestRateLimitPolicyPerHost(t *testing.T) {
+	rl := NewRateLimitPolicy(&RateLimitPolicyOptions{PerHost: true})
+	require.Same(t, rl.bucketFor("contoso.com"), rl.bucketFor("CONTOSO.com"))
+	require.NotSame(t, rl.bucketFor("contoso.com"), rl.bucketFor("fabrikam.com"))
+
+	rl = NewRateLimitPolicy(nil)
+	require.Same(t, rl.bucketFor("contoso.com"), rl.bucketFor("fabrikam.com"))
+}
+
+func TestRateLimitPolicyContextCancelled(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	rl := NewRateLimitPolicy(&RateLimitPolicyOptions{RequestsPerSecond: 0.1, Burst: 1})
+	pl := exported.NewPipeline(srv, rl)
+
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err = NewRequest(ctx, http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	_, err = pl.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.EqualValues(t, 1, srv.Requests())
+}
@@ -9,10 +9,13 @@ package runtime
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
@@ -58,7 +61,8 @@ func setDefaults(o *policy.RetryOptions) {
 	}
 }
 
-func calcDelay(o policy.RetryOptions, try int32) time.Duration { // try is >=1; never 0
+// exponentialDelay returns ((2 ^ try) - 1) * RetryDelay without jitter
+func exponentialDelay(o policy.RetryOptions, try int32) time.Duration { // try is >=1; never 0
 	// avoid overflow when shifting left
 	factor := time.Duration(math.MaxInt64)
 	if try < 63 {
@@ -66,10 +70,29 @@ func calcDelay(o policy.RetryOptions, try int32) time.Duration { // try is >=1;
 	}
 
 	delay := factor * o.RetryDelay
-	if delay < factor {
+	if o.RetryDelay > 0 && delay/o.RetryDelay != factor {
 		// overflow has happened so set to max value
 		delay = time.Duration(math.MaxInt64)
 	}
+	return delay
+}
+
+// nextDelay returns the delay before the next try using the backoff strategy in o.
+// prev is the delay before the current try, or RetryDelay for the first try.
+// o.Backoff has been validated by retryPolicy.Do; the zero value selects exponential.
+func nextDelay(o policy.RetryOptions, try int32, prev time.Duration) time.Duration {
+	switch o.Backoff {
+	case policy.RetryBackoffFullJitter:
+		return calcFullJitterDelay(o, try)
+	case policy.RetryBackoffDecorrelatedJitter:
+		return calcDecorrelatedJitterDelay(o, prev)
+	default: // "" and RetryBackoffExponential
+		return calcDelay(o, try)
+	}
+}
+
+func calcDelay(o policy.RetryOptions, try int32) time.Duration { // try is >=1; never 0
+	delay := exponentialDelay(o, try)
 
 	// Introduce jitter:  [0.0, 1.0) / 2 = [0.0, 0.5) + 0.8 = [0.8, 1.3)
 	jitterMultiplier := rand.Float64()/2 + 0.8 // NOTE: We want math/rand; not crypto/rand
@@ -89,18 +112,57 @@ func calcDelay(o policy.RetryOptions, try int32) time.Duration { // try is >=1;
 	return delay
 }
 
+// calcFullJitterDelay returns a random delay in [0, min(MaxRetryDelay, ((2 ^ try) - 1) * RetryDelay))
+func calcFullJitterDelay(o policy.RetryOptions, try int32) time.Duration {
+	ceiling := exponentialDelay(o, try)
+	if ceiling > o.MaxRetryDelay {
+		ceiling = o.MaxRetryDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// calcDecorrelatedJitterDelay returns a random delay in [RetryDelay, min(MaxRetryDelay, 3 * prev))
+func calcDecorrelatedJitterDelay(o policy.RetryOptions, prev time.Duration) time.Duration {
+	ceiling := prev * 3
+	if ceiling/3 != prev {
+		// overflow has happened so set to max value
+		ceiling = time.Duration(math.MaxInt64)
+	}
+	if ceiling > o.MaxRetryDelay {
+		ceiling = o.MaxRetryDelay
+	}
+	if ceiling <= o.RetryDelay {
+		return ceiling
+	}
+	return o.RetryDelay + time.Duration(rand.Int63n(int64(ceiling-o.RetryDelay)))
+}
+
 // NewRetryPolicy creates a policy object configured using the specified options.
 // Pass nil to accept the default values; this is the same as passing a zero-value options.
 func NewRetryPolicy(o *policy.RetryOptions) policy.Policy {
+	return newRetryPolicy(o, "")
+}
+
+// newRetryPolicy creates a retry policy that includes the namespace in its structured log entries.
+func newRetryPolicy(o *policy.RetryOptions, namespace string) policy.Policy {
 	if o == nil {
 		o = &policy.RetryOptions{}
 	}
-	p := &retryPolicy{options: *o}
+	p := &retryPolicy{options: *o, namespace: namespace}
+	if o.Budget != nil {
+		p.budget = newRetryBudget(o.Budget)
+	}
 	return p
 }
 
 type retryPolicy struct {
-	options policy.RetryOptions
+	options   policy.RetryOptions
+	namespace string
+	// budget is nil when the retry budget is disabled
+	budget *retryBudget
 }
 
 func (p *retryPolicy) Do(req *policy.Request) (resp *http.Response, err error) {
@@ -110,7 +172,12 @@ func (p *retryPolicy) Do(req *policy.Request) (resp *http.Response, err error) {
 		options = override.(policy.RetryOptions)
 	}
 	setDefaults(&options)
-	// Exponential retry algorithm: ((2 ^ attempt) - 1) * delay * random(0.8, 1.2)
+	switch options.Backoff {
+	case "", policy.RetryBackoffExponential, policy.RetryBackoffFullJitter, policy.RetryBackoffDecorrelatedJitter:
+	default:
+		return nil, fmt.Errorf("unknown retry backoff %q", options.Backoff)
+	}
+	// Default retry algorithm is exponential: ((2 ^ attempt) - 1) * delay * random(0.8, 1.3), see nextDelay for the others
 	// When to retry: connection failure or temporary/timeout.
 	var rwbody *retryableRequestBody
 	if req.Body() != nil {
@@ -119,11 +186,17 @@ func (p *retryPolicy) Do(req *policy.Request) (resp *http.Response, err error) {
 		rwbody = &retryableRequestBody{body: req.Body()}
 		defer rwbody.realClose()
 	}
+	if p.budget != nil {
+		p.budget.addRequest()
+	}
 	try := int32(1)
+	prevDelay := options.RetryDelay
 	for {
 		resp = nil // reset
 		// unfortunately we don't have access to the custom allow-list of query params, so we'll redact everything but the default allowed QPs
-		log.Writef(log.EventRetryPolicy, "=====> Try=%d for %s %s", try, req.Raw().Method, getSanitizedURL(*req.Raw().URL, getAllowedQueryParams(nil)))
+		p.log(req, try, func() string {
+			return fmt.Sprintf("=====> Try=%d for %s %s", try, req.Raw().Method, getSanitizedURL(*req.Raw().URL, getAllowedQueryParams(nil)))
+		}, "Starting try")
 
 		// For each try, seek to the beginning of the Body stream. We do this even for the 1st try because
 		// the stream may not be at offset 0 when we first get it and we want the same behavior for the
@@ -156,15 +229,18 @@ func (p *retryPolicy) Do(req *policy.Request) (resp *http.Response, err error) {
 			}
 		}
 		if err == nil {
-			log.Writef(log.EventRetryPolicy, "response %d", resp.StatusCode)
+			p.log(req, try, func() string { return fmt.Sprintf("response %d", resp.StatusCode) },
+				"Received response", slog.Int(attrHTTPResponseStatusCode, resp.StatusCode))
 		} else {
-			log.Writef(log.EventRetryPolicy, "error %v", err)
+			p.log(req, try, func() string { return fmt.Sprintf("error %v", err) },
+				"Request failed", slog.Any(attrError, err))
 		}
 
 		if ctxErr := req.Raw().Context().Err(); ctxErr != nil {
 			// don't retry if the parent context has been cancelled or its deadline exceeded
 			err = ctxErr
-			log.Writef(log.EventRetryPolicy, "abort due to %v", err)
+			p.log(req, try, func() string { return fmt.Sprintf("abort due to %v", err) },
+				"Request aborted", slog.Any(attrError, err))
 			return
 		}
 
@@ -172,7 +248,8 @@ func (p *retryPolicy) Do(req *policy.Request) (resp *http.Response, err error) {
 		var nre errorinfo.NonRetriable
 		if errors.As(err, &nre) {
 			// the error says it's not retriable so don't retry
-			log.Writef(log.EventRetryPolicy, "non-retriable error %T", nre)
+			p.log(req, try, func() string { return fmt.Sprintf("non-retriable error %T", nre) },
+				"Request won't be retried, the error is non-retriable", slog.String(attrErrorType, fmt.Sprintf("%T", nre)))
 			return
 		}
 
@@ -180,46 +257,74 @@ func (p *retryPolicy) Do(req *policy.Request) (resp *http.Response, err error) {
 			// a non-nil ShouldRetry overrides our HTTP status code check
 			if !options.ShouldRetry(resp, err) {
 				// predicate says we shouldn't retry
-				log.Write(log.EventRetryPolicy, "exit due to ShouldRetry")
+				p.log(req, try, func() string { return "exit due to ShouldRetry" },
+					"Request won't be retried, ShouldRetry returned false")
 				return
 			}
 		} else if err == nil && !HasStatusCode(resp, options.StatusCodes...) {
 			// if there is no error and the response code isn't in the list of retry codes then we're done.
-			log.Write(log.EventRetryPolicy, "exit due to non-retriable status code")
+			p.log(req, try, func() string { return "exit due to non-retriable status code" },
+				"Request won't be retried, the status code is non-retriable", slog.Int(attrHTTPResponseStatusCode, resp.StatusCode))
 			return
 		}
 
 		if try == options.MaxRetries+1 {
 			// max number of tries has been reached, don't sleep again
-			log.Writef(log.EventRetryPolicy, "MaxRetries %d exceeded", options.MaxRetries)
+			p.log(req, try, func() string { return fmt.Sprintf("MaxRetries %d exceeded", options.MaxRetries) },
+				"Request won't be retried, MaxRetries exceeded", slog.Int(attrMaxRetries, int(options.MaxRetries)))
 			return
 		}
 
 		// use the delay from retry-after if available
 		delay := shared.RetryAfter(resp)
 		if delay <= 0 {
-			delay = calcDelay(options, try)
+			delay = nextDelay(options, try, prevDelay)
 		} else if delay > options.MaxRetryDelay {
 			// the retry-after delay exceeds the the cap so don't retry
-			log.Writef(log.EventRetryPolicy, "Retry-After delay %s exceeds MaxRetryDelay of %s", delay, options.MaxRetryDelay)
+			p.log(req, try, func() string {
+				return fmt.Sprintf("Retry-After delay %s exceeds MaxRetryDelay of %s", delay, options.MaxRetryDelay)
+			}, "Request won't be retried, Retry-After exceeds MaxRetryDelay", slog.Duration(attrRetryDelay, delay), slog.Duration(attrMaxRetryDelay, options.MaxRetryDelay))
+			return
+		}
+		prevDelay = delay
+
+		if p.budget != nil && !p.budget.withdraw() {
+			// the client has sent its share of retries, don't add to the load on the service
+			p.log(req, try, func() string { return "exit due to exhausted retry budget" },
+				"Request won't be retried, the retry budget is exhausted")
 			return
 		}
 
 		// drain before retrying so nothing is leaked
 		Drain(resp)
 
-		log.Writef(log.EventRetryPolicy, "End Try #%d, Delay=%v", try, delay)
+		p.log(req, try, func() string { return fmt.Sprintf("End Try #%d, Delay=%v", try, delay) },
+			"Retrying request", slog.Duration(attrRetryDelay, delay))
 		select {
 		case <-time.After(delay):
 			try++
 		case <-req.Raw().Context().Done():
 			err = req.Raw().Context().Err()
-			log.Writef(log.EventRetryPolicy, "abort due to %v", err)
+			p.log(req, try, func() string { return fmt.Sprintf("abort due to %v", err) },
+				"Request aborted", slog.Any(attrError, err))
 			return
 		}
 	}
 }
 
+// log writes the text returned by text to the log listener, and an entry
+// with msg and the request's attributes followed by attrs to the slog logger.
+func (p *retryPolicy) log(req *policy.Request, try int32, text func() string, msg string, attrs ...slog.Attr) {
+	if log.ShouldListener(log.EventRetryPolicy) {
+		log.WriteListener(log.EventRetryPolicy, text())
+	}
+	ctx := req.Raw().Context()
+	if log.ShouldAttrs(ctx, log.EventRetryPolicy) {
+		// unfortunately we don't have access to the custom allow-list of query params, so we'll redact everything but the default allowed QPs
+		log.WriteAttrs(ctx, log.EventRetryPolicy, msg, append(requestLogAttrs(req, try, p.namespace, getAllowedQueryParams(nil)), attrs...)...)
+	}
+}
+
 // WithRetryOptions adds the specified RetryOptions to the parent context.
 // Use this to specify custom RetryOptions at the API-call level.
 // Deprecated: use [policy.WithRetryOptions] instead.
@@ -274,3 +379,85 @@ func (rc *contextCancelReadCloser) Close() error {
 	rc.cf()
 	return err
 }
+
+const (
+	// retryBudgetWindow is the number of seconds of traffic considered by a retry budget
+	retryBudgetWindow = 10
+
+	defaultRetryBudgetRatio               = 0.1
+	defaultRetryBudgetMinRetriesPerSecond = 10
+)
+
+// retryBudget limits the retries of a client to a share of the requests it sent in the last
+// retryBudgetWindow seconds, plus a minimum number of retries per second. It's safe for concurrent use.
+type retryBudget struct {
+	mu         sync.Mutex
+	ratio      float64
+	minRetries int
+	// buckets contains the counts for each second of the window
+	buckets [retryBudgetWindow]retryBudgetBucket
+	now     func() time.Time
+}
+
+// retryBudgetBucket contains the counts for one second
+type retryBudgetBucket struct {
+	second   int64
+	requests int
+	retries  int
+}
+
+func newRetryBudget(o *policy.RetryBudgetOptions) *retryBudget {
+	b := &retryBudget{
+		ratio:      o.Ratio,
+		minRetries: o.MinRetriesPerSecond * retryBudgetWindow,
+		now:        time.Now,
+	}
+	if b.ratio == 0 {
+		b.ratio = defaultRetryBudgetRatio
+	} else if b.ratio < 0 {
+		b.ratio = 0
+	}
+	if o.MinRetriesPerSecond == 0 {
+		b.minRetries = defaultRetryBudgetMinRetriesPerSecond * retryBudgetWindow
+	} else if o.MinRetriesPerSecond < 0 {
+		b.minRetries = 0
+	}
+	return b
+}
+
+// bucket returns the current second's bucket, resetting it when it contains counts from an earlier window.
+// Callers must hold the lock.
+func (b *retryBudget) bucket() *retryBudgetBucket {
+	second := b.now().Unix()
+	bucket := &b.buckets[second%retryBudgetWindow]
+	if bucket.second != second {
+		*bucket = retryBudgetBucket{second: second}
+	}
+	return bucket
+}
+
+// addRequest records a request's first try
+func (b *retryBudget) addRequest() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bucket().requests++
+}
+
+// withdraw records a retry and returns true if the budget allows it
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current := b.bucket()
+	requests, retries := 0, 0
+	for _, bucket := range b.buckets {
+		if current.second-bucket.second < retryBudgetWindow {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+	if float64(retries) >= b.ratio*float64(requests)+float64(b.minRetries) {
+		return false
+	}
+	current.retries++
+	return true
+}
@@ -950,6 +950,19 @@ func TestCalcDelay(t *testing.T) {
 		}
 	})
 
+	t.Run("no delay", func(t *testing.T) {
+		// a negative RetryDelay is set to zero by setDefaults, which must not be mistaken for an overflow
+		retryOptions := policy.RetryOptions{
+			RetryDelay: -1,
+		}
+		setDefaults(&retryOptions)
+
+		for i := int32(1); i < 100; i++ {
+			require.Zero(t, exponentialDelay(retryOptions, i))
+			require.Zero(t, calcDelay(retryOptions, i))
+		}
+	})
+
 	t.Run("overflow", func(t *testing.T) {
 		retryOptions := policy.RetryOptions{
 			RetryDelay:    1,
@@ -1010,3 +1023,135 @@ func TestRetryPolicyWithLoggingChecker(t *testing.T) {
 	require.EqualValues(t, 2, body.rcount)
 	require.True(t, body.closed)
 }
+
+func TestNextDelayBackoffStrategies(t *testing.T) {
+	retryOptions := policy.RetryOptions{
+		RetryDelay:    time.Second,
+		MaxRetryDelay: 30 * time.Second,
+		Backoff:       policy.RetryBackoffFullJitter,
+	}
+	setDefaults(&retryOptions)
+	for i := int32(1); i < 100; i++ {
+		delay := nextDelay(retryOptions, i, 0)
+		require.GreaterOrEqual(t, delay, time.Duration(0))
+		require.Less(t, delay, exponentialDelay(retryOptions, i))
+		require.Less(t, delay, retryOptions.MaxRetryDelay)
+	}
+
+	retryOptions.Backoff = policy.RetryBackoffDecorrelatedJitter
+	prev := retryOptions.RetryDelay
+	for i := int32(1); i < 100; i++ {
+		delay := nextDelay(retryOptions, i, prev)
+		require.GreaterOrEqual(t, delay, retryOptions.RetryDelay)
+		require.LessOrEqual(t, delay, 3*prev)
+		require.LessOrEqual(t, delay, retryOptions.MaxRetryDelay)
+		prev = delay
+	}
+	// overflow
+	require.Equal(t, retryOptions.MaxRetryDelay, calcDecorrelatedJitterDelay(policy.RetryOptions{RetryDelay: retryOptions.MaxRetryDelay, MaxRetryDelay: retryOptions.MaxRetryDelay}, math.MaxInt64))
+
+	// no delay
+	retryOptions = policy.RetryOptions{RetryDelay: -1}
+	setDefaults(&retryOptions)
+	for _, backoff := range []policy.RetryBackoff{policy.RetryBackoffExponential, policy.RetryBackoffFullJitter, policy.RetryBackoffDecorrelatedJitter} {
+		retryOptions.Backoff = backoff
+		require.Zero(t, nextDelay(retryOptions, 3, retryOptions.RetryDelay), backoff)
+	}
+}
+
+func TestRetryPolicyBackoffStrategy(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK))
+	pl := exported.NewPipeline(srv, NewRetryPolicy(&policy.RetryOptions{
+		Backoff:       policy.RetryBackoffDecorrelatedJitter,
+		RetryDelay:    time.Millisecond,
+		MaxRetryDelay: 5 * time.Millisecond,
+	}))
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 3, srv.Requests())
+}
+
+func TestRetryPolicyUnknownBackoff(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusOK))
+	pl := exported.NewPipeline(srv, NewRetryPolicy(&policy.RetryOptions{Backoff: "linear"}))
+	req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.Nil(t, resp)
+	require.EqualError(t, err, `unknown retry backoff "linear"`)
+	require.Zero(t, srv.Requests())
+}
+
+func TestRetryPolicyBudget(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.SetResponse(mock.WithStatusCode(http.StatusServiceUnavailable))
+	pl := exported.NewPipeline(srv, NewRetryPolicy(&policy.RetryOptions{
+		RetryDelay: time.Nanosecond,
+		Budget:     &policy.RetryBudgetOptions{Ratio: 0.5, MinRetriesPerSecond: -1},
+	}))
+
+	// the budget is shared by all requests
+	for i := 0; i < 4; i++ {
+		req, err := NewRequest(context.Background(), http.MethodGet, srv.URL())
+		require.NoError(t, err)
+		resp, err := pl.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	// 4 requests allow 2 retries, which are sent for the first request
+	require.Equal(t, 6, srv.Requests())
+
+	// requests with overridden retry options are limited by the client's budget
+	req, err := NewRequest(policy.WithRetryOptions(context.Background(), policy.RetryOptions{RetryDelay: time.Nanosecond}), http.MethodGet, srv.URL())
+	require.NoError(t, err)
+	resp, err := pl.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	// 5 requests allow one more retry instead of the 3 retries the options allow
+	require.Equal(t, 8, srv.Requests())
+}
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newRetryBudget(&policy.RetryBudgetOptions{Ratio: 0.2, MinRetriesPerSecond: 1})
+	b.now = func() time.Time { return now }
+	// the minimum allows 10 retries in the window without requests
+	for i := 0; i < retryBudgetWindow; i++ {
+		require.True(t, b.withdraw())
+	}
+	require.False(t, b.withdraw())
+
+	// every 5 requests allow another retry
+	for i := 0; i < 5; i++ {
+		b.addRequest()
+	}
+	require.True(t, b.withdraw())
+	require.False(t, b.withdraw())
+
+	// counts expire after the window
+	now = now.Add(retryBudgetWindow * time.Second)
+	require.True(t, b.withdraw())
+	now = now.Add(time.Second)
+	for i := 0; i < retryBudgetWindow-1; i++ {
+		require.True(t, b.withdraw())
+	}
+	require.False(t, b.withdraw())
+
+	// defaults
+	b = newRetryBudget(&policy.RetryBudgetOptions{})
+	require.Equal(t, defaultRetryBudgetRatio, b.ratio)
+	require.Equal(t, defaultRetryBudgetMinRetriesPerSecond*retryBudgetWindow, b.minRetries)
+	b = newRetryBudget(&policy.RetryBudgetOptions{Ratio: -1, MinRetriesPerSecond: -1})
+	b.addRequest()
+	require.False(t, b.withdraw())
+}
@@ -214,6 +214,11 @@ type Poller[T any] struct {
 	result *T
 	tracer tracing.Tracer
 	done   bool
+
+	// afterPoll, when set, is called after each successful call to Poll.
+	// it's used by TrackPoller to persist the poller's state. it can't fail
+	// Poll because the poller's state has already been updated.
+	afterPoll func(context.Context)
 }
 
 // PollUntilDoneOptions contains the optional values for the Poller[T].PollUntilDone() method.
@@ -307,6 +312,9 @@ func (p *Poller[T]) Poll(ctx context.Context) (resp *http.Response, err error) {
 		return
 	}
 	p.resp = resp
+	if p.afterPoll != nil {
+		p.afterPoll(ctx)
+	}
 	return
 }
 
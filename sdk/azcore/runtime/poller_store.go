@@ -0,0 +1,320 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/exported"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/log"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/internal/pollers"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/tracing"
+)
+
+// PollerStore persists the resume tokens of in-flight pollers, allowing them
+// to be resumed after the process restarts. See [TrackPoller] and [ResumePollers].
+// Implementations must be safe for concurrent use.
+type PollerStore interface {
+	// Save stores the resume token for the poller with the specified ID, replacing any existing token.
+	Save(ctx context.Context, id, token string) error
+
+	// Load returns the resume token for the poller with the specified ID.
+	// If there's no token for the ID, the returned error wraps [fs.ErrNotExist].
+	Load(ctx context.Context, id string) (string, error)
+
+	// Delete removes the resume token for the poller with the specified ID.
+	// Deleting an ID that doesn't exist isn't an error.
+	Delete(ctx context.Context, id string) error
+
+	// List returns the IDs of all pollers in the store.
+	List(ctx context.Context) ([]string, error)
+}
+
+// InMemoryPollerStore is a [PollerStore] that keeps resume tokens in memory.
+// It doesn't survive process restarts and is intended for testing.
+// Don't use this type directly, use [NewInMemoryPollerStore] instead.
+type InMemoryPollerStore struct {
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewInMemoryPollerStore creates a new instance of [InMemoryPollerStore].
+func NewInMemoryPollerStore() *InMemoryPollerStore {
+	return &InMemoryPollerStore{tokens: map[string]string{}}
+}
+
+// Save implements the [PollerStore] interface for [InMemoryPollerStore].
+func (s *InMemoryPollerStore) Save(ctx context.Context, id, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[id] = token
+	return nil
+}
+
+// Load implements the [PollerStore] interface for [InMemoryPollerStore].
+func (s *InMemoryPollerStore) Load(ctx context.Context, id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[id]
+	if !ok {
+		return "", fmt.Errorf("no resume token for poller %q: %w", id, fs.ErrNotExist)
+	}
+	return token, nil
+}
+
+// Delete implements the [PollerStore] interface for [InMemoryPollerStore].
+func (s *InMemoryPollerStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, id)
+	return nil
+}
+
+// List implements the [PollerStore] interface for [InMemoryPollerStore].
+func (s *InMemoryPollerStore) List(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.tokens))
+	for id := range s.tokens {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// filePollerStoreExt is the file extension for the files created by FilePollerStore
+const filePollerStoreExt = ".poller.json"
+
+// filePollerEntry is the content of a file created by FilePollerStore
+type filePollerEntry struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+// FilePollerStore is a [PollerStore] that keeps each resume token in a file within a directory.
+// Files are replaced atomically, so a crash during Save leaves the previous token intact.
+// Don't use this type directly, use [NewFilePollerStore] instead.
+type FilePollerStore struct {
+	dir string
+}
+
+// NewFilePollerStore creates a new instance of [FilePollerStore].
+//   - dir is the directory containing the files. It's created if it doesn't exist
+func NewFilePollerStore(dir string) (*FilePollerStore, error) {
+	if dir == "" {
+		return nil, errors.New("dir can't be empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FilePollerStore{dir: dir}, nil
+}
+
+// path returns the file path for the specified ID. the ID is hashed
+// as it can contain characters that aren't valid in file names.
+func (s *FilePollerStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+filePollerStoreExt)
+}
+
+// Save implements the [PollerStore] interface for [FilePollerStore].
+func (s *FilePollerStore) Save(ctx context.Context, id, token string) error {
+	content, err := json.Marshal(filePollerEntry{ID: id, Token: token})
+	if err != nil {
+		return err
+	}
+	// write to a temp file then rename it so the existing token is replaced atomically
+	f, err := os.CreateTemp(s.dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path(id))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Load implements the [PollerStore] interface for [FilePollerStore].
+func (s *FilePollerStore) Load(ctx context.Context, id string) (string, error) {
+	entry, err := s.read(s.path(id))
+	if err != nil {
+		return "", err
+	}
+	return entry.Token, nil
+}
+
+// Delete implements the [PollerStore] interface for [FilePollerStore].
+func (s *FilePollerStore) Delete(ctx context.Context, id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List implements the [PollerStore] interface for [FilePollerStore].
+func (s *FilePollerStore) List(ctx context.Context) ([]string, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), filePollerStoreExt) {
+			continue
+		}
+		entry, err := s.read(filepath.Join(s.dir, file.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			// deleted after the directory was read
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ids = append(ids, entry.ID)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *FilePollerStore) read(path string) (filePollerEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return filePollerEntry{}, err
+	}
+	var entry filePollerEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return filePollerEntry{}, fmt.Errorf("invalid poller file %s: %w", path, err)
+	}
+	return entry, nil
+}
+
+// TrackPollerOptions contains the optional values for [TrackPoller].
+type TrackPollerOptions struct {
+	// OnSaveError is called when saving or deleting the poller's resume token fails after a
+	// call to [Poller.Poll]. The default value is nil, in which case the failure is only logged.
+	OnSaveError func(id string, err error)
+}
+
+// TrackPoller persists the state of the poller in the store under the specified ID.
+// The resume token is saved immediately and after each successful call to [Poller.Poll],
+// including the calls made by [Poller.PollUntilDone]. Once the poller reaches a terminal
+// state, its token is deleted from the store.
+//
+// A failure to save the initial token is returned by TrackPoller. Failures after Poll don't
+// fail Poll, because the poller's state has already been updated and the LRO continues
+// regardless. They're logged and passed to [TrackPollerOptions.OnSaveError], and the store
+// keeps the previous token until a later save succeeds.
+//   - ctx is the [context.Context] for the initial save
+//   - id uniquely identifies the poller within the store
+//   - poller is the [Poller] to track
+//   - store is the [PollerStore] in which the resume token is saved
+//   - options contains optional configuration, pass nil to accept the default values
+func TrackPoller[T any](ctx context.Context, id string, poller *Poller[T], store PollerStore, options *TrackPollerOptions) error {
+	if id == "" {
+		return errors.New("id can't be empty")
+	}
+	if options == nil {
+		options = &TrackPollerOptions{}
+	}
+	poller.afterPoll = afterPollSave(id, poller, store, options.OnSaveError)
+	return savePollerState(ctx, id, poller, store)
+}
+
+// afterPollSave returns a Poller.afterPoll func that saves the poller's state,
+// reporting failures to onSaveError when it isn't nil
+func afterPollSave[T any](id string, poller *Poller[T], store PollerStore, onSaveError func(string, error)) func(context.Context) {
+	return func(ctx context.Context) {
+		if err := savePollerState(ctx, id, poller, store); err != nil {
+			log.Writef(log.EventLRO, "Failed to save the state of poller %s: %v", id, err)
+			if onSaveError != nil {
+				onSaveError(id, err)
+			}
+		}
+	}
+}
+
+// savePollerState saves the poller's resume token, or deletes it once the poller is done
+func savePollerState[T any](ctx context.Context, id string, poller *Poller[T], store PollerStore) error {
+	if poller.Done() {
+		log.Writef(log.EventLRO, "Poller %s reached a terminal state, deleting its resume token", id)
+		return store.Delete(ctx, id)
+	}
+	token, err := poller.ResumeToken()
+	if err != nil {
+		return err
+	}
+	return store.Save(ctx, id, token)
+}
+
+// ResumePollersOptions contains the optional values for [ResumePollers].
+type ResumePollersOptions struct {
+	// OnSaveError is called when saving or deleting a resumed poller's resume token fails.
+	// See [TrackPollerOptions.OnSaveError].
+	OnSaveError func(id string, err error)
+
+	// Tracer contains the Tracer from the client that's resuming the Pollers.
+	Tracer tracing.Tracer
+}
+
+// ResumePollers creates a [Poller] for each resume token in the store that's applicable
+// for type T, and tracks it as if it had been passed to [TrackPoller]. Tokens for other
+// types are left untouched so they can be resumed by calling ResumePollers with the
+// matching type. The returned map is keyed by poller ID.
+//   - ctx is the [context.Context] for reading the store
+//   - store is the [PollerStore] containing the resume tokens
+//   - pl is the [Pipeline] used by the pollers
+//   - options contains optional configuration, pass nil to accept the default values
+func ResumePollers[T any](ctx context.Context, store PollerStore, pl exported.Pipeline, options *ResumePollersOptions) (map[string]*Poller[T], error) {
+	if options == nil {
+		options = &ResumePollersOptions{}
+	}
+	ids, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resumed := map[string]*Poller[T]{}
+	for _, id := range ids {
+		token, err := store.Load(ctx, id)
+		if errors.Is(err, fs.ErrNotExist) {
+			// deleted after the store was listed
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if pollers.IsTokenValid[T](token) != nil {
+			// the token belongs to a poller of another type
+			continue
+		}
+		poller, err := NewPollerFromResumeToken[T](token, pl, &NewPollerFromResumeTokenOptions[T]{
+			Tracer: options.Tracer,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resume poller %s: %w", id, err)
+		}
+		poller.afterPoll = afterPollSave(id, poller, store, options.OnSaveError)
+		log.Writef(log.EventLRO, "Resumed poller %s", id)
+		resumed[id] = poller
+	}
+	return resumed, nil
+}
@@ -0,0 +1,212 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollerStores(t *testing.T) {
+	fileStore, err := NewFilePollerStore(filepath.Join(t.TempDir(), "pollers"))
+	require.NoError(t, err)
+	for name, store := range map[string]PollerStore{
+		"file":     fileStore,
+		"inMemory": NewInMemoryPollerStore(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			ids, err := store.List(ctx)
+			require.NoError(t, err)
+			require.Empty(t, ids)
+
+			_, err = store.Load(ctx, "missing")
+			require.ErrorIs(t, err, fs.ErrNotExist)
+			require.NoError(t, store.Delete(ctx, "missing"))
+
+			require.NoError(t, store.Save(ctx, "sub/rg:deployment", "token1"))
+			require.NoError(t, store.Save(ctx, "other", "token2"))
+			require.NoError(t, store.Save(ctx, "sub/rg:deployment", "token3"))
+			token, err := store.Load(ctx, "sub/rg:deployment")
+			require.NoError(t, err)
+			require.Equal(t, "token3", token)
+
+			ids, err = store.List(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"other", "sub/rg:deployment"}, ids)
+
+			require.NoError(t, store.Delete(ctx, "other"))
+			ids, err = store.List(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"sub/rg:deployment"}, ids)
+		})
+	}
+}
+
+func TestNewFilePollerStore(t *testing.T) {
+	_, err := NewFilePollerStore("")
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	store, err := NewFilePollerStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(context.Background(), "id", "token"))
+	// unrelated files are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("hello"), 0600))
+
+	// a new instance over the same directory sees the existing tokens
+	store, err = NewFilePollerStore(dir)
+	require.NoError(t, err)
+	ids, err := store.List(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"id"}, ids)
+	token, err := store.Load(context.Background(), "id")
+	require.NoError(t, err)
+	require.Equal(t, "token", token)
+}
+
+func newTestLocPoller(t *testing.T, pl Pipeline, pollingURL string) *Poller[widget] {
+	resp := &http.Response{
+		StatusCode: http.StatusAccepted,
+		Header: http.Header{
+			"Location": []string{pollingURL},
+		},
+		Body: http.NoBody,
+	}
+	poller, err := NewPoller[widget](resp, pl, nil)
+	require.NoError(t, err)
+	return poller
+}
+
+func TestTrackPoller(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusAccepted))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"size": 3}`)))
+	pl := newTestPipeline(&policy.ClientOptions{Transport: srv})
+
+	ctx := context.Background()
+	store := NewInMemoryPollerStore()
+	poller := newTestLocPoller(t, pl, srv.URL())
+	require.Error(t, TrackPoller(ctx, "", poller, store, nil))
+	require.NoError(t, TrackPoller(ctx, "widget", poller, store, nil))
+
+	token, err := store.Load(ctx, "widget")
+	require.NoError(t, err)
+	expected, err := poller.ResumeToken()
+	require.NoError(t, err)
+	require.Equal(t, expected, token)
+
+	_, err = poller.Poll(ctx)
+	require.NoError(t, err)
+	require.False(t, poller.Done())
+	_, err = store.Load(ctx, "widget")
+	require.NoError(t, err)
+
+	w, err := poller.PollUntilDone(ctx, &PollUntilDoneOptions{Frequency: time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, 3, w.Size)
+	_, err = store.Load(ctx, "widget")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+type errorPollerStore struct {
+	*InMemoryPollerStore
+	err error
+}
+
+func (e *errorPollerStore) Save(ctx context.Context, id, token string) error {
+	return e.err
+}
+
+func TestTrackPollerSaveError(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusAccepted))
+	pl := newTestPipeline(&policy.ClientOptions{Transport: srv})
+
+	ctx := context.Background()
+	store := &errorPollerStore{InMemoryPollerStore: NewInMemoryPollerStore()}
+	poller := newTestLocPoller(t, pl, srv.URL())
+	store.err = errors.New("disk full")
+	require.ErrorIs(t, TrackPoller(ctx, "widget", poller, store, nil), store.err)
+
+	var saveErrs []error
+	store.err = nil
+	require.NoError(t, TrackPoller(ctx, "widget", poller, store, &TrackPollerOptions{
+		OnSaveError: func(id string, err error) {
+			require.Equal(t, "widget", id)
+			saveErrs = append(saveErrs, err)
+		},
+	}))
+
+	// a failure to save after polling doesn't fail the poll
+	store.err = errors.New("disk full")
+	resp, err := poller.Poll(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Len(t, saveErrs, 1)
+	require.ErrorIs(t, saveErrs[0], store.err)
+}
+
+func TestResumePollers(t *testing.T) {
+	srv, close := mock.NewServer()
+	defer close()
+	srv.AppendResponse(mock.WithStatusCode(http.StatusAccepted))
+	srv.AppendResponse(mock.WithStatusCode(http.StatusOK), mock.WithBody([]byte(`{"size": 5}`)))
+	pl := newTestPipeline(&policy.ClientOptions{Transport: srv})
+
+	ctx := context.Background()
+	store, err := NewFilePollerStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, TrackPoller(ctx, "first", newTestLocPoller(t, pl, srv.URL()), store, nil))
+	require.NoError(t, TrackPoller(ctx, "second", newTestLocPoller(t, pl, srv.URL()+"/second"), store, nil))
+	otherResp := &http.Response{
+		StatusCode: http.StatusAccepted,
+		Header:     http.Header{"Location": []string{srv.URL()}},
+		Body:       http.NoBody,
+	}
+	other, err := NewPoller[none](otherResp, pl, nil)
+	require.NoError(t, err)
+	require.NoError(t, TrackPoller(ctx, "other", other, store, nil))
+
+	// simulate a process restart; the pollers are resumed from the store
+	resumed, err := ResumePollers[widget](ctx, store, pl, nil)
+	require.NoError(t, err)
+	require.Len(t, resumed, 2)
+	require.Contains(t, resumed, "first")
+	require.Contains(t, resumed, "second")
+
+	w, err := resumed["first"].PollUntilDone(ctx, &PollUntilDoneOptions{Frequency: time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, 5, w.Size)
+
+	ids, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"other", "second"}, ids)
+
+	resumedOther, err := ResumePollers[none](ctx, store, pl, nil)
+	require.NoError(t, err)
+	require.Len(t, resumedOther, 1)
+	require.Contains(t, resumedOther, "other")
+}
+
+func TestResumePollersInvalidToken(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryPollerStore()
+	require.NoError(t, store.Save(ctx, "bad", `{"type":"widget","token":{"unknown":true}}`))
+	_, err := ResumePollers[widget](ctx, store, newTestPipeline(nil), nil)
+	require.ErrorContains(t, err, "bad")
+}
@@ -21,7 +21,16 @@ import (
 // Payload reads and returns the response body or an error.
 // On a successful read, the response body is cached.
 // Subsequent reads will access the cached value.
+// Reading a body larger than the maximum size configured with the
+// MaxResponseBodySize field of policy.ClientOptions returns a [*ResponseBodyTooLargeError].
 func Payload(resp *http.Response) ([]byte, error) {
+	if max := maxResponseBodySize(resp); max > 0 && resp.Body != nil && !exported.PayloadDownloaded(resp) {
+		if resp.ContentLength > max {
+			resp.Body.Close()
+			return nil, &ResponseBodyTooLargeError{MaxSize: max}
+		}
+		resp.Body = &limitedBody{body: resp.Body, max: max, remaining: max}
+	}
 	return exported.Payload(resp, nil)
 }
 
@@ -0,0 +1,439 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// TraceFlags contains the flags of a trace, as defined by the W3C Trace Context specification.
+type TraceFlags byte
+
+const (
+	// TraceFlagsSampled indicates the caller may have recorded the trace.
+	TraceFlagsSampled TraceFlags = 0x01
+)
+
+// SpanContext contains the identifying trace information of a [Span].
+type SpanContext struct {
+	// TraceID is the ID of the trace the span belongs to.
+	TraceID [16]byte
+
+	// SpanID is the ID of the span.
+	SpanID [8]byte
+
+	// TraceFlags contains the flags of the trace, such as [TraceFlagsSampled].
+	TraceFlags TraceFlags
+
+	// TraceState contains vendor-specific trace information in the format of the W3C tracestate header.
+	TraceState string
+
+	// Remote is true when the SpanContext was extracted from an incoming request or message.
+	Remote bool
+}
+
+// IsValid returns true if the SpanContext has a nonzero trace ID and span ID.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// IsSampled returns true if the [TraceFlagsSampled] flag is set.
+func (sc SpanContext) IsSampled() bool {
+	return sc.TraceFlags&TraceFlagsSampled != 0
+}
+
+type ctxSpanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx containing sc.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, ctxSpanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the [SpanContext] in ctx.
+// A zero-value SpanContext is returned when ctx contains none.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(ctxSpanContextKey{}).(SpanContext)
+	return sc
+}
+
+// Baggage contains application-defined key-value pairs that are propagated along with the trace context.
+type Baggage map[string]string
+
+type ctxBaggageKey struct{}
+
+// ContextWithBaggage returns a copy of ctx containing b.
+func ContextWithBaggage(ctx context.Context, b Baggage) context.Context {
+	return context.WithValue(ctx, ctxBaggageKey{}, b)
+}
+
+// BaggageFromContext returns the [Baggage] in ctx or nil.
+func BaggageFromContext(ctx context.Context) Baggage {
+	b, _ := ctx.Value(ctxBaggageKey{}).(Baggage)
+	return b
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Carrier stores the values written and read by a [Propagator], e.g. the headers of an HTTP request.
+type Carrier interface {
+	// Get returns the value for the specified key or the empty string.
+	Get(key string) string
+
+	// Set stores the value for the specified key, replacing any existing value.
+	Set(key, value string)
+
+	// Keys returns the keys in the carrier.
+	Keys() []string
+}
+
+// HTTPHeaderCarrier adapts an [http.Header] to the [Carrier] interface.
+type HTTPHeaderCarrier http.Header
+
+// Get implements the [Carrier] interface for [HTTPHeaderCarrier].
+func (h HTTPHeaderCarrier) Get(key string) string {
+	return http.Header(h).Get(key)
+}
+
+// Set implements the [Carrier] interface for [HTTPHeaderCarrier].
+func (h HTTPHeaderCarrier) Set(key, value string) {
+	http.Header(h).Set(key, value)
+}
+
+// Keys implements the [Carrier] interface for [HTTPHeaderCarrier].
+func (h HTTPHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MapCarrier adapts a map to the [Carrier] interface, e.g. the application properties of an AMQP message.
+// Only string values are returned by Get.
+type MapCarrier map[string]any
+
+// Get implements the [Carrier] interface for [MapCarrier].
+func (m MapCarrier) Get(key string) string {
+	v, _ := m[key].(string)
+	return v
+}
+
+// Set implements the [Carrier] interface for [MapCarrier].
+func (m MapCarrier) Set(key, value string) {
+	m[key] = value
+}
+
+// Keys implements the [Carrier] interface for [MapCarrier].
+func (m MapCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Propagator writes the trace context to, and reads it from, a [Carrier].
+// Implementations must be safe for concurrent use.
+type Propagator interface {
+	// Inject writes the [SpanContext] and [Baggage] in ctx to carrier.
+	Inject(ctx context.Context, carrier Carrier)
+
+	// Extract returns a copy of ctx containing the [SpanContext] and [Baggage] read from carrier.
+	// ctx is returned unchanged when carrier doesn't contain valid values.
+	Extract(ctx context.Context, carrier Carrier) context.Context
+
+	// Fields returns the keys the Propagator writes.
+	Fields() []string
+}
+
+// NewCompositePropagator creates a [Propagator] that calls each of the specified propagators in order.
+func NewCompositePropagator(propagators ...Propagator) Propagator {
+	return compositePropagator(slices.Clone(propagators))
+}
+
+type compositePropagator []Propagator
+
+func (c compositePropagator) Inject(ctx context.Context, carrier Carrier) {
+	for _, p := range c {
+		p.Inject(ctx, carrier)
+	}
+}
+
+func (c compositePropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	for _, p := range c {
+		ctx = p.Extract(ctx, carrier)
+	}
+	return ctx
+}
+
+func (c compositePropagator) Fields() []string {
+	var fields []string
+	for _, p := range c {
+		for _, f := range p.Fields() {
+			if !slices.Contains(fields, f) {
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+const (
+	headerTraceparent = "traceparent"
+	headerTracestate  = "tracestate"
+)
+
+// NewTraceContextPropagator creates a [Propagator] for the traceparent and
+// tracestate headers defined by the W3C Trace Context specification.
+func NewTraceContextPropagator() Propagator {
+	return traceContextPropagator{}
+}
+
+type traceContextPropagator struct{}
+
+func (traceContextPropagator) Inject(ctx context.Context, carrier Carrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	carrier.Set(headerTraceparent, "00-"+hex.EncodeToString(sc.TraceID[:])+"-"+hex.EncodeToString(sc.SpanID[:])+"-"+hex.EncodeToString([]byte{byte(sc.TraceFlags)}))
+	if sc.TraceState != "" {
+		carrier.Set(headerTracestate, sc.TraceState)
+	}
+}
+
+func (traceContextPropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	sc, ok := parseTraceparent(carrier.Get(headerTraceparent))
+	if !ok {
+		return ctx
+	}
+	sc.TraceState = carrier.Get(headerTracestate)
+	return ContextWithSpanContext(ctx, sc)
+}
+
+func (traceContextPropagator) Fields() []string {
+	return []string{headerTraceparent, headerTracestate}
+}
+
+// parseTraceparent parses a traceparent header value of the form version-traceid-spanid-flags
+func parseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	var version, flags [1]byte
+	if len(parts) < 4 || !decodeLowerHex(version[:], parts[0]) || version[0] == 0xff {
+		return SpanContext{}, false
+	}
+	// future versions can append fields
+	if version[0] == 0 && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	sc := SpanContext{Remote: true}
+	if !decodeLowerHex(sc.TraceID[:], parts[1]) || !decodeLowerHex(sc.SpanID[:], parts[2]) || !decodeLowerHex(flags[:], parts[3]) {
+		return SpanContext{}, false
+	}
+	sc.TraceFlags = TraceFlags(flags[0])
+	return sc, sc.IsValid()
+}
+
+// decodeLowerHex decodes s into dst, requiring len(s) == 2*len(dst) and lowercase hex digits
+func decodeLowerHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+const headerBaggage = "baggage"
+
+// NewBaggagePropagator creates a [Propagator] for the baggage header defined by the W3C Baggage specification.
+func NewBaggagePropagator() Propagator {
+	return baggagePropagator{}
+}
+
+type baggagePropagator struct{}
+
+func (baggagePropagator) Inject(ctx context.Context, carrier Carrier) {
+	b := BaggageFromContext(ctx)
+	if len(b) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		if isBaggageKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	slices.Sort(keys)
+	members := make([]string, 0, len(keys))
+	for _, k := range keys {
+		members = append(members, k+"="+url.PathEscape(b[k]))
+	}
+	carrier.Set(headerBaggage, strings.Join(members, ","))
+}
+
+func (baggagePropagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	value := carrier.Get(headerBaggage)
+	if value == "" {
+		return ctx
+	}
+	b := Baggage{}
+	for _, member := range strings.Split(value, ",") {
+		// properties following a semicolon aren't supported
+		member, _, _ = strings.Cut(member, ";")
+		k, v, ok := strings.Cut(member, "=")
+		k = strings.TrimSpace(k)
+		if !ok || !isBaggageKey(k) {
+			continue
+		}
+		v, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		b[k] = v
+	}
+	if len(b) == 0 {
+		return ctx
+	}
+	return ContextWithBaggage(ctx, b)
+}
+
+func (baggagePropagator) Fields() []string {
+	return []string{headerBaggage}
+}
+
+// isBaggageKey returns true if k is a valid token as defined by RFC 7230
+func isBaggageKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, c := range k {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+const (
+	headerB3        = "b3"
+	headerB3TraceID = "X-B3-TraceId"
+	headerB3SpanID  = "X-B3-SpanId"
+	headerB3Sampled = "X-B3-Sampled"
+	headerB3Flags   = "X-B3-Flags"
+)
+
+// B3PropagatorOptions contains the optional values for [NewB3Propagator].
+type B3PropagatorOptions struct {
+	// SingleHeader writes the trace context to the single b3 header instead of the X-B3-* headers.
+	// Both formats are read regardless of this value, the single header taking precedence.
+	SingleHeader bool
+}
+
+// NewB3Propagator creates a [Propagator] for the B3 headers used by Zipkin.
+//   - options contains optional configuration, pass nil to accept the default values
+func NewB3Propagator(options *B3PropagatorOptions) Propagator {
+	if options == nil {
+		options = &B3PropagatorOptions{}
+	}
+	return b3Propagator{singleHeader: options.SingleHeader}
+}
+
+type b3Propagator struct {
+	singleHeader bool
+}
+
+func (b b3Propagator) Inject(ctx context.Context, carrier Carrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	traceID, spanID := hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:])
+	sampled := "0"
+	if sc.IsSampled() {
+		sampled = "1"
+	}
+	if b.singleHeader {
+		carrier.Set(headerB3, traceID+"-"+spanID+"-"+sampled)
+		return
+	}
+	carrier.Set(headerB3TraceID, traceID)
+	carrier.Set(headerB3SpanID, spanID)
+	carrier.Set(headerB3Sampled, sampled)
+}
+
+func (b3Propagator) Extract(ctx context.Context, carrier Carrier) context.Context {
+	var sc SpanContext
+	var ok bool
+	if single := carrier.Get(headerB3); single != "" {
+		// traceid-spanid[-sampled[-parentspanid]]
+		parts := strings.Split(single, "-")
+		if len(parts) < 2 || len(parts) > 4 {
+			return ctx
+		}
+		sampled := ""
+		if len(parts) > 2 {
+			sampled = parts[2]
+		}
+		sc, ok = parseB3(parts[0], parts[1], sampled)
+	} else {
+		sampled := carrier.Get(headerB3Sampled)
+		if carrier.Get(headerB3Flags) == "1" {
+			// debug implies sampled
+			sampled = "d"
+		}
+		sc, ok = parseB3(carrier.Get(headerB3TraceID), carrier.Get(headerB3SpanID), sampled)
+	}
+	if !ok {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+func (b b3Propagator) Fields() []string {
+	if b.singleHeader {
+		return []string{headerB3}
+	}
+	return []string{headerB3TraceID, headerB3SpanID, headerB3Sampled}
+}
+
+func parseB3(traceID, spanID, sampled string) (SpanContext, bool) {
+	sc := SpanContext{Remote: true}
+	traceID = strings.ToLower(traceID)
+	if len(traceID) == 16 {
+		// 64-bit trace IDs are left-padded
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if !decodeLowerHex(sc.TraceID[:], traceID) || !decodeLowerHex(sc.SpanID[:], strings.ToLower(spanID)) {
+		return SpanContext{}, false
+	}
+	switch strings.ToLower(sampled) {
+	case "1", "d", "true":
+		sc.TraceFlags = TraceFlagsSampled
+	case "", "0", "false":
+	default:
+		return SpanContext{}, false
+	}
+	return sc, sc.IsValid()
+}
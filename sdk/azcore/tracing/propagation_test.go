@@ -0,0 +1,187 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testSpanContext = SpanContext{
+	TraceID:    [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+	SpanID:     [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	TraceFlags: TraceFlagsSampled,
+}
+
+func TestTraceContextPropagator(t *testing.T) {
+	p := NewTraceContextPropagator()
+	require.Equal(t, []string{"traceparent", "tracestate"}, p.Fields())
+
+	sc := testSpanContext
+	sc.TraceState = "congo=t61rcWkgMzE"
+	carrier := HTTPHeaderCarrier(http.Header{})
+	p.Inject(ContextWithSpanContext(context.Background(), sc), carrier)
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", carrier.Get("Traceparent"))
+	require.Equal(t, "congo=t61rcWkgMzE", carrier.Get("tracestate"))
+
+	extracted := SpanContextFromContext(p.Extract(context.Background(), carrier))
+	sc.Remote = true
+	require.Equal(t, sc, extracted)
+
+	// nothing is injected without a valid span context
+	carrier = HTTPHeaderCarrier(http.Header{})
+	p.Inject(context.Background(), carrier)
+	require.Empty(t, carrier.Keys())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		ctx := p.Extract(context.Background(), MapCarrier{"traceparent": invalid})
+		require.False(t, SpanContextFromContext(ctx).IsValid(), invalid)
+	}
+
+	// future versions can append fields
+	ctx := p.Extract(context.Background(), MapCarrier{"traceparent": "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"})
+	extracted = SpanContextFromContext(ctx)
+	require.True(t, extracted.IsValid())
+	require.False(t, extracted.IsSampled())
+}
+
+func TestBaggagePropagator(t *testing.T) {
+	p := NewBaggagePropagator()
+	carrier := MapCarrier{}
+	p.Inject(ContextWithBaggage(context.Background(), Baggage{"user": "a b,c", "tenant": "contoso", "in valid": "x"}), carrier)
+	require.Equal(t, "tenant=contoso,user=a%20b%2Cc", carrier.Get("baggage"))
+
+	b := BaggageFromContext(p.Extract(context.Background(), carrier))
+	require.Equal(t, Baggage{"user": "a b,c", "tenant": "contoso"}, b)
+
+	b = BaggageFromContext(p.Extract(context.Background(), MapCarrier{"baggage": " key1 = value1;property , noValue, =x, key2=%zz"}))
+	require.Equal(t, Baggage{"key1": "value1"}, b)
+
+	require.Nil(t, BaggageFromContext(p.Extract(context.Background(), MapCarrier{})))
+}
+
+func TestB3Propagator(t *testing.T) {
+	multi := NewB3Propagator(nil)
+	carrier := HTTPHeaderCarrier(http.Header{})
+	multi.Inject(ContextWithSpanContext(context.Background(), testSpanContext), carrier)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", carrier.Get("X-B3-TraceId"))
+	require.Equal(t, "00f067aa0ba902b7", carrier.Get("X-B3-SpanId"))
+	require.Equal(t, "1", carrier.Get("X-B3-Sampled"))
+	expected := testSpanContext
+	expected.Remote = true
+	require.Equal(t, expected, SpanContextFromContext(multi.Extract(context.Background(), carrier)))
+
+	single := NewB3Propagator(&B3PropagatorOptions{SingleHeader: true})
+	require.Equal(t, []string{"b3"}, single.Fields())
+	carrier = HTTPHeaderCarrier(http.Header{})
+	single.Inject(ContextWithSpanContext(context.Background(), testSpanContext), carrier)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", carrier.Get("b3"))
+	require.Equal(t, expected, SpanContextFromContext(multi.Extract(context.Background(), carrier)))
+
+	// 64-bit trace IDs, debug flag, and parent span IDs
+	sc := SpanContextFromContext(multi.Extract(context.Background(), MapCarrier{"b3": "a3ce929d0e0e4736-00f067aa0ba902b7-d-05e3ac9a4f6e3b90"}))
+	require.True(t, sc.IsValid())
+	require.True(t, sc.IsSampled())
+	require.Equal(t, [16]byte{8: 0xa3, 9: 0xce, 10: 0x92, 11: 0x9d, 12: 0x0e, 13: 0x0e, 14: 0x47, 15: 0x36}, sc.TraceID)
+	sc = SpanContextFromContext(multi.Extract(context.Background(), MapCarrier{"X-B3-TraceId": "a3ce929d0e0e4736", "X-B3-SpanId": "00f067aa0ba902b7", "X-B3-Flags": "1"}))
+	require.True(t, sc.IsSampled())
+
+	for _, invalid := range []string{"1", "a3ce929d0e0e4736-00f067aa0ba902b7-x", "a3ce929d0e0e47-00f067aa0ba902b7"} {
+		require.False(t, SpanContextFromContext(multi.Extract(context.Background(), MapCarrier{"b3": invalid})).IsValid(), invalid)
+	}
+}
+
+func TestCompositePropagator(t *testing.T) {
+	p := NewCompositePropagator(NewTraceContextPropagator(), NewBaggagePropagator(), NewTraceContextPropagator())
+	require.Equal(t, []string{"traceparent", "tracestate", "baggage"}, p.Fields())
+
+	ctx := ContextWithSpanContext(context.Background(), testSpanContext)
+	ctx = ContextWithBaggage(ctx, Baggage{"key": "value"})
+	carrier := MapCarrier{}
+	p.Inject(ctx, carrier)
+	require.ElementsMatch(t, []string{"traceparent", "baggage"}, carrier.Keys())
+
+	ctx = p.Extract(context.Background(), carrier)
+	require.True(t, SpanContextFromContext(ctx).Remote)
+	require.Equal(t, Baggage{"key": "value"}, BaggageFromContext(ctx))
+}
+
+func TestTracerInjectExtract(t *testing.T) {
+	type spanKey struct{}
+	newTracer := func(name, version string) Tracer {
+		return NewTracer(func(ctx context.Context, spanName string, options *SpanOptions) (context.Context, Span) {
+			span := NewSpan(SpanImpl{SpanContext: func() SpanContext { return testSpanContext }})
+			return context.WithValue(ctx, spanKey{}, span), span
+		}, &TracerOptions{
+			SpanFromContext: func(ctx context.Context) Span {
+				span, _ := ctx.Value(spanKey{}).(Span)
+				return span
+			},
+		})
+	}
+
+	// no propagator
+	tr := NewProvider(newTracer, nil).NewTracer("module", "v1.0.0")
+	ctx, _ := tr.Start(context.Background(), "span", nil)
+	carrier := MapCarrier{}
+	tr.Inject(ctx, carrier)
+	require.Empty(t, carrier)
+	require.Equal(t, ctx, tr.Extract(ctx, MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}))
+
+	// the span context of the span in ctx is injected, e.g. into AMQP application properties
+	tr = NewProvider(newTracer, &ProviderOptions{Propagator: NewTraceContextPropagator()}).NewTracer("module", "v1.0.0")
+	ctx, _ = tr.Start(context.Background(), "span", nil)
+	tr.Inject(ctx, carrier)
+	require.Equal(t, MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, carrier)
+	sc := SpanContextFromContext(tr.Extract(context.Background(), carrier))
+	require.True(t, sc.Remote)
+	require.Equal(t, testSpanContext.TraceID, sc.TraceID)
+
+	// spans without a span context
+	require.Zero(t, Span{}.SpanContext())
+}
+
+func TestTracerRemoteParent(t *testing.T) {
+	var parent SpanContext
+	noSpanContext := func(name, version string) Tracer {
+		return NewTracer(func(ctx context.Context, spanName string, options *SpanOptions) (context.Context, Span) {
+			parent = options.Parent
+			return ctx, NewSpan(SpanImpl{})
+		}, nil)
+	}
+	tr := NewProvider(noSpanContext, &ProviderOptions{Propagator: NewTraceContextPropagator()}).NewTracer("module", "v1.0.0")
+	ctx := tr.Extract(context.Background(), MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"})
+
+	// the extracted span context is the parent of the next span
+	ctx, _ = tr.Start(ctx, "span", nil)
+	require.True(t, parent.Remote)
+	require.Equal(t, testSpanContext.TraceID, parent.TraceID)
+	require.Equal(t, testSpanContext.SpanID, parent.SpanID)
+
+	// an explicit parent isn't replaced
+	explicit := SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{1}}
+	tr.Start(ctx, "span", &SpanOptions{Parent: explicit})
+	require.Equal(t, explicit, parent)
+
+	// without a current span context, the remote one isn't injected as if it were the current span's
+	carrier := MapCarrier{}
+	tr.Inject(ctx, carrier)
+	require.Empty(t, carrier)
+
+	// no parent without a remote span context
+	tr.Start(context.Background(), "span", nil)
+	require.Zero(t, parent)
+}
@@ -13,15 +13,23 @@ import (
 
 // ProviderOptions contains the optional values when creating a Provider.
 type ProviderOptions struct {
-	// for future expansion
+	// Propagator injects the trace context into outgoing HTTP requests and messages, and extracts it
+	// from incoming messages. The pipeline uses it to add headers to each HTTP request. Use
+	// [NewCompositePropagator] to combine multiple propagators.
+	// The default value is nil, which leaves propagation to the tracing implementation.
+	Propagator Propagator
 }
 
 // NewProvider creates a new Provider with the specified values.
 //   - newTracerFn is the underlying implementation for creating Tracer instances
 //   - options contains optional values; pass nil to accept the default value
 func NewProvider(newTracerFn func(name, version string) Tracer, options *ProviderOptions) Provider {
+	if options == nil {
+		options = &ProviderOptions{}
+	}
 	return Provider{
 		newTracerFn: newTracerFn,
+		propagator:  options.Propagator,
 	}
 }
 
@@ -29,6 +37,7 @@ func NewProvider(newTracerFn func(name, version string) Tracer, options *Provide
 // It defaults to a no-op provider.
 type Provider struct {
 	newTracerFn func(name, version string) Tracer
+	propagator  Propagator
 }
 
 // NewTracer creates a new Tracer for the specified module name and version.
@@ -38,6 +47,7 @@ func (p Provider) NewTracer(module, version string) (tracer Tracer) {
 	if p.newTracerFn != nil {
 		tracer = p.newTracerFn(module, version)
 	}
+	tracer.propagator = p.propagator
 	return
 }
 
@@ -67,10 +77,12 @@ type Tracer struct {
 	attrs             []Attribute
 	newSpanFn         func(ctx context.Context, spanName string, options *SpanOptions) (context.Context, Span)
 	spanFromContextFn func(ctx context.Context) Span
+	propagator        Propagator
 }
 
 // Start creates a new span and a context.Context that contains it.
-//   - ctx is the parent context for this span. If it contains a Span, the newly created span will be a child of that span, else it will be a root span
+//   - ctx is the parent context for this span. If it contains a Span, the newly created span will be a child of that span.
+//     Else, if it contains a remote [SpanContext], e.g. one returned by [Tracer.Extract], the span will be its child, else it will be a root span
 //   - spanName identifies the span within a trace, it's typically the fully qualified API name
 //   - options contains optional values for the span, pass nil to accept any defaults
 func (t Tracer) Start(ctx context.Context, spanName string, options *SpanOptions) (context.Context, Span) {
@@ -79,6 +91,9 @@ func (t Tracer) Start(ctx context.Context, spanName string, options *SpanOptions
 		if options != nil {
 			opts = *options
 		}
+		if sc := SpanContextFromContext(ctx); !opts.Parent.IsValid() && sc.IsValid() && sc.Remote {
+			opts.Parent = sc
+		}
 		opts.Attributes = append(opts.Attributes, t.attrs...)
 		return t.newSpanFn(ctx, spanName, &opts)
 	}
@@ -106,6 +121,29 @@ func (t Tracer) SpanFromContext(ctx context.Context) Span {
 	return Span{}
 }
 
+// Inject adds the trace context of the Span associated with ctx, and the [Baggage] in ctx, to carrier.
+// No trace context is added when the Span doesn't provide a [SpanContext]; in particular, a remote
+// SpanContext in ctx, such as one returned by [Tracer.Extract], is never added in place of the Span's.
+// It's a no-op when the Tracer's [Provider] has no [Propagator].
+func (t Tracer) Inject(ctx context.Context, carrier Carrier) {
+	if t.propagator == nil {
+		return
+	}
+	ctx = ContextWithSpanContext(ctx, t.SpanFromContext(ctx).SpanContext())
+	t.propagator.Inject(ctx, carrier)
+}
+
+// Extract returns a copy of ctx containing the trace context and [Baggage] read from carrier, e.g.
+// the application properties of a received message. Use [SpanContextFromContext] and
+// [BaggageFromContext] to retrieve them. ctx is returned unchanged when the Tracer's
+// [Provider] has no [Propagator].
+func (t Tracer) Extract(ctx context.Context, carrier Carrier) context.Context {
+	if t.propagator == nil {
+		return ctx
+	}
+	return t.propagator.Extract(ctx, carrier)
+}
+
 // SpanOptions contains optional settings for creating a span.
 type SpanOptions struct {
 	// Kind indicates the kind of Span.
@@ -113,6 +151,11 @@ type SpanOptions struct {
 
 	// Attributes contains key-value pairs of attributes for the span.
 	Attributes []Attribute
+
+	// Parent is the remote span the span is a child of when its context contains no Span.
+	// [Tracer.Start] sets it to the remote [SpanContext] in the context, e.g. one returned by
+	// [Tracer.Extract]. Tracing implementations must use it as the span's parent.
+	Parent SpanContext
 }
 
 /////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -132,6 +175,9 @@ type SpanImpl struct {
 
 	// SetStatus contains the implementation for the Span.SetStatus method.
 	SetStatus func(SpanStatus, string)
+
+	// SpanContext contains the implementation for the Span.SpanContext method.
+	SpanContext func() SpanContext
 }
 
 // NewSpan creates a Span with the specified implementation.
@@ -177,6 +223,15 @@ func (s Span) SetStatus(code SpanStatus, desc string) {
 	}
 }
 
+// SpanContext returns the identifying trace information of the Span.
+// A zero-value SpanContext is returned when the implementation doesn't provide it.
+func (s Span) SpanContext() SpanContext {
+	if s.impl.SpanContext != nil {
+		return s.impl.SpanContext()
+	}
+	return SpanContext{}
+}
+
 /////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 // Attribute is a key-value pair.
@@ -60,7 +60,9 @@ var (
 // Construct one with [github.com/Azure/azure-sdk-for-go/sdk/azidentity/cache.New]. This package's
 // [persistent user authentication example] shows how to use a persistent cache to reuse user
 // logins across application runs. For service principal credential types such as
-// [ClientCertificateCredential], simply set the Cache field on the credential options.
+// [ClientCertificateCredential], simply set the Cache field on the credential options. To store
+// cache data elsewhere, for example in a database shared by several processes, construct a Cache
+// with [NewCacheFromStorage].
 //
 // [persistent user authentication example]: https://pkg.go.dev/github.com/Azure/azure-sdk-for-go/sdk/azidentity#example-package-PersistentUserAuthentication
 type Cache = internal.Cache
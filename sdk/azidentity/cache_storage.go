@@ -0,0 +1,231 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity/internal"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+	"github.com/AzureAD/microsoft-authentication-library-for-go/apps/cache"
+)
+
+// CacheStorage stores the serialized data of a persistent token cache, for example in Redis or in files
+// on a shared volume. Credentials read the data before each token request and write it after
+// acquiring a token, so a storage shared by many processes gives them a common token cache.
+// The data is opaque and isn't encrypted; use [NewEncryptedCacheStorage] to encrypt it at rest.
+// Implementations must be safe for concurrent use.
+type CacheStorage interface {
+	// Read returns the data stored under key. It returns nil data and a nil error when there's none.
+	Read(ctx context.Context, key string) ([]byte, error)
+
+	// Write stores data under key, replacing any data stored previously.
+	Write(ctx context.Context, key string, data []byte) error
+}
+
+// CacheStorageOptions contains optional parameters for [NewCacheFromStorage].
+type CacheStorageOptions struct {
+	// Name distinguishes caches sharing a storage. It's the prefix of each key passed to the storage.
+	// The default value is "msal.cache".
+	Name string
+}
+
+// NewCacheFromStorage constructs a persistent token cache backed by the specified storage. Set the Cache
+// field of a credential's options to the returned value to enable persistent caching.
+// The cache stores data under several keys, each prefixed by the cache's name, because credentials
+// store some kinds of tokens separately and partition data by application and tenant.
+//   - storage reads and writes cache data
+//   - options contains optional configuration, pass nil to accept the default values
+func NewCacheFromStorage(storage CacheStorage, options *CacheStorageOptions) (Cache, error) {
+	if storage == nil {
+		return Cache{}, errors.New("storage can't be nil")
+	}
+	name := "msal.cache"
+	if options != nil && options.Name != "" {
+		name = options.Name
+	}
+	return internal.NewCache(func(cae bool) (cache.ExportReplace, error) {
+		xr := &storageExportReplace{name: name, storage: storage}
+		if cae {
+			xr.name += ".cae"
+		}
+		return xr, nil
+	}), nil
+}
+
+// storageExportReplace adapts a CacheStorage to MSAL's ExportReplace interface
+type storageExportReplace struct {
+	name    string
+	storage CacheStorage
+}
+
+func (s *storageExportReplace) key(partition string) string {
+	if partition == "" {
+		return s.name
+	}
+	return s.name + "." + partition
+}
+
+func (s *storageExportReplace) Replace(ctx context.Context, u cache.Unmarshaler, hints cache.ReplaceHints) error {
+	data, err := s.storage.Read(ctx, s.key(hints.PartitionKey))
+	if err != nil || len(data) == 0 {
+		return err
+	}
+	return u.Unmarshal(data)
+}
+
+func (s *storageExportReplace) Export(ctx context.Context, m cache.Marshaler, hints cache.ExportHints) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	return s.storage.Write(ctx, s.key(hints.PartitionKey), data)
+}
+
+// EncryptedCacheStorageOptions contains optional parameters for [NewEncryptedCacheStorage].
+type EncryptedCacheStorageOptions struct {
+	// DecryptionKeys are additional keys used to decrypt data that wasn't encrypted with the current key,
+	// so that the key can be rotated without losing cached data. Data is always encrypted with the current key.
+	DecryptionKeys [][]byte
+}
+
+// NewEncryptedCacheStorage returns a [CacheStorage] that encrypts data with AES-GCM before writing it to
+// storage and decrypts it after reading. Each value is bound to its key, so encrypted values can't be swapped.
+// Data that can't be decrypted, for example because it was encrypted with a retired key, is logged and
+// treated as missing, so that credentials acquire new tokens and overwrite it.
+//   - storage stores the encrypted data
+//   - key is a 16, 24 or 32 byte AES key; keep it in a secret store such as Key Vault, not with the data
+//   - options contains optional configuration, pass nil to accept the default values
+func NewEncryptedCacheStorage(storage CacheStorage, key []byte, options *EncryptedCacheStorageOptions) (CacheStorage, error) {
+	if storage == nil {
+		return nil, errors.New("storage can't be nil")
+	}
+	if options == nil {
+		options = &EncryptedCacheStorageOptions{}
+	}
+	current, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &encryptedCacheStorage{storage: storage, aeads: []cipher.AEAD{current}}
+	for _, k := range options.DecryptionKeys {
+		aead, err := newGCM(k)
+		if err != nil {
+			return nil, err
+		}
+		s.aeads = append(s.aeads, aead)
+	}
+	return s, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedFormatVersion is the first byte of encrypted data, followed by the nonce and ciphertext
+const encryptedFormatVersion byte = 1
+
+// encryptedCacheStorage encrypts data with the first AEAD and decrypts it with any of them
+type encryptedCacheStorage struct {
+	storage CacheStorage
+	aeads   []cipher.AEAD
+}
+
+func (s *encryptedCacheStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.storage.Read(ctx, key)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	for _, aead := range s.aeads {
+		if len(data) < 1+aead.NonceSize() || data[0] != encryptedFormatVersion {
+			break
+		}
+		nonce, ciphertext := data[1:1+aead.NonceSize()], data[1+aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(key)); err == nil {
+			return plaintext, nil
+		}
+	}
+	log.Writef(EventAuthentication, "Ignoring cached data for %q because it couldn't be decrypted", key)
+	return nil, nil
+}
+
+func (s *encryptedCacheStorage) Write(ctx context.Context, key string, data []byte) error {
+	aead := s.aeads[0]
+	b := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(data)+aead.Overhead())
+	b[0] = encryptedFormatVersion
+	if _, err := rand.Read(b[1:]); err != nil {
+		return err
+	}
+	return s.storage.Write(ctx, key, aead.Seal(b, b[1:], data, []byte(key)))
+}
+
+// NewFileCacheStorage returns a [CacheStorage] that stores data in files in the specified directory,
+// which is created if it doesn't exist. Writes are atomic, so processes sharing the directory, for example
+// on a shared volume, never read partially written data. When processes write concurrently, the last
+// write wins. The files aren't encrypted; use [NewEncryptedCacheStorage] to encrypt them.
+func NewFileCacheStorage(dir string) (CacheStorage, error) {
+	if dir == "" {
+		return nil, errors.New("dir can't be empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &fileCacheStorage{dir: dir}, nil
+}
+
+type fileCacheStorage struct {
+	dir string
+}
+
+// path maps a key to a file name, which is a hash of the key because keys may contain any character
+func (s *fileCacheStorage) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(h[:]))
+}
+
+func (s *fileCacheStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *fileCacheStorage) Write(ctx context.Context, key string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(name, s.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(name)
+	}
+	return err
+}
@@ -0,0 +1,215 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+// mapCacheStorage is a CacheStorage backed by a map, like a Redis instance shared by several processes
+type mapCacheStorage struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	err  error
+}
+
+func (s *mapCacheStorage) Read(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key], s.err
+}
+
+func (s *mapCacheStorage) Write(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if s.data == nil {
+		s.data = map[string][]byte{}
+	}
+	s.data[key] = data
+	return nil
+}
+
+func TestNewCacheFromStorage(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	fileStorage, err := NewFileCacheStorage(t.TempDir())
+	require.NoError(t, err)
+	encryptedFileStorage, err := NewEncryptedCacheStorage(fileStorage, key, nil)
+	require.NoError(t, err)
+	encryptedMapStorage, err := NewEncryptedCacheStorage(&mapCacheStorage{}, key, nil)
+	require.NoError(t, err)
+
+	for name, storage := range map[string]CacheStorage{
+		"map":            &mapCacheStorage{},
+		"encrypted map":  encryptedMapStorage,
+		"encrypted file": encryptedFileStorage,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewCacheFromStorage(storage, nil)
+			require.NoError(t, err)
+			tokenReqs := 0
+			sts := mockSTS{
+				tokenRequestCallback: func(*http.Request) *http.Response {
+					tokenReqs++
+					return nil
+				},
+			}
+			newCred := func() *ClientSecretCredential {
+				// each credential represents a different process sharing the storage
+				cred, err := NewClientSecretCredential(fakeTenantID, fakeClientID, fakeSecret, &ClientSecretCredentialOptions{
+					Cache:         c,
+					ClientOptions: policy.ClientOptions{Transport: &sts},
+				})
+				require.NoError(t, err)
+				return cred
+			}
+			_, err = newCred().GetToken(ctx, testTRO)
+			require.NoError(t, err)
+			require.Equal(t, 1, tokenReqs)
+
+			_, err = newCred().GetToken(ctx, testTRO)
+			require.NoError(t, err)
+			require.Equal(t, 1, tokenReqs)
+
+			// CAE tokens are cached separately
+			caeTRO := testTRO
+			caeTRO.EnableCAE = true
+			_, err = newCred().GetToken(ctx, caeTRO)
+			require.NoError(t, err)
+			require.Equal(t, 2, tokenReqs)
+		})
+	}
+
+	_, err = NewCacheFromStorage(nil, nil)
+	require.Error(t, err)
+}
+
+func TestNewCacheFromStorageKeys(t *testing.T) {
+	storage := &mapCacheStorage{}
+	c, err := NewCacheFromStorage(storage, &CacheStorageOptions{Name: "myapp"})
+	require.NoError(t, err)
+	cred, err := NewClientSecretCredential(fakeTenantID, fakeClientID, fakeSecret, &ClientSecretCredentialOptions{
+		Cache:         c,
+		ClientOptions: policy.ClientOptions{Transport: &mockSTS{}},
+	})
+	require.NoError(t, err)
+	_, err = cred.GetToken(ctx, testTRO)
+	require.NoError(t, err)
+	caeTRO := testTRO
+	caeTRO.EnableCAE = true
+	_, err = cred.GetToken(ctx, caeTRO)
+	require.NoError(t, err)
+
+	cae, noCAE := 0, 0
+	for k := range storage.data {
+		require.True(t, strings.HasPrefix(k, "myapp"), k)
+		if strings.HasPrefix(k, "myapp.cae") {
+			cae++
+		} else {
+			noCAE++
+		}
+	}
+	require.NotZero(t, cae)
+	require.NotZero(t, noCAE)
+
+	// storage errors are returned
+	storage.err = errors.New("storage unavailable")
+	cred, err = NewClientSecretCredential(fakeTenantID, fakeClientID, fakeSecret, &ClientSecretCredentialOptions{
+		Cache:         c,
+		ClientOptions: policy.ClientOptions{Transport: &mockSTS{}},
+	})
+	require.NoError(t, err)
+	_, err = cred.GetToken(ctx, testTRO)
+	require.ErrorContains(t, err, "storage unavailable")
+}
+
+func TestEncryptedCacheStorage(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 16), bytes.Repeat([]byte{2}, 32)
+	inner := &mapCacheStorage{}
+	old, err := NewEncryptedCacheStorage(inner, oldKey, nil)
+	require.NoError(t, err)
+	plaintext := []byte(`{"AccessToken": {}}`)
+	require.NoError(t, old.Write(ctx, "key", plaintext))
+	require.NotContains(t, string(inner.data["key"]), "AccessToken")
+
+	actual, err := old.Read(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, plaintext, actual)
+
+	// values are bound to their keys
+	inner.data["other"] = inner.data["key"]
+	actual, err = old.Read(ctx, "other")
+	require.NoError(t, err)
+	require.Nil(t, actual)
+
+	// rotation
+	rotated, err := NewEncryptedCacheStorage(inner, newKey, &EncryptedCacheStorageOptions{DecryptionKeys: [][]byte{oldKey}})
+	require.NoError(t, err)
+	actual, err = rotated.Read(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, plaintext, actual)
+	require.NoError(t, rotated.Write(ctx, "key", plaintext))
+	actual, err = old.Read(ctx, "key")
+	require.NoError(t, err)
+	require.Nil(t, actual, "data encrypted with the new key shouldn't be readable with the old key")
+
+	// corrupt, unencrypted, and missing data is treated as missing
+	for _, data := range [][]byte{nil, []byte("{}"), {encryptedFormatVersion}, append(bytes.Clone(inner.data["key"]), 0)} {
+		inner.data["key"] = data
+		actual, err = rotated.Read(ctx, "key")
+		require.NoError(t, err)
+		require.Nil(t, actual)
+	}
+
+	_, err = NewEncryptedCacheStorage(inner, []byte("short"), nil)
+	require.Error(t, err)
+	_, err = NewEncryptedCacheStorage(inner, newKey, &EncryptedCacheStorageOptions{DecryptionKeys: [][]byte{{1}}})
+	require.Error(t, err)
+	_, err = NewEncryptedCacheStorage(nil, newKey, nil)
+	require.Error(t, err)
+}
+
+func TestFileCacheStorage(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileCacheStorage(dir + "/cache")
+	require.NoError(t, err)
+
+	data, err := s.Read(ctx, "key")
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	for _, content := range []string{"first", "second"} {
+		require.NoError(t, s.Write(ctx, "key/with:any*characters", []byte(content)))
+		data, err = s.Read(ctx, "key/with:any*characters")
+		require.NoError(t, err)
+		require.Equal(t, content, string(data))
+	}
+
+	// another process sharing the directory reads the same data
+	s2, err := NewFileCacheStorage(dir + "/cache")
+	require.NoError(t, err)
+	data, err = s2.Read(ctx, "key/with:any*characters")
+	require.NoError(t, err)
+	require.Equal(t, "second", string(data))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Read(canceled, "key")
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, s.Write(canceled, "key", nil), context.Canceled)
+
+	_, err = NewFileCacheStorage("")
+	require.Error(t, err)
+}
@@ -117,26 +117,37 @@ func (c *ChainedTokenCredential) GetToken(ctx context.Context, opts policy.Token
 	}
 	// err is the error returned by the last GetToken call. It will be nil when that call succeeds
 	if err != nil {
-		// return credentialUnavailableError iff all sources did so; return AuthenticationFailedError otherwise
-		msg := createChainedErrorMessage(errs)
-		var authFailedErr *AuthenticationFailedError
-		switch {
-		case errors.As(err, &authFailedErr):
-			err = newAuthenticationFailedError(c.name, msg, authFailedErr.RawResponse)
-			if af, ok := err.(*AuthenticationFailedError); ok {
-				// stop Error() printing the response again; it's already in msg
-				af.omitResponse = true
-			}
-		case errors.As(err, &unavailableErr):
-			err = newCredentialUnavailableError(c.name, msg)
-		default:
-			res := getResponseFromError(err)
-			err = newAuthenticationFailedError(c.name, msg, res)
-		}
+		err = c.chainError(errs)
 	}
 	return token, err
 }
 
+// chainError returns the error for a failed authentication attempt given the errors returned by the sources,
+// the last of which determines the error's type
+func (c *ChainedTokenCredential) chainError(errs []error) error {
+	// return credentialUnavailableError iff all sources did so; return AuthenticationFailedError otherwise
+	err := errs[len(errs)-1]
+	msg := createChainedErrorMessage(errs)
+	var (
+		authFailedErr  *AuthenticationFailedError
+		unavailableErr credentialUnavailable
+	)
+	switch {
+	case errors.As(err, &authFailedErr):
+		err = newAuthenticationFailedError(c.name, msg, authFailedErr.RawResponse)
+		if af, ok := err.(*AuthenticationFailedError); ok {
+			// stop Error() printing the response again; it's already in msg
+			af.omitResponse = true
+		}
+	case errors.As(err, &unavailableErr):
+		err = newCredentialUnavailableError(c.name, msg)
+	default:
+		res := getResponseFromError(err)
+		err = newAuthenticationFailedError(c.name, msg, res)
+	}
+	return err
+}
+
 func createChainedErrorMessage(errs []error) string {
 	msg := "failed to acquire a token.\nAttempted credentials:"
 	for _, err := range errs {
@@ -146,6 +157,9 @@ func createChainedErrorMessage(errs []error) string {
 }
 
 func extractCredentialName(credential azcore.TokenCredential) string {
+	if t, ok := credential.(*timeoutCredential); ok {
+		return t.name
+	}
 	return strings.TrimPrefix(fmt.Sprintf("%T", credential), "*azidentity.")
 }
 
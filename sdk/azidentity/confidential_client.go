@@ -117,6 +117,7 @@ func (c *confidentialClient) GetToken(ctx context.Context, tro policy.TokenReque
 	} else {
 		msg := fmt.Sprintf(scopeLogFmt, c.name, strings.Join(ar.GrantedScopes, ", "))
 		log.Write(EventAuthentication, msg)
+		recordTokenSource(ctx, ar.Metadata.TokenSource == confidential.TokenSourceCache)
 	}
 	return azcore.AccessToken{Token: ar.AccessToken, ExpiresOn: ar.ExpiresOn.UTC(), RefreshOn: ar.Metadata.RefreshOn.UTC()}, err
 }
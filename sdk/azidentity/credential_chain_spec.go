@@ -0,0 +1,365 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/internal/log"
+)
+
+// azureCredentialChain is the environment variable read by NewChainedTokenCredentialFromEnvironment
+const azureCredentialChain = "AZURE_CREDENTIAL_CHAIN"
+
+// CredentialChainSpec describes a credential chain for [NewChainedTokenCredentialFromSpec]. Applications can read
+// it from a JSON configuration file with [ParseCredentialChainSpec]. This module doesn't depend on a YAML package,
+// but the spec's YAML struct tags have the same names as its JSON tags, so a YAML package such as gopkg.in/yaml.v3
+// decodes the equivalent YAML document into a spec. For example:
+//
+//	{
+//	  "sources": [
+//	    {"type": "WorkloadIdentityCredential"},
+//	    {"type": "ManagedIdentityCredential", "clientId": "...", "timeout": "5s"},
+//	    {"type": "AzureCLICredential", "tenantId": "..."}
+//	  ]
+//	}
+type CredentialChainSpec struct {
+	// RetrySources sets [ChainedTokenCredentialOptions.RetrySources].
+	RetrySources bool `json:"retrySources,omitempty" yaml:"retrySources,omitempty"`
+
+	// Sources are the credentials in the chain, in the order the chain tries them.
+	Sources []CredentialSpec `json:"sources" yaml:"sources"`
+}
+
+// CredentialSpec describes a credential in a [CredentialChainSpec]. Type is required. The other fields are
+// optional and apply only to some types; specifying a field for a type it doesn't apply to is an error.
+// The spec never contains secrets. Instead, it names environment variables containing them.
+type CredentialSpec struct {
+	// Type is the credential's type, one of "AzureCLICredential", "AzureDeveloperCLICredential",
+	// "ClientCertificateCredential", "ClientSecretCredential", "EnvironmentCredential",
+	// "ManagedIdentityCredential" or "WorkloadIdentityCredential". It isn't case-sensitive.
+	Type string `json:"type" yaml:"type"`
+
+	// AdditionallyAllowedTenants specifies tenants to which the credential may authenticate, in addition to
+	// TenantID. Add the wildcard value "*" to allow the credential to authenticate to any tenant. It doesn't
+	// apply to EnvironmentCredential or ManagedIdentityCredential.
+	AdditionallyAllowedTenants []string `json:"additionallyAllowedTenants,omitempty" yaml:"additionallyAllowedTenants,omitempty"`
+
+	// CertificatePasswordEnv is the name of an environment variable containing the password for the certificate
+	// file, if the file is encrypted. It applies to ClientCertificateCredential. The default value is
+	// "AZURE_CLIENT_CERTIFICATE_PASSWORD".
+	CertificatePasswordEnv string `json:"certificatePasswordEnv,omitempty" yaml:"certificatePasswordEnv,omitempty"`
+
+	// CertificatePath is the path of a PEM or PKCS12 file containing the certificate and private key. It applies
+	// to ClientCertificateCredential. The default value is the value of environment variable AZURE_CLIENT_CERTIFICATE_PATH.
+	CertificatePath string `json:"certificatePath,omitempty" yaml:"certificatePath,omitempty"`
+
+	// ClientID is the client ID of the application for ClientCertificateCredential, ClientSecretCredential and
+	// WorkloadIdentityCredential, for which the default value is the value of environment variable AZURE_CLIENT_ID.
+	// For ManagedIdentityCredential, it's the client ID of a user-assigned identity.
+	ClientID string `json:"clientId,omitempty" yaml:"clientId,omitempty"`
+
+	// ClientSecretEnv is the name of an environment variable containing the client secret. It applies to
+	// ClientSecretCredential. The default value is "AZURE_CLIENT_SECRET".
+	ClientSecretEnv string `json:"clientSecretEnv,omitempty" yaml:"clientSecretEnv,omitempty"`
+
+	// ObjectID is the object ID of a user-assigned identity. It applies to ManagedIdentityCredential.
+	ObjectID string `json:"objectId,omitempty" yaml:"objectId,omitempty"`
+
+	// ResourceID is the resource ID of a user-assigned identity. It applies to ManagedIdentityCredential.
+	ResourceID string `json:"resourceId,omitempty" yaml:"resourceId,omitempty"`
+
+	// TenantID is the tenant the credential authenticates in. For ClientCertificateCredential, ClientSecretCredential
+	// and WorkloadIdentityCredential, the default value is the value of environment variable AZURE_TENANT_ID. For the
+	// CLI credentials, the default is the CLI's tenant. It doesn't apply to EnvironmentCredential or ManagedIdentityCredential.
+	TenantID string `json:"tenantId,omitempty" yaml:"tenantId,omitempty"`
+
+	// Timeout limits the time the credential may spend acquiring a token, for example "5s". When it elapses, the chain
+	// tries its next credential. By default, the credential's time is limited only by the context passed to GetToken.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// TokenFilePath is the path of a file containing a Kubernetes service account token. It applies to
+	// WorkloadIdentityCredential. The default value is the value of environment variable AZURE_FEDERATED_TOKEN_FILE.
+	TokenFilePath string `json:"tokenFilePath,omitempty" yaml:"tokenFilePath,omitempty"`
+}
+
+// CredentialChainSpecOptions contains optional parameters for [NewChainedTokenCredentialFromSpec]
+// and [NewChainedTokenCredentialFromEnvironment].
+type CredentialChainSpecOptions struct {
+	// ClientOptions configures credentials that send HTTP requests.
+	azcore.ClientOptions
+
+	// Cache is a persistent cache for credentials that support it. The default is an in-memory cache.
+	Cache Cache
+
+	// DisableInstanceDiscovery should be set true only by applications authenticating in disconnected clouds, or
+	// private clouds such as Azure Stack. See [DefaultAzureCredentialOptions.DisableInstanceDiscovery].
+	DisableInstanceDiscovery bool
+}
+
+// ParseCredentialChainSpec parses a JSON [CredentialChainSpec]. It returns an error when the JSON has fields
+// the spec doesn't define, so that misspelled settings aren't silently ignored.
+func ParseCredentialChainSpec(data []byte) (*CredentialChainSpec, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	spec := CredentialChainSpec{}
+	if err := d.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid credential chain spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// NewChainedTokenCredentialFromSpec creates a ChainedTokenCredential having the sources described by spec. It
+// returns an error when spec is invalid, for example because it specifies an unknown type. When a source can't be
+// constructed because its configuration is incomplete, for example because a certificate file doesn't exist, the
+// chain includes a placeholder that reports the problem as the source being unavailable. This allows a spec
+// to describe sources for several environments, as [DefaultAzureCredential] does.
+//   - spec describes the chain's sources
+//   - options contains optional configuration, pass nil to accept the default values
+func NewChainedTokenCredentialFromSpec(spec *CredentialChainSpec, options *CredentialChainSpecOptions) (*ChainedTokenCredential, error) {
+	if spec == nil || len(spec.Sources) == 0 {
+		return nil, errors.New("credential chain spec must have at least one source")
+	}
+	if options == nil {
+		options = &CredentialChainSpecOptions{}
+	}
+	var (
+		creds         []azcore.TokenCredential
+		errorMessages []string
+	)
+	for i, s := range spec.Sources {
+		name, err := s.validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential chain spec: source %d: %w", i, err)
+		}
+		cred, err := s.newCredential(name, options)
+		if err != nil {
+			errorMessages = append(errorMessages, name+": "+err.Error())
+			cred = &defaultCredentialErrorReporter{credType: name, err: err}
+		} else if mi, ok := cred.(*ManagedIdentityCredential); ok {
+			// NewChainedTokenCredential does this for unwrapped sources
+			mi.mic.chained = true
+		}
+		if s.Timeout != "" {
+			// validate checked this value
+			timeout, _ := time.ParseDuration(s.Timeout)
+			cred = &timeoutCredential{cred: cred, name: name, timeout: timeout}
+		}
+		creds = append(creds, cred)
+	}
+	if len(errorMessages) > 0 {
+		log.Writef(EventAuthentication, "NewChainedTokenCredentialFromSpec failed to initialize some credentials:\n\t%s", strings.Join(errorMessages, "\n\t"))
+	}
+	return NewChainedTokenCredential(creds, &ChainedTokenCredentialOptions{RetrySources: spec.RetrySources})
+}
+
+// NewChainedTokenCredentialFromEnvironment creates a ChainedTokenCredential from the value of environment
+// variable AZURE_CREDENTIAL_CHAIN. The value is either a JSON [CredentialChainSpec] or a comma-separated list of
+// credential types such as "WorkloadIdentityCredential,ManagedIdentityCredential,AzureCLICredential", in which
+// case each credential has its default configuration. See [NewChainedTokenCredentialFromSpec] for more details.
+//   - options contains optional configuration, pass nil to accept the default values
+func NewChainedTokenCredentialFromEnvironment(options *CredentialChainSpecOptions) (*ChainedTokenCredential, error) {
+	v := strings.TrimSpace(os.Getenv(azureCredentialChain))
+	if v == "" {
+		return nil, fmt.Errorf("environment variable %s isn't set", azureCredentialChain)
+	}
+	var spec *CredentialChainSpec
+	if strings.HasPrefix(v, "{") {
+		var err error
+		if spec, err = ParseCredentialChainSpec([]byte(v)); err != nil {
+			return nil, fmt.Errorf("%s: %w", azureCredentialChain, err)
+		}
+	} else {
+		spec = &CredentialChainSpec{}
+		for _, t := range strings.Split(v, ",") {
+			spec.Sources = append(spec.Sources, CredentialSpec{Type: strings.TrimSpace(t)})
+		}
+	}
+	return NewChainedTokenCredentialFromSpec(spec, options)
+}
+
+// credentialSpecFields maps the name of each credential type a CredentialSpec may describe
+// to the optional fields that apply to it. Type and Timeout apply to all types.
+var credentialSpecFields = map[string][]string{
+	credNameAzureCLI:          {"additionallyAllowedTenants", "tenantId"},
+	credNameAzureDeveloperCLI: {"additionallyAllowedTenants", "tenantId"},
+	credNameCert:              {"additionallyAllowedTenants", "certificatePasswordEnv", "certificatePath", "clientId", "tenantId"},
+	credNameSecret:            {"additionallyAllowedTenants", "clientId", "clientSecretEnv", "tenantId"},
+	credNameEnvironment:       {},
+	credNameManagedIdentity:   {"clientId", "objectId", "resourceId"},
+	credNameWorkloadIdentity:  {"additionallyAllowedTenants", "clientId", "tenantId", "tokenFilePath"},
+}
+
+// validate returns the canonical name of the spec's type or an error when the spec is invalid
+func (s CredentialSpec) validate() (string, error) {
+	name := ""
+	for n := range credentialSpecFields {
+		if strings.EqualFold(s.Type, n) {
+			name = n
+			break
+		}
+	}
+	if name == "" {
+		return "", fmt.Errorf("unknown credential type %q", s.Type)
+	}
+	if s.Timeout != "" {
+		if d, err := time.ParseDuration(s.Timeout); err != nil || d <= 0 {
+			return "", fmt.Errorf("%s: invalid timeout %q", name, s.Timeout)
+		}
+	}
+	set := map[string]bool{
+		"additionallyAllowedTenants": len(s.AdditionallyAllowedTenants) > 0,
+		"certificatePasswordEnv":     s.CertificatePasswordEnv != "",
+		"certificatePath":            s.CertificatePath != "",
+		"clientId":                   s.ClientID != "",
+		"clientSecretEnv":            s.ClientSecretEnv != "",
+		"objectId":                   s.ObjectID != "",
+		"resourceId":                 s.ResourceID != "",
+		"tenantId":                   s.TenantID != "",
+		"tokenFilePath":              s.TokenFilePath != "",
+	}
+	for _, field := range credentialSpecFields[name] {
+		delete(set, field)
+	}
+	for field, isSet := range set {
+		if isSet {
+			return "", fmt.Errorf("%s doesn't support %q", name, field)
+		}
+	}
+	if name == credNameManagedIdentity {
+		ids := 0
+		for _, id := range []string{s.ClientID, s.ObjectID, s.ResourceID} {
+			if id != "" {
+				ids++
+			}
+		}
+		if ids > 1 {
+			return "", fmt.Errorf("%s: specify only one of clientId, objectId and resourceId", name)
+		}
+	}
+	return name, nil
+}
+
+// newCredential constructs the credential described by the spec. name is the canonical name of its type.
+func (s CredentialSpec) newCredential(name string, options *CredentialChainSpecOptions) (azcore.TokenCredential, error) {
+	tenantID := s.TenantID
+	clientID := s.ClientID
+	if name == credNameCert || name == credNameSecret {
+		// WorkloadIdentityCredential reads these variables itself
+		if tenantID == "" {
+			tenantID = os.Getenv(azureTenantID)
+		}
+		if clientID == "" {
+			clientID = os.Getenv(azureClientID)
+		}
+	}
+	switch name {
+	case credNameAzureCLI:
+		return NewAzureCLICredential(&AzureCLICredentialOptions{
+			AdditionallyAllowedTenants: s.AdditionallyAllowedTenants,
+			TenantID:                   tenantID,
+		})
+	case credNameAzureDeveloperCLI:
+		return NewAzureDeveloperCLICredential(&AzureDeveloperCLICredentialOptions{
+			AdditionallyAllowedTenants: s.AdditionallyAllowedTenants,
+			TenantID:                   tenantID,
+		})
+	case credNameCert:
+		certPath := s.CertificatePath
+		if certPath == "" {
+			if certPath = os.Getenv(azureClientCertificatePath); certPath == "" {
+				return nil, fmt.Errorf("no certificate path specified and environment variable %s isn't set", azureClientCertificatePath)
+			}
+		}
+		certData, err := os.ReadFile(certPath)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to read certificate file "%s": %v`, certPath, err)
+		}
+		passwordEnv := s.CertificatePasswordEnv
+		if passwordEnv == "" {
+			passwordEnv = azureClientCertificatePassword
+		}
+		certs, key, err := ParseCertificates(certData, []byte(os.Getenv(passwordEnv)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", certPath, err)
+		}
+		return NewClientCertificateCredential(tenantID, clientID, certs, key, &ClientCertificateCredentialOptions{
+			AdditionallyAllowedTenants: s.AdditionallyAllowedTenants,
+			Cache:                      options.Cache,
+			ClientOptions:              options.ClientOptions,
+			DisableInstanceDiscovery:   options.DisableInstanceDiscovery,
+		})
+	case credNameSecret:
+		secretEnv := s.ClientSecretEnv
+		if secretEnv == "" {
+			secretEnv = azureClientSecret
+		}
+		secret := os.Getenv(secretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("environment variable %s isn't set", secretEnv)
+		}
+		return NewClientSecretCredential(tenantID, clientID, secret, &ClientSecretCredentialOptions{
+			AdditionallyAllowedTenants: s.AdditionallyAllowedTenants,
+			Cache:                      options.Cache,
+			ClientOptions:              options.ClientOptions,
+			DisableInstanceDiscovery:   options.DisableInstanceDiscovery,
+		})
+	case credNameEnvironment:
+		return NewEnvironmentCredential(&EnvironmentCredentialOptions{
+			ClientOptions:            options.ClientOptions,
+			DisableInstanceDiscovery: options.DisableInstanceDiscovery,
+		})
+	case credNameManagedIdentity:
+		o := &ManagedIdentityCredentialOptions{ClientOptions: options.ClientOptions}
+		switch {
+		case s.ClientID != "":
+			o.ID = ClientID(s.ClientID)
+		case s.ObjectID != "":
+			o.ID = ObjectID(s.ObjectID)
+		case s.ResourceID != "":
+			o.ID = ResourceID(s.ResourceID)
+		}
+		return NewManagedIdentityCredential(o)
+	case credNameWorkloadIdentity:
+		return NewWorkloadIdentityCredential(&WorkloadIdentityCredentialOptions{
+			AdditionallyAllowedTenants: s.AdditionallyAllowedTenants,
+			Cache:                      options.Cache,
+			ClientID:                   clientID,
+			ClientOptions:              options.ClientOptions,
+			DisableInstanceDiscovery:   options.DisableInstanceDiscovery,
+			TenantID:                   tenantID,
+			TokenFilePath:              s.TokenFilePath,
+		})
+	}
+	return nil, fmt.Errorf("unknown credential type %q", name)
+}
+
+// timeoutCredential limits the time a credential in a chain may spend acquiring a token. When the
+// time elapses, it reports the credential as unavailable so the chain tries its next credential.
+type timeoutCredential struct {
+	cred    azcore.TokenCredential
+	name    string
+	timeout time.Duration
+}
+
+func (t *timeoutCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	tk, err := t.cred.GetToken(c, opts)
+	if err != nil && ctx.Err() == nil && errors.Is(c.Err(), context.DeadlineExceeded) {
+		err = newCredentialUnavailableError(t.name, fmt.Sprintf("timed out after %s: %v", t.timeout, err))
+	}
+	return tk, err
+}
+
+var _ azcore.TokenCredential = (*timeoutCredential)(nil)
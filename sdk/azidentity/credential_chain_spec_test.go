@@ -0,0 +1,197 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCredentialChainSpec(t *testing.T) {
+	expected := &CredentialChainSpec{
+		RetrySources: true,
+		Sources: []CredentialSpec{
+			{Type: credNameWorkloadIdentity, TokenFilePath: "/var/run/token"},
+			{Type: credNameManagedIdentity, ClientID: "client", Timeout: "2s"},
+			{Type: credNameCert, CertificatePath: "cert.pem", CertificatePasswordEnv: "CERT_PASSWORD", TenantID: "tenant"},
+			{Type: credNameSecret, ClientSecretEnv: "SECRET", AdditionallyAllowedTenants: []string{"*"}},
+			{Type: credNameAzureCLI, TenantID: "tenant"},
+		},
+	}
+	actual, err := ParseCredentialChainSpec([]byte(`{
+		"retrySources": true,
+		"sources": [
+			{"type": "WorkloadIdentityCredential", "tokenFilePath": "/var/run/token"},
+			{"type": "ManagedIdentityCredential", "clientId": "client", "timeout": "2s"},
+			{"type": "ClientCertificateCredential", "certificatePath": "cert.pem", "certificatePasswordEnv": "CERT_PASSWORD", "tenantId": "tenant"},
+			{"type": "ClientSecretCredential", "clientSecretEnv": "SECRET", "additionallyAllowedTenants": ["*"]},
+			{"type": "AzureCLICredential", "tenantId": "tenant"}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+
+	for _, invalid := range []string{"", "[]", `{"sources": [{"type": "AzureCLICredential", "tenant": "misspelled"}]}`} {
+		_, err = ParseCredentialChainSpec([]byte(invalid))
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestCredentialChainSpecYAMLTags(t *testing.T) {
+	// YAML documents use the same field names as JSON documents
+	for _, typ := range []reflect.Type{reflect.TypeOf(CredentialChainSpec{}), reflect.TypeOf(CredentialSpec{})} {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			require.NotEmpty(t, field.Tag.Get("json"), "%s.%s", typ.Name(), field.Name)
+			require.Equal(t, field.Tag.Get("json"), field.Tag.Get("yaml"), "%s.%s", typ.Name(), field.Name)
+		}
+	}
+}
+
+func TestNewChainedTokenCredentialFromSpec(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("assertion"), 0600))
+	t.Setenv(azureClientID, fakeClientID)
+	t.Setenv(azureTenantID, fakeTenantID)
+	t.Setenv("MY_SECRET", fakeSecret)
+
+	cred, err := NewChainedTokenCredentialFromSpec(&CredentialChainSpec{
+		Sources: []CredentialSpec{
+			{Type: "workloadidentitycredential", TokenFilePath: tokenFile},
+			{Type: credNameManagedIdentity, ResourceID: "/subscriptions/.../id", Timeout: "1s"},
+			{Type: credNameSecret, ClientSecretEnv: "MY_SECRET"},
+			{Type: credNameAzureDeveloperCLI},
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, cred.sources, 4)
+	require.IsType(t, &WorkloadIdentityCredential{}, cred.sources[0])
+	require.IsType(t, &timeoutCredential{}, cred.sources[1])
+	mi := cred.sources[1].(*timeoutCredential)
+	require.Equal(t, time.Second, mi.timeout)
+	require.Equal(t, credNameManagedIdentity, extractCredentialName(mi))
+	require.IsType(t, &ManagedIdentityCredential{}, mi.cred)
+	require.True(t, mi.cred.(*ManagedIdentityCredential).mic.chained)
+	require.True(t, mi.cred.(*ManagedIdentityCredential).mic.userAssigned)
+	require.IsType(t, &ClientSecretCredential{}, cred.sources[2])
+	require.IsType(t, &AzureDeveloperCLICredential{}, cred.sources[3])
+
+	for name, spec := range map[string]*CredentialChainSpec{
+		"nil":                nil,
+		"no sources":         {},
+		"unknown type":       {Sources: []CredentialSpec{{Type: "PasswordCredential"}}},
+		"invalid timeout":    {Sources: []CredentialSpec{{Type: credNameAzureCLI, Timeout: "soon"}}},
+		"negative timeout":   {Sources: []CredentialSpec{{Type: credNameAzureCLI, Timeout: "-1s"}}},
+		"inapplicable field": {Sources: []CredentialSpec{{Type: credNameAzureCLI, ClientID: "client"}}},
+		"multiple IDs":       {Sources: []CredentialSpec{{Type: credNameManagedIdentity, ClientID: "client", ObjectID: "object"}}},
+	} {
+		_, err = NewChainedTokenCredentialFromSpec(spec, nil)
+		require.Error(t, err, name)
+	}
+}
+
+func TestNewChainedTokenCredentialFromSpec_GetToken(t *testing.T) {
+	t.Setenv(azureClientID, fakeClientID)
+	t.Setenv(azureTenantID, fakeTenantID)
+	t.Setenv("MY_SECRET", fakeSecret)
+	sts := mockSTS{
+		tokenRequestCallback: func(req *http.Request) *http.Response {
+			require.NoError(t, req.ParseForm())
+			require.Equal(t, fakeSecret, req.PostForm.Get("client_secret"))
+			return nil
+		},
+	}
+	cred, err := NewChainedTokenCredentialFromSpec(&CredentialChainSpec{
+		Sources: []CredentialSpec{
+			// the certificate doesn't exist, so the chain should try the next source
+			{Type: credNameCert, CertificatePath: filepath.Join(t.TempDir(), "missing.pem")},
+			{Type: credNameSecret, ClientSecretEnv: "MY_SECRET"},
+		},
+	}, &CredentialChainSpecOptions{ClientOptions: policy.ClientOptions{Transport: &sts}})
+	require.NoError(t, err)
+	require.IsType(t, &defaultCredentialErrorReporter{}, cred.sources[0])
+
+	d := cred.Diagnose(ctx, testTRO)
+	require.NoError(t, d.Err)
+	require.Len(t, d.Sources, 2)
+	require.Equal(t, credNameCert, d.Sources[0].Credential)
+	require.True(t, d.Sources[0].Unavailable)
+	require.ErrorContains(t, d.Sources[0].Err, "missing.pem")
+	require.Equal(t, credNameSecret, d.Sources[1].Credential)
+
+	// a missing secret makes the source unavailable
+	cred, err = NewChainedTokenCredentialFromSpec(&CredentialChainSpec{
+		Sources: []CredentialSpec{{Type: credNameSecret, ClientSecretEnv: "NOT_SET"}},
+	}, nil)
+	require.NoError(t, err)
+	_, err = cred.GetToken(ctx, testTRO)
+	var unavailable credentialUnavailable
+	require.ErrorAs(t, err, &unavailable)
+	require.ErrorContains(t, err, "NOT_SET")
+}
+
+// slowCredential returns an error when its context is done
+type slowCredential struct{}
+
+func (slowCredential) GetToken(ctx context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	<-ctx.Done()
+	return azcore.AccessToken{}, ctx.Err()
+}
+
+func TestTimeoutCredential(t *testing.T) {
+	slow := slowCredential{}
+	fast := NewFakeCredential()
+	fast.SetResponse(azcore.AccessToken{Token: tokenValue, ExpiresOn: time.Now().Add(time.Hour)}, nil)
+	cred, err := NewChainedTokenCredential([]azcore.TokenCredential{
+		&timeoutCredential{cred: slow, name: credNameManagedIdentity, timeout: time.Millisecond},
+		fast,
+	}, nil)
+	require.NoError(t, err)
+	d := cred.Diagnose(ctx, testTRO)
+	require.NoError(t, d.Err)
+	require.True(t, d.Sources[0].Unavailable)
+	require.ErrorContains(t, d.Sources[0].Err, "timed out after 1ms")
+
+	// the caller's context expiring isn't a timeout
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	tc := &timeoutCredential{cred: slow, name: credNameManagedIdentity, timeout: time.Hour}
+	_, err = tc.GetToken(canceled, testTRO)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewChainedTokenCredentialFromEnvironment(t *testing.T) {
+	t.Setenv(azureCredentialChain, "")
+	_, err := NewChainedTokenCredentialFromEnvironment(nil)
+	require.ErrorContains(t, err, azureCredentialChain)
+
+	t.Setenv(azureCredentialChain, "ManagedIdentityCredential, AzureCLICredential")
+	cred, err := NewChainedTokenCredentialFromEnvironment(nil)
+	require.NoError(t, err)
+	require.Len(t, cred.sources, 2)
+	require.IsType(t, &ManagedIdentityCredential{}, cred.sources[0])
+	require.IsType(t, &AzureCLICredential{}, cred.sources[1])
+
+	t.Setenv(azureCredentialChain, `{"retrySources": true, "sources": [{"type": "AzureCLICredential", "timeout": "10s"}]}`)
+	cred, err = NewChainedTokenCredentialFromEnvironment(nil)
+	require.NoError(t, err)
+	require.True(t, cred.retrySources)
+	require.IsType(t, &timeoutCredential{}, cred.sources[0])
+
+	for _, invalid := range []string{"ManagedIdentityCredential,", `{"sources": [{"type": "AzureCLICredential", "timeout": 10}]}`} {
+		t.Setenv(azureCredentialChain, invalid)
+		_, err = NewChainedTokenCredentialFromEnvironment(nil)
+		require.Error(t, err, invalid)
+	}
+}
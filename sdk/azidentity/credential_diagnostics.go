@@ -0,0 +1,221 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// CredentialDiagnostics describes an authentication attempt by a credential chain such as [DefaultAzureCredential].
+// It's intended for troubleshooting, for example by an application's debug endpoint, and so doesn't include the
+// access token. Its JSON representation is suitable for such an endpoint.
+type CredentialDiagnostics struct {
+	// Credential is the type of the credential chain, for example "DefaultAzureCredential".
+	Credential string
+
+	// Duration is the time the attempt took.
+	Duration time.Duration
+
+	// Err is the error the chain's GetToken method would have returned. It's nil when a source authenticated.
+	Err error
+
+	// Sources describes the attempt of each source the chain tried, in order. The chain tries sources until one
+	// authenticates or returns an error other than one indicating it's unavailable, so this doesn't include
+	// sources after that one.
+	Sources []SourceDiagnostics
+
+	// Token describes the token acquired by the successful source. It's nil when no source authenticated.
+	Token *TokenDiagnostics
+}
+
+// MarshalJSON implements the json.Marshaler interface for CredentialDiagnostics.
+func (c CredentialDiagnostics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Credential string              `json:"credential"`
+		Duration   string              `json:"duration"`
+		Error      string              `json:"error,omitempty"`
+		Sources    []SourceDiagnostics `json:"sources"`
+		Token      *TokenDiagnostics   `json:"token,omitempty"`
+	}{
+		Credential: c.Credential,
+		Duration:   c.Duration.String(),
+		Error:      errorString(c.Err),
+		Sources:    c.Sources,
+		Token:      c.Token,
+	})
+}
+
+// SourceDiagnostics describes the authentication attempt of one credential in a chain.
+type SourceDiagnostics struct {
+	// Credential is the type of the credential, for example "ManagedIdentityCredential".
+	Credential string
+
+	// Duration is the time the attempt took.
+	Duration time.Duration
+
+	// Err is the error returned by the credential. It's nil when the credential authenticated.
+	Err error
+
+	// Unavailable is true when the credential reported it couldn't attempt authentication, for example because
+	// it isn't configured or the service it depends on isn't available. Err explains why.
+	Unavailable bool
+}
+
+// MarshalJSON implements the json.Marshaler interface for SourceDiagnostics.
+func (s SourceDiagnostics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Credential  string `json:"credential"`
+		Duration    string `json:"duration"`
+		Error       string `json:"error,omitempty"`
+		Unavailable bool   `json:"unavailable,omitempty"`
+	}{
+		Credential:  s.Credential,
+		Duration:    s.Duration.String(),
+		Error:       errorString(s.Err),
+		Unavailable: s.Unavailable,
+	})
+}
+
+// TokenDiagnostics describes an access token. Fields other than ExpiresOn, FromCache and Credential are read from
+// the token's claims without validating it. They're empty when the token isn't a JWT or doesn't have the claim.
+type TokenDiagnostics struct {
+	// AppID is the ID of the application for which the token was issued (the "appid" or "azp" claim).
+	AppID string `json:"appId,omitempty"`
+
+	// Credential is the type of the credential that acquired the token.
+	Credential string `json:"credential"`
+
+	// ExpiresOn is when the token expires.
+	ExpiresOn time.Time `json:"expiresOn"`
+
+	// FromCache indicates whether the credential retrieved the token from its cache. It's nil when the credential
+	// doesn't report this, for example because it gets tokens from a developer tool.
+	FromCache *bool `json:"fromCache,omitempty"`
+
+	// ObjectID is the object ID of the authenticated principal (the "oid" claim).
+	ObjectID string `json:"objectId,omitempty"`
+
+	// Roles are the application permissions granted to the principal (the "roles" claim).
+	Roles []string `json:"roles,omitempty"`
+
+	// Scopes are the delegated permissions granted to the principal (the "scp" claim).
+	Scopes []string `json:"scopes,omitempty"`
+
+	// TenantID is the ID of the tenant that issued the token (the "tid" claim).
+	TenantID string `json:"tenantId,omitempty"`
+}
+
+// Diagnose attempts to authenticate through each source in turn, like GetToken, and reports the outcome of
+// each attempt and claims of the acquired token. Diagnose always tries the sources in order; it doesn't use,
+// or affect which source GetToken uses.
+func (c *ChainedTokenCredential) Diagnose(ctx context.Context, opts policy.TokenRequestOptions) CredentialDiagnostics {
+	d := CredentialDiagnostics{Credential: c.name}
+	start := time.Now()
+	var errs []error
+	for _, cred := range c.sources {
+		sd := SourceDiagnostics{Credential: sourceName(cred)}
+		ts := &tokenSource{}
+		sourceStart := time.Now()
+		tk, err := cred.GetToken(context.WithValue(ctx, tokenSourceKey{}, ts), opts)
+		sd.Duration = time.Since(sourceStart)
+		if err == nil {
+			d.Sources = append(d.Sources, sd)
+			d.Token = newTokenDiagnostics(sd.Credential, tk, ts.fromCache)
+			break
+		}
+		sd.Err = err
+		errs = append(errs, err)
+		var unavailableErr credentialUnavailable
+		sd.Unavailable = errors.As(err, &unavailableErr)
+		d.Sources = append(d.Sources, sd)
+		if !sd.Unavailable {
+			break
+		}
+	}
+	if d.Token == nil {
+		d.Err = c.chainError(errs)
+	}
+	d.Duration = time.Since(start)
+	return d
+}
+
+// Diagnose attempts to authenticate through each credential in the chain, like GetToken, and reports the
+// outcome of each attempt and claims of the acquired token. See [ChainedTokenCredential.Diagnose].
+func (c *DefaultAzureCredential) Diagnose(ctx context.Context, opts policy.TokenRequestOptions) CredentialDiagnostics {
+	return c.chain.Diagnose(ctx, opts)
+}
+
+// tokenSourceKey is the context key for a *tokenSource
+type tokenSourceKey struct{}
+
+// tokenSource is where credentials record whether they retrieved a token from a cache
+type tokenSource struct {
+	fromCache *bool
+}
+
+// recordTokenSource records whether a credential retrieved a token from its cache,
+// when ctx is from Diagnose. It's a no-op otherwise.
+func recordTokenSource(ctx context.Context, fromCache bool) {
+	if ts, ok := ctx.Value(tokenSourceKey{}).(*tokenSource); ok {
+		ts.fromCache = &fromCache
+	}
+}
+
+func newTokenDiagnostics(credential string, tk azcore.AccessToken, fromCache *bool) *TokenDiagnostics {
+	td := &TokenDiagnostics{Credential: credential, ExpiresOn: tk.ExpiresOn, FromCache: fromCache}
+	parts := strings.Split(tk.Token, ".")
+	if len(parts) != 3 {
+		return td
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return td
+	}
+	claims := struct {
+		AppID    string   `json:"appid"`
+		AZP      string   `json:"azp"`
+		OID      string   `json:"oid"`
+		Roles    []string `json:"roles"`
+		SCP      string   `json:"scp"`
+		TenantID string   `json:"tid"`
+	}{}
+	if json.Unmarshal(payload, &claims) != nil {
+		return td
+	}
+	td.AppID = claims.AppID
+	if td.AppID == "" {
+		// v2.0 tokens have azp instead of appid
+		td.AppID = claims.AZP
+	}
+	td.ObjectID = claims.OID
+	td.Roles = claims.Roles
+	if claims.SCP != "" {
+		td.Scopes = strings.Fields(claims.SCP)
+	}
+	td.TenantID = claims.TenantID
+	return td
+}
+
+// sourceName returns the type name of a credential in a chain
+func sourceName(cred azcore.TokenCredential) string {
+	if r, ok := cred.(*defaultCredentialErrorReporter); ok {
+		return r.credType
+	}
+	return extractCredentialName(cred)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
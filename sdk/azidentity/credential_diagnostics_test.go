@@ -0,0 +1,137 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainedTokenCredential_Diagnose(t *testing.T) {
+	claims := `{"appid":"app","oid":"object","roles":["Reader","Writer"],"scp":"user.read  mail.send","tid":"tenant"}`
+	jwt := "header." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+	expiresOn := time.Now().Add(time.Hour).UTC()
+	unavailable := NewFakeCredential()
+	unavailable.SetResponse(azcore.AccessToken{}, newCredentialUnavailableError("unavailable", "not configured"))
+	success := NewFakeCredential()
+	success.SetResponse(azcore.AccessToken{Token: jwt, ExpiresOn: expiresOn}, nil)
+	cred, err := NewChainedTokenCredential([]azcore.TokenCredential{unavailable, success}, nil)
+	require.NoError(t, err)
+
+	d := cred.Diagnose(ctx, testTRO)
+	require.NoError(t, d.Err)
+	require.Equal(t, "ChainedTokenCredential", d.Credential)
+	require.Positive(t, d.Duration)
+	require.Len(t, d.Sources, 2)
+	require.Equal(t, "fakeCredential", d.Sources[0].Credential)
+	require.True(t, d.Sources[0].Unavailable)
+	require.ErrorContains(t, d.Sources[0].Err, "not configured")
+	require.NoError(t, d.Sources[1].Err)
+	require.False(t, d.Sources[1].Unavailable)
+	require.Equal(t, &TokenDiagnostics{
+		AppID:      "app",
+		Credential: "fakeCredential",
+		ExpiresOn:  expiresOn,
+		ObjectID:   "object",
+		Roles:      []string{"Reader", "Writer"},
+		Scopes:     []string{"user.read", "mail.send"},
+		TenantID:   "tenant",
+	}, d.Token)
+
+	// Diagnose doesn't affect which source GetToken uses
+	require.Nil(t, cred.successfulCredential)
+
+	b, err := json.Marshal(d)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), jwt)
+	actual := map[string]any{}
+	require.NoError(t, json.Unmarshal(b, &actual))
+	require.Equal(t, "ChainedTokenCredential", actual["credential"])
+	require.Contains(t, actual["sources"].([]any)[0].(map[string]any)["error"], "not configured")
+	require.Equal(t, "object", actual["token"].(map[string]any)["objectId"])
+
+	// tokens that aren't JWTs have no claims
+	success.SetResponse(azcore.AccessToken{Token: tokenValue, ExpiresOn: expiresOn}, nil)
+	d = cred.Diagnose(ctx, testTRO)
+	require.NoError(t, d.Err)
+	require.Equal(t, &TokenDiagnostics{Credential: "fakeCredential", ExpiresOn: expiresOn}, d.Token)
+}
+
+func TestChainedTokenCredential_DiagnoseFailure(t *testing.T) {
+	unavailable := NewFakeCredential()
+	unavailable.SetResponse(azcore.AccessToken{}, newCredentialUnavailableError("unavailable", "not configured"))
+	failed := NewFakeCredential()
+	failed.SetResponse(azcore.AccessToken{}, newAuthenticationFailedError("failed", "invalid secret", nil))
+	notTried := NewFakeCredential()
+	cred, err := NewChainedTokenCredential([]azcore.TokenCredential{unavailable, failed, notTried}, nil)
+	require.NoError(t, err)
+
+	d := cred.Diagnose(ctx, testTRO)
+	require.Nil(t, d.Token)
+	var af *AuthenticationFailedError
+	require.ErrorAs(t, d.Err, &af)
+	require.ErrorContains(t, d.Err, "not configured")
+	require.ErrorContains(t, d.Err, "invalid secret")
+	require.Len(t, d.Sources, 2)
+	require.False(t, d.Sources[1].Unavailable)
+	require.Zero(t, notTried.getTokenCalls)
+
+	// the error matches GetToken's
+	_, err = cred.GetToken(ctx, testTRO)
+	require.EqualError(t, err, d.Err.Error())
+}
+
+func TestChainedTokenCredential_DiagnoseCache(t *testing.T) {
+	secret, err := NewClientSecretCredential(fakeTenantID, fakeClientID, fakeSecret, &ClientSecretCredentialOptions{
+		ClientOptions: policy.ClientOptions{Transport: &mockSTS{}},
+	})
+	require.NoError(t, err)
+	cred, err := NewChainedTokenCredential([]azcore.TokenCredential{secret}, nil)
+	require.NoError(t, err)
+	for _, fromCache := range []bool{false, true} {
+		d := cred.Diagnose(ctx, testTRO)
+		require.NoError(t, d.Err)
+		require.Equal(t, credNameSecret, d.Sources[0].Credential)
+		require.NotNil(t, d.Token.FromCache)
+		require.Equal(t, fromCache, *d.Token.FromCache)
+	}
+}
+
+func TestDefaultAzureCredential_Diagnose(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "service-account-token-file")
+	require.NoError(t, os.WriteFile(tempFile, []byte("assertion"), os.ModePerm))
+	for k, v := range map[string]string{
+		azureAuthorityHost:      cloud.AzurePublic.ActiveDirectoryAuthorityHost,
+		azureClientID:           fakeClientID,
+		azureFederatedTokenFile: tempFile,
+		azureTenantID:           fakeTenantID,
+	} {
+		t.Setenv(k, v)
+	}
+	cred, err := NewDefaultAzureCredential(&DefaultAzureCredentialOptions{ClientOptions: policy.ClientOptions{Transport: &mockSTS{}}})
+	require.NoError(t, err)
+
+	d := cred.Diagnose(ctx, testTRO)
+	require.NoError(t, d.Err)
+	require.Equal(t, "DefaultAzureCredential", d.Credential)
+	require.Len(t, d.Sources, 2)
+	require.Equal(t, credNameEnvironment, d.Sources[0].Credential)
+	require.True(t, d.Sources[0].Unavailable)
+	require.Equal(t, credNameWorkloadIdentity, d.Sources[1].Credential)
+	require.NotNil(t, d.Token.FromCache)
+	require.False(t, *d.Token.FromCache)
+
+	var unavailable credentialUnavailable
+	require.True(t, errors.As(d.Sources[0].Err, &unavailable))
+}
@@ -190,6 +190,7 @@ func (c *managedIdentityClient) GetToken(ctx context.Context, tro policy.TokenRe
 	if err == nil {
 		msg := fmt.Sprintf(scopeLogFmt, credNameManagedIdentity, strings.Join(ar.GrantedScopes, ", "))
 		log.Write(EventAuthentication, msg)
+		recordTokenSource(ctx, ar.Metadata.TokenSource == managedidentity.TokenSourceCache)
 		return azcore.AccessToken{Token: ar.AccessToken, ExpiresOn: ar.ExpiresOn.UTC(), RefreshOn: ar.Metadata.RefreshOn.UTC()}, err
 	}
 	if c.imds {
@@ -149,7 +149,7 @@ func (p *publicClient) GetToken(ctx context.Context, tro policy.TokenRequestOpti
 	defer mu.Unlock()
 	ar, err := client.AcquireTokenSilent(ctx, tro.Scopes, public.WithSilentAccount(p.record.account()), public.WithClaims(tro.Claims), public.WithTenantID(tenant))
 	if err == nil {
-		return p.token(ar, err)
+		return p.token(ctx, ar, err)
 	}
 	if p.opts.DisableAutomaticAuthentication {
 		return azcore.AccessToken{}, newAuthenticationRequiredError(p.name, tro)
@@ -190,7 +190,7 @@ func (p *publicClient) reqToken(ctx context.Context, c msalPublicClient, tro pol
 	default:
 		return azcore.AccessToken{}, fmt.Errorf("unknown credential %q", p.name)
 	}
-	return p.token(ar, err)
+	return p.token(ctx, ar, err)
 }
 
 func (p *publicClient) client(tro policy.TokenRequestOptions) (msalPublicClient, *sync.Mutex, error) {
@@ -235,10 +235,11 @@ func (p *publicClient) newMSALClient(enableCAE bool) (msalPublicClient, error) {
 	return public.New(p.clientID, o...)
 }
 
-func (p *publicClient) token(ar public.AuthResult, err error) (azcore.AccessToken, error) {
+func (p *publicClient) token(ctx context.Context, ar public.AuthResult, err error) (azcore.AccessToken, error) {
 	if err == nil {
 		msg := fmt.Sprintf(scopeLogFmt, p.name, strings.Join(ar.GrantedScopes, ", "))
 		log.Write(EventAuthentication, msg)
+		recordTokenSource(ctx, ar.Metadata.TokenSource == public.TokenSourceCache)
 		p.record, err = newAuthenticationRecord(ar)
 	} else {
 		err = newAuthenticationFailedErrorFromMSAL(p.name, err)
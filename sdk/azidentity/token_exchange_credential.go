@@ -0,0 +1,297 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+const credNameTokenExchange = "TokenExchangeCredential"
+
+const (
+	// assertionRefreshMargin is how long before an assertion expires the credential gets a new one
+	assertionRefreshMargin = 5 * time.Minute
+	// assertionCacheDuration is how long the credential uses an assertion whose expiration it can't determine
+	assertionCacheDuration = 10 * time.Minute
+)
+
+// AssertionSource provides the assertions a [TokenExchangeCredential] exchanges for access tokens. Construct
+// one with [NewEnvAssertionSource], [NewFileAssertionSource] or [NewHTTPAssertionSource].
+type AssertionSource struct {
+	// description identifies the source in error messages
+	description string
+	get         func(context.Context, *azcore.Client) (string, error)
+}
+
+// NewEnvAssertionSource returns an AssertionSource that reads assertions from the environment variable
+// with the given name. The credential reads the variable each time it needs a new assertion.
+func NewEnvAssertionSource(name string) AssertionSource {
+	return AssertionSource{
+		description: fmt.Sprintf("environment variable %s", name),
+		get: func(context.Context, *azcore.Client) (string, error) {
+			v := os.Getenv(name)
+			if v == "" {
+				return "", newCredentialUnavailableError(credNameTokenExchange, fmt.Sprintf("no value for environment variable %s", name))
+			}
+			return v, nil
+		},
+	}
+}
+
+// NewFileAssertionSource returns an AssertionSource that reads assertions from the file at the given path,
+// for example a SPIFFE JWT-SVID or a token projected by an orchestrator. The credential reads the file each
+// time it needs a new assertion, so another process may update the file as assertions expire.
+func NewFileAssertionSource(path string) AssertionSource {
+	return AssertionSource{
+		description: fmt.Sprintf("file %q", path),
+		get: func(context.Context, *azcore.Client) (string, error) {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return "", newCredentialUnavailableError(credNameTokenExchange, err.Error())
+				}
+				return "", err
+			}
+			return string(content), nil
+		},
+	}
+}
+
+// HTTPAssertionSourceOptions contains optional parameters for NewHTTPAssertionSource.
+type HTTPAssertionSourceOptions struct {
+	// Headers are added to each request. For example, an endpoint may require an Authorization header.
+	Headers map[string]string
+
+	// JSONField is the name of the top level field containing the assertion in the endpoint's JSON
+	// response, for example "value" for GitHub Actions. When empty, the credential uses the entire
+	// response body as the assertion.
+	JSONField string
+
+	// Method is the HTTP method of the request. Defaults to GET.
+	Method string
+}
+
+// NewHTTPAssertionSource returns an AssertionSource that requests assertions from the given URL, for example
+// the OIDC token endpoint of a CI system. The credential sends requests through its pipeline, so they're
+// subject to the credential's retry, logging and transport options. Pass nil for options to accept defaults.
+//
+// For example, a GitHub Actions workflow having the "id-token: write" permission can get assertions like this:
+//
+//	u := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") + "&audience=api://AzureADTokenExchange"
+//	source := azidentity.NewHTTPAssertionSource(u, &azidentity.HTTPAssertionSourceOptions{
+//		Headers:   map[string]string{"Authorization": "Bearer " + os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")},
+//		JSONField: "value",
+//	})
+func NewHTTPAssertionSource(url string, options *HTTPAssertionSourceOptions) AssertionSource {
+	if options == nil {
+		options = &HTTPAssertionSourceOptions{}
+	}
+	method := options.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	headers := make(map[string]string, len(options.Headers))
+	for k, v := range options.Headers {
+		headers[k] = v
+	}
+	field := options.JSONField
+	return AssertionSource{
+		description: "assertion endpoint",
+		get: func(ctx context.Context, client *azcore.Client) (string, error) {
+			req, err := http.NewRequestWithContext(ctx, method, url, nil)
+			if err != nil {
+				return "", fmt.Errorf("couldn't create assertion request: %w", err)
+			}
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+			res, err := doForClient(client, req)
+			if err != nil {
+				return "", fmt.Errorf("couldn't send assertion request: %w", err)
+			}
+			if res.StatusCode != http.StatusOK {
+				// include the response because its body, if any, probably contains an error message.
+				// OK responses aren't included with errors because they probably contain secrets
+				return "", newAuthenticationFailedError(credNameTokenExchange, res.Status+" response from the assertion endpoint", res)
+			}
+			b, err := runtime.Payload(res)
+			if err != nil {
+				return "", fmt.Errorf("couldn't read assertion response: %w", err)
+			}
+			if field == "" {
+				return string(b), nil
+			}
+			r := map[string]any{}
+			if err = json.Unmarshal(b, &r); err != nil {
+				return "", errors.New("assertion endpoint returned invalid JSON")
+			}
+			s, ok := r[field].(string)
+			if !ok {
+				return "", fmt.Errorf("assertion endpoint response has no string field %q", field)
+			}
+			return s, nil
+		},
+	}
+}
+
+// TokenExchangeCredential authenticates a service principal or user-assigned managed identity with workload
+// identity federation, exchanging a token from an external OpenID Connect identity provider such as GitHub
+// Actions, GitLab, SPIFFE or a custom provider for a Microsoft Entra access token. The application must have
+// a federated identity credential trusting the provider. See [Microsoft Entra ID documentation] for details.
+//
+// The credential gets assertions from an [AssertionSource] and reuses each until shortly before it expires.
+// [WorkloadIdentityCredential] and [AzurePipelinesCredential] are more convenient in Kubernetes and Azure
+// Pipelines respectively.
+//
+// [Microsoft Entra ID documentation]: https://learn.microsoft.com/entra/workload-id/workload-identity-federation
+type TokenExchangeCredential struct {
+	cred   *ClientAssertionCredential
+	source AssertionSource
+
+	assertion          string
+	expires, refreshOn time.Time
+	mtx                *sync.Mutex
+}
+
+// TokenExchangeCredentialOptions contains optional parameters for TokenExchangeCredential.
+type TokenExchangeCredentialOptions struct {
+	azcore.ClientOptions
+
+	// AdditionallyAllowedTenants specifies additional tenants for which the credential may acquire tokens.
+	// Add the wildcard value "*" to allow the credential to acquire tokens for any tenant in which the
+	// application is registered.
+	AdditionallyAllowedTenants []string
+
+	// Cache is a persistent cache the credential will use to store the tokens it acquires, making
+	// them available to other processes and credential instances. The default, zero value means the
+	// credential will store tokens in memory and not share them with any other credential instance.
+	Cache Cache
+
+	// DisableInstanceDiscovery should be set true only by applications authenticating in disconnected clouds, or
+	// private clouds such as Azure Stack. It determines whether the credential requests Microsoft Entra instance metadata
+	// from https://login.microsoft.com before authenticating. Setting this to true will skip this request, making
+	// the application responsible for ensuring the configured authority is valid and trustworthy.
+	DisableInstanceDiscovery bool
+}
+
+// NewTokenExchangeCredential constructs a TokenExchangeCredential. Pass nil for options to accept defaults.
+//
+//   - tenantID: tenant ID of the application or managed identity having the federated identity credential
+//   - clientID: client ID of that application or managed identity
+//   - source: provides assertions issued by the external identity provider
+func NewTokenExchangeCredential(tenantID, clientID string, source AssertionSource, options *TokenExchangeCredentialOptions) (*TokenExchangeCredential, error) {
+	if !validTenantID(tenantID) {
+		return nil, errInvalidTenantID
+	}
+	if clientID == "" {
+		return nil, errors.New("no client ID specified")
+	}
+	if source.get == nil {
+		return nil, errors.New("source must be an AssertionSource returned by one of this package's constructors")
+	}
+	if options == nil {
+		options = &TokenExchangeCredentialOptions{}
+	}
+	t := TokenExchangeCredential{mtx: &sync.Mutex{}, source: source}
+	caco := ClientAssertionCredentialOptions{
+		AdditionallyAllowedTenants: options.AdditionallyAllowedTenants,
+		Cache:                      options.Cache,
+		ClientOptions:              options.ClientOptions,
+		DisableInstanceDiscovery:   options.DisableInstanceDiscovery,
+	}
+	cred, err := NewClientAssertionCredential(tenantID, clientID, t.getAssertion, &caco)
+	if err != nil {
+		return nil, err
+	}
+	cred.client.name = credNameTokenExchange
+	t.cred = cred
+	return &t, nil
+}
+
+// GetToken requests an access token from Microsoft Entra ID. Azure SDK clients call this method automatically.
+func (t *TokenExchangeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	var err error
+	ctx, endSpan := runtime.StartSpan(ctx, credNameTokenExchange+"."+traceOpGetToken, t.cred.client.azClient.Tracer(), nil)
+	defer func() { endSpan(err) }()
+	tk, err := t.cred.client.GetToken(ctx, opts)
+	return tk, err
+}
+
+// getAssertion returns the cached assertion, getting a new one from the source when the cached
+// assertion is about to expire. When that fails, it returns the cached assertion if it's still valid.
+func (t *TokenExchangeCredential) getAssertion(ctx context.Context) (string, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	now := time.Now()
+	if now.Before(t.refreshOn) {
+		return t.assertion, nil
+	}
+	assertion, err := t.source.get(ctx, t.cred.client.azClient)
+	if err == nil {
+		assertion = strings.TrimSpace(assertion)
+		if assertion == "" {
+			err = errors.New("assertion is empty")
+		}
+	}
+	if err != nil {
+		if t.assertion != "" && now.Before(t.expires) {
+			return t.assertion, nil
+		}
+		var (
+			unavailableErr credentialUnavailable
+			authFailedErr  *AuthenticationFailedError
+		)
+		if !(errors.As(err, &unavailableErr) || errors.As(err, &authFailedErr)) {
+			err = newAuthenticationFailedError(credNameTokenExchange, fmt.Sprintf("couldn't get an assertion from %s: %s", t.source.description, err), nil)
+		}
+		return "", err
+	}
+	t.assertion = assertion
+	t.expires, t.refreshOn = assertionLifetime(assertion, now)
+	return assertion, nil
+}
+
+// assertionLifetime returns when an assertion expires and when the credential should replace it. The
+// credential replaces an assertion before it expires, or after halfway through its remaining lifetime
+// when that's shorter than assertionRefreshMargin. When the assertion isn't a JWT having an "exp" claim,
+// the credential uses it for assertionCacheDuration.
+func assertionLifetime(assertion string, now time.Time) (expires, refreshOn time.Time) {
+	expires = now.Add(assertionCacheDuration)
+	refreshOn = expires
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		return expires, refreshOn
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return expires, refreshOn
+	}
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return expires, refreshOn
+	}
+	expires = time.Unix(claims.Exp, 0)
+	margin := assertionRefreshMargin
+	if remaining := expires.Sub(now); remaining < 2*margin {
+		margin = remaining / 2
+	}
+	return expires, expires.Add(-margin)
+}
+
+var _ azcore.TokenCredential = (*TokenExchangeCredential)(nil)
@@ -0,0 +1,180 @@
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT License.
+
+package azidentity
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+// assertionEndpoint serves assertions from assertionHost and sends other requests to an STS
+type assertionEndpoint struct {
+	assertions []string
+	requests   []*http.Request
+	status     int
+	sts        *mockSTS
+}
+
+const assertionHost = "assertion.localhost"
+
+func (a *assertionEndpoint) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != assertionHost {
+		return a.sts.Do(req)
+	}
+	a.requests = append(a.requests, req)
+	res := &http.Response{Request: req, StatusCode: http.StatusOK, Status: "200 OK"}
+	if a.status != 0 {
+		res.StatusCode, res.Status = a.status, http.StatusText(a.status)
+	}
+	body := ""
+	if len(a.assertions) > 0 {
+		body = fmt.Sprintf(`{"value":%q}`, a.assertions[0])
+		a.assertions = a.assertions[1:]
+	}
+	res.Body = io.NopCloser(strings.NewReader(body))
+	return res, nil
+}
+
+func testJWT(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return "header." + payload + ".signature"
+}
+
+func TestTokenExchangeCredential(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-assertion\n"), 0600))
+	t.Setenv("TEST_ASSERTION", "env-assertion")
+	endpoint := &assertionEndpoint{assertions: []string{"http-assertion"}}
+	for expected, source := range map[string]AssertionSource{
+		"env-assertion":  NewEnvAssertionSource("TEST_ASSERTION"),
+		"file-assertion": NewFileAssertionSource(tokenFile),
+		"http-assertion": NewHTTPAssertionSource("https://"+assertionHost+"/token?audience=api://AzureADTokenExchange", &HTTPAssertionSourceOptions{
+			Headers:   map[string]string{"Authorization": "Bearer request-token"},
+			JSONField: "value",
+		}),
+	} {
+		t.Run(expected, func(t *testing.T) {
+			endpoint.sts = &mockSTS{
+				tokenRequestCallback: func(req *http.Request) *http.Response {
+					require.NoError(t, req.ParseForm())
+					require.Equal(t, expected, req.PostForm.Get("client_assertion"))
+					return nil
+				},
+			}
+			cred, err := NewTokenExchangeCredential(fakeTenantID, fakeClientID, source, &TokenExchangeCredentialOptions{
+				ClientOptions: policy.ClientOptions{Transport: endpoint},
+			})
+			require.NoError(t, err)
+			tk, err := cred.GetToken(ctx, testTRO)
+			require.NoError(t, err)
+			require.Equal(t, tokenValue, tk.Token)
+		})
+	}
+	require.Len(t, endpoint.requests, 1)
+	require.Equal(t, http.MethodGet, endpoint.requests[0].Method)
+	require.Equal(t, "Bearer request-token", endpoint.requests[0].Header.Get("Authorization"))
+	require.Equal(t, "api://AzureADTokenExchange", endpoint.requests[0].URL.Query().Get("audience"))
+
+	for name, args := range map[string]struct {
+		tenantID, clientID string
+		source             AssertionSource
+	}{
+		"invalid tenant": {"not a tenant", fakeClientID, NewEnvAssertionSource("TEST_ASSERTION")},
+		"no client ID":   {fakeTenantID, "", NewEnvAssertionSource("TEST_ASSERTION")},
+		"zero source":    {fakeTenantID, fakeClientID, AssertionSource{}},
+	} {
+		_, err := NewTokenExchangeCredential(args.tenantID, args.clientID, args.source, nil)
+		require.Error(t, err, name)
+	}
+}
+
+func TestTokenExchangeCredential_Refresh(t *testing.T) {
+	first, second := testJWT(time.Now().Add(time.Hour)), testJWT(time.Now().Add(2*time.Hour))
+	endpoint := &assertionEndpoint{assertions: []string{first, second}, sts: &mockSTS{}}
+	source := NewHTTPAssertionSource("https://"+assertionHost, &HTTPAssertionSourceOptions{JSONField: "value", Method: http.MethodPost})
+	cred, err := NewTokenExchangeCredential(fakeTenantID, fakeClientID, source, &TokenExchangeCredentialOptions{
+		ClientOptions: policy.ClientOptions{Transport: endpoint},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		actual, err := cred.getAssertion(ctx)
+		require.NoError(t, err)
+		require.Equal(t, first, actual)
+	}
+	require.Len(t, endpoint.requests, 1)
+	require.Equal(t, http.MethodPost, endpoint.requests[0].Method)
+
+	// the credential gets a new assertion shortly before the cached one expires
+	cred.refreshOn = time.Now().Add(-time.Second)
+	actual, err := cred.getAssertion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, second, actual)
+
+	// when it can't get a new assertion, it uses the cached one until that expires
+	endpoint.status = http.StatusInternalServerError
+	cred.refreshOn = time.Now().Add(-time.Second)
+	actual, err = cred.getAssertion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, second, actual)
+
+	cred.expires = time.Now().Add(-time.Second)
+	_, err = cred.getAssertion(ctx)
+	var af *AuthenticationFailedError
+	require.ErrorAs(t, err, &af)
+	require.Equal(t, http.StatusInternalServerError, af.RawResponse.StatusCode)
+}
+
+func TestTokenExchangeCredential_SourceErrors(t *testing.T) {
+	endpoint := &assertionEndpoint{sts: &mockSTS{}}
+	for name, test := range map[string]struct {
+		source      AssertionSource
+		unavailable bool
+	}{
+		"env var not set":   {NewEnvAssertionSource("NOT_SET"), true},
+		"missing file":      {NewFileAssertionSource(filepath.Join(t.TempDir(), "missing")), true},
+		"empty response":    {NewHTTPAssertionSource("https://"+assertionHost, nil), false},
+		"missing JSONField": {NewHTTPAssertionSource("https://"+assertionHost, &HTTPAssertionSourceOptions{JSONField: "token"}), false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cred, err := NewTokenExchangeCredential(fakeTenantID, fakeClientID, test.source, &TokenExchangeCredentialOptions{
+				ClientOptions: policy.ClientOptions{Transport: endpoint},
+			})
+			require.NoError(t, err)
+			_, err = cred.GetToken(ctx, testTRO)
+			require.Error(t, err)
+			require.ErrorContains(t, err, credNameTokenExchange)
+			var unavailable credentialUnavailable
+			require.Equal(t, test.unavailable, errors.As(err, &unavailable), err.Error())
+		})
+	}
+}
+
+func TestAssertionLifetime(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	for _, test := range []struct {
+		assertion          string
+		expires, refreshOn time.Time
+	}{
+		{"not a JWT", now.Add(assertionCacheDuration), now.Add(assertionCacheDuration)},
+		{testJWT(now.Add(time.Hour)), now.Add(time.Hour), now.Add(time.Hour - assertionRefreshMargin)},
+		// short-lived assertions are replaced halfway through their remaining lifetime
+		{testJWT(now.Add(4 * time.Minute)), now.Add(4 * time.Minute), now.Add(2 * time.Minute)},
+	} {
+		expires, refreshOn := assertionLifetime(test.assertion, now)
+		require.Equal(t, test.expires, expires, test.assertion)
+		require.Equal(t, test.refreshOn, refreshOn, test.assertion)
+	}
+}